@@ -0,0 +1,58 @@
+package auth_test
+
+import (
+	"hexagone/gateway-service/src/auth"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	signer, err := auth.NewSigner("test-key", time.Hour)
+	require.NoError(t, err)
+	signer.Clock = func() time.Time { return now }
+
+	token, claims, err := signer.Issue(42, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour).Unix(), claims.ExpiresAt)
+
+	verified, err := signer.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), verified.UserID)
+	assert.Equal(t, "alice", verified.Username)
+
+	t.Run("Tampered payload", func(t *testing.T) {
+		other, _, err := signer.Issue(1, "admin")
+		require.NoError(t, err)
+		payload, _, _ := strings.Cut(other, ".")
+		_, signature, _ := strings.Cut(token, ".")
+		_, err = signer.Verify(payload + "." + signature)
+		assert.ErrorIs(t, err, auth.ErrBadSignature)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		for _, token := range []string{"", "abc", "abc.", ".abc"} {
+			_, err := signer.Verify(token)
+			assert.ErrorIs(t, err, auth.ErrMalformedToken, token)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		_, err := signer.Verify(token)
+		assert.ErrorIs(t, err, auth.ErrExpiredToken)
+	})
+
+	t.Run("Random key when none is set", func(t *testing.T) {
+		first, err := auth.NewSigner("", time.Hour)
+		require.NoError(t, err)
+		second, err := auth.NewSigner("", time.Hour)
+		require.NoError(t, err)
+		assert.Len(t, first.Key, 32)
+		assert.NotEqual(t, first.Key, second.Key)
+	})
+}
@@ -0,0 +1,85 @@
+// Package config reads the settings of the service from the environment.
+// They are loaded and checked once, before anything starts, so that a
+// missing or malformed setting stops the service with a clear message.
+package config
+
+import (
+	"errors"
+	"hexagone/gateway-service/src/graph"
+	shared "hexagone/shared/config"
+	"hexagone/shared/tracing"
+	"os"
+	"time"
+)
+
+// Config is the configuration of the gateway
+type Config struct {
+	Port         string        // PORT
+	SessionTTL   time.Duration // SESSION_TTL, 12h by default
+	SigningKey   string        // SESSION_SIGNING_KEY, random when empty
+	SecureCookie bool          // SESSION_COOKIE_SECURE, only send the session cookie over HTTPS
+	GraphQL      graph.Limits  // GRAPHQL_MAX_DEPTH and GRAPHQL_MAX_COMPLEXITY
+	Server       shared.Server
+
+	Services     Services
+	ServiceToken string // SERVICE_TOKEN, presented to the services with the identity header
+
+	TracesExporter string // OTEL_TRACES_EXPORTER: none, stdout or otlp
+	Logging        shared.Logging
+}
+
+// Load reads the configuration and reports every invalid setting at once
+func Load() (*Config, error) {
+	var errs []error
+	cfg := &Config{
+		Port:         shared.Port("PORT", &errs),
+		SessionTTL:   shared.Duration("SESSION_TTL", 12*time.Hour, &errs),
+		SigningKey:   os.Getenv("SESSION_SIGNING_KEY"),
+		SecureCookie: os.Getenv("SESSION_COOKIE_SECURE") == "true",
+		GraphQL: graph.Limits{
+			MaxDepth:      shared.Positive("GRAPHQL_MAX_DEPTH", graph.DefaultLimits.MaxDepth, &errs),
+			MaxComplexity: shared.Positive("GRAPHQL_MAX_COMPLEXITY", graph.DefaultLimits.MaxComplexity, &errs),
+		},
+		Server: shared.LoadServer(&errs),
+
+		Services:     LoadServices(&errs),
+		ServiceToken: shared.ServiceToken(&errs),
+
+		TracesExporter: shared.OneOf("OTEL_TRACES_EXPORTER", tracing.ExporterNone,
+			[]string{tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP}, &errs),
+		Logging: shared.LoadLogging(&errs),
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// DefaultServiceTimeout bounds the calls the gateway makes itself to a
+// service, when building aggregates
+const DefaultServiceTimeout = 3 * time.Second
+
+// Service is where the gateway reaches a service behind it
+type Service struct {
+	URL     string        // <SERVICE>_SERVICE_URL, or the compose service on <SERVICE>_PORT
+	Timeout time.Duration // <SERVICE>_SERVICE_TIMEOUT, each call the gateway makes itself
+}
+
+// Services are the four services behind the gateway
+type Services struct {
+	Home, Room, Object, User Service
+}
+
+// LoadServices reads the addresses of the services behind the gateway and
+// how long the gateway waits on each of them
+func LoadServices(errs *[]error) Services {
+	load := func(host, prefix string) Service {
+		return Service{
+			URL:     shared.RequiredServiceURL(prefix+"_SERVICE_URL", host, prefix+"_PORT", errs),
+			Timeout: shared.Duration(prefix+"_SERVICE_TIMEOUT", DefaultServiceTimeout, errs),
+		}
+	}
+	return Services{
+		Home:   load("home-service", "HOME"),
+		Room:   load("room-service", "ROOM"),
+		Object: load("object-service", "OBJECT"),
+		User:   load("user-service", "USER"),
+	}
+}
@@ -0,0 +1,68 @@
+package config_test
+
+import (
+	"hexagone/gateway-service/src/config"
+	"hexagone/gateway-service/src/graph"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Setenv("PORT", "8090")
+	t.Setenv("SESSION_TTL", "")
+	t.Setenv("SESSION_COOKIE_SECURE", "true")
+	t.Setenv("GRAPHQL_MAX_DEPTH", "6")
+	t.Setenv("GRAPHQL_MAX_COMPLEXITY", "")
+	t.Setenv("SERVICE_TOKEN", "0123456789abcdef0123456789abcdef")
+	t.Setenv("HOME_SERVICE_URL", "http://homes.internal:8080/")
+	t.Setenv("ROOM_SERVICE_URL", "")
+	t.Setenv("ROOM_PORT", "8082")
+	t.Setenv("OBJECT_SERVICE_URL", "http://objects.internal:8080")
+	t.Setenv("OBJECT_SERVICE_TIMEOUT", "10s")
+	t.Setenv("USER_SERVICE_URL", "http://users.internal:8080")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "8090", cfg.Port)
+	assert.Equal(t, 12*time.Hour, cfg.SessionTTL)
+	assert.True(t, cfg.SecureCookie)
+	assert.Equal(t, graph.Limits{MaxDepth: 6, MaxComplexity: graph.DefaultLimits.MaxComplexity}, cfg.GraphQL)
+	assert.Equal(t, 60*time.Second, cfg.Server.WriteTimeout)
+	assert.Equal(t, "none", cfg.TracesExporter)
+	assert.Equal(t, "info", cfg.Logging.Level)
+	assert.Equal(t, "json", cfg.Logging.Format)
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", cfg.ServiceToken)
+	assert.Equal(t, config.Service{URL: "http://homes.internal:8080", Timeout: config.DefaultServiceTimeout}, cfg.Services.Home)
+	assert.Equal(t, "http://room-service:8082", cfg.Services.Room.URL)
+	assert.Equal(t, 10*time.Second, cfg.Services.Object.Timeout)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	t.Setenv("PORT", "")
+	t.Setenv("SESSION_TTL", "12")
+	t.Setenv("GRAPHQL_MAX_DEPTH", "deep")
+	t.Setenv("HTTP_READ_TIMEOUT", "0s")
+	t.Setenv("OTEL_TRACES_EXPORTER", "zipkin")
+	t.Setenv("LOG_FORMAT", "xml")
+	t.Setenv("SERVICE_TOKEN", "")
+	t.Setenv("HOME_SERVICE_URL", "")
+	t.Setenv("HOME_PORT", "")
+	t.Setenv("ROOM_SERVICE_URL", "rooms.internal")
+	t.Setenv("OBJECT_SERVICE_TIMEOUT", "soon")
+
+	_, err := config.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "PORT is required")
+	assert.Contains(t, err.Error(), `SESSION_TTL must be a positive duration such as 30s, got "12"`)
+	assert.Contains(t, err.Error(), `GRAPHQL_MAX_DEPTH must be a positive number, got "deep"`)
+	assert.Contains(t, err.Error(), `HTTP_READ_TIMEOUT must be a positive duration such as 30s, got "0s"`)
+	assert.Contains(t, err.Error(), `OTEL_TRACES_EXPORTER must be one of none, stdout, otlp, got "zipkin"`)
+	assert.Contains(t, err.Error(), `LOG_FORMAT must be one of json, text, got "xml"`)
+	assert.Contains(t, err.Error(), "SERVICE_TOKEN is required")
+	assert.Contains(t, err.Error(), "HOME_SERVICE_URL or HOME_PORT is required")
+	assert.Contains(t, err.Error(), `ROOM_SERVICE_URL must be an http or https address, got "rooms.internal"`)
+	assert.Contains(t, err.Error(), `OBJECT_SERVICE_TIMEOUT must be a positive duration such as 30s, got "soon"`)
+}
@@ -0,0 +1,135 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// listFactor is how many items a list field is assumed to return when
+// weighing a query, since the real count is only known once it has run
+const listFactor = 10
+
+// Limits bound the queries the gateway runs. Depth counts nested fields;
+// complexity counts every field once per item of the lists above it.
+// Introspection fields are not counted.
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// DefaultLimits allow the nesting of home, rooms, objects, reservation and
+// reserving user, with room to spare
+var DefaultLimits = Limits{MaxDepth: 8, MaxComplexity: 10000}
+
+// cost is the weight of a selection set
+type cost struct {
+	depth, complexity int
+}
+
+// Check measures the operation of the document that will run and rejects it
+// when it goes past the limits. The document must have been validated, so
+// that its fields exist and its fragments do not form cycles.
+func (l Limits) Check(schema *graphql.Schema, document *ast.Document, operationName string) error {
+	fragments := map[string]*ast.FragmentDefinition{}
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		}
+	}
+	if operation == nil {
+		return nil // Reported by the executor
+	}
+
+	root := schema.QueryType()
+	if operation.Operation == ast.OperationTypeMutation {
+		root = schema.MutationType()
+	}
+
+	measured := measure(schema, fragments, root, operation.SelectionSet)
+	if measured.depth > l.MaxDepth {
+		return fmt.Errorf("query depth %d exceeds the limit of %d", measured.depth, l.MaxDepth)
+	}
+	if measured.complexity > l.MaxComplexity {
+		return fmt.Errorf("query complexity %d exceeds the limit of %d", measured.complexity, l.MaxComplexity)
+	}
+	return nil
+}
+
+// measure weighs a selection set made on parent. The fragments spread in it
+// are weighed as if their fields were written in place.
+func measure(schema *graphql.Schema, fragments map[string]*ast.FragmentDefinition, parent graphql.Type, selections *ast.SelectionSet) cost {
+	var total cost
+	if selections == nil {
+		return total
+	}
+
+	for _, selection := range selections.Selections {
+		var sub cost
+		switch selection := selection.(type) {
+		case *ast.Field:
+			name := selection.Name.Value
+			if strings.HasPrefix(name, "__") {
+				continue
+			}
+			definition := fieldDefinition(parent, name)
+			if definition == nil {
+				continue
+			}
+
+			children := measure(schema, fragments, graphql.GetNamed(definition.Type).(graphql.Type), selection.SelectionSet)
+			factor := 1
+			if isList(definition.Type) {
+				factor = listFactor
+			}
+			sub = cost{depth: children.depth + 1, complexity: factor * (1 + children.complexity)}
+
+		case *ast.InlineFragment:
+			sub = measure(schema, fragments, conditionType(schema, selection.TypeCondition, parent), selection.SelectionSet)
+
+		case *ast.FragmentSpread:
+			fragment := fragments[selection.Name.Value]
+			if fragment == nil {
+				continue
+			}
+			sub = measure(schema, fragments, conditionType(schema, fragment.TypeCondition, parent), fragment.SelectionSet)
+		}
+
+		total.depth = max(total.depth, sub.depth)
+		total.complexity += sub.complexity
+	}
+	return total
+}
+
+func fieldDefinition(parent graphql.Type, name string) *graphql.FieldDefinition {
+	switch parent := parent.(type) {
+	case *graphql.Object:
+		return parent.Fields()[name]
+	case *graphql.Interface:
+		return parent.Fields()[name]
+	}
+	return nil
+}
+
+func conditionType(schema *graphql.Schema, condition *ast.Named, parent graphql.Type) graphql.Type {
+	if condition == nil {
+		return parent
+	}
+	return schema.Type(condition.Name.Value)
+}
+
+func isList(ttype graphql.Type) bool {
+	if nonNull, ok := ttype.(*graphql.NonNull); ok {
+		ttype = nonNull.OfType
+	}
+	_, ok := ttype.(*graphql.List)
+	return ok
+}
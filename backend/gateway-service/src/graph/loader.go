@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"context"
+	"sync"
+)
+
+// maxBatch bounds the keys sent to a service in one call, to keep the
+// query strings short
+const maxBatch = 100
+
+// Loader batches and caches lookups by key for the length of one GraphQL
+// request. Load only records the key and returns a thunk; the executor runs
+// the thunks once a whole level of the query has been resolved, so the
+// first one fetches every key recorded so far with a single call.
+type Loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	queued  map[K]bool
+	results map[K]V
+	errs    map[K]error
+
+	dispatching sync.Mutex // Held while a batch is fetched
+}
+
+// NewLoader makes a loader around fetch, which returns the values of the
+// keys it finds; missing keys load as the zero value
+func NewLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:   fetch,
+		queued:  map[K]bool{},
+		results: map[K]V{},
+		errs:    map[K]error{},
+	}
+}
+
+// Load queues key for the next batch and returns a thunk giving its value
+func (l *Loader[K, V]) Load(ctx context.Context, key K) func() (V, error) {
+	l.mu.Lock()
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.dispatch(ctx)
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.results[key], l.errs[key]
+	}
+}
+
+// Prime caches the value of a key, such as the object a mutation returned
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.queued[key] = true
+	l.results[key] = value
+	delete(l.errs, key)
+}
+
+// dispatch fetches the queued keys in batches of at most maxBatch
+func (l *Loader[K, V]) dispatch(ctx context.Context) {
+	l.dispatching.Lock()
+	defer l.dispatching.Unlock()
+
+	l.mu.Lock()
+	keys := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+
+	for len(keys) > 0 {
+		batch := keys[:min(len(keys), maxBatch)]
+		keys = keys[len(batch):]
+
+		values, err := l.fetch(ctx, batch)
+
+		l.mu.Lock()
+		for _, key := range batch {
+			if err != nil {
+				l.errs[key] = err
+				continue
+			}
+			l.results[key] = values[key]
+		}
+		l.mu.Unlock()
+	}
+}
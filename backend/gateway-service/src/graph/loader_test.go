@@ -0,0 +1,70 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"hexagone/gateway-service/src/graph"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoader(t *testing.T) {
+	ctx := context.Background()
+	var batches [][]int
+	loader := graph.NewLoader(func(_ context.Context, keys []int) (map[int]string, error) {
+		batches = append(batches, keys)
+		values := map[int]string{}
+		for _, key := range keys {
+			if key != 404 {
+				values[key] = "item"
+			}
+		}
+		return values, nil
+	})
+
+	first, again, missing := loader.Load(ctx, 1), loader.Load(ctx, 1), loader.Load(ctx, 404)
+	second := loader.Load(ctx, 2)
+
+	value, err := first()
+	require.NoError(t, err)
+	assert.Equal(t, "item", value)
+	value, _ = again()
+	assert.Equal(t, "item", value)
+	value, err = missing()
+	require.NoError(t, err)
+	assert.Empty(t, value)
+	second()
+	assert.Equal(t, [][]int{{1, 404, 2}}, batches, "keys should be fetched once, in one batch")
+
+	t.Run("Cached and primed keys are not fetched", func(t *testing.T) {
+		loader.Prime(3, "primed")
+		value, _ := loader.Load(ctx, 3)()
+		assert.Equal(t, "primed", value)
+		loader.Load(ctx, 2)()
+		assert.Len(t, batches, 1)
+	})
+
+	t.Run("Large batches are split", func(t *testing.T) {
+		var thunks []func() (string, error)
+		for key := 1000; key < 1250; key++ {
+			thunks = append(thunks, loader.Load(ctx, key))
+		}
+		thunks[0]()
+		require.Len(t, batches, 4)
+		assert.Len(t, batches[1], 100)
+		assert.Len(t, batches[3], 50)
+	})
+}
+
+func TestLoaderError(t *testing.T) {
+	failure := errors.New("503 Service Unavailable")
+	loader := graph.NewLoader(func(context.Context, []string) (map[string]int, error) {
+		return nil, failure
+	})
+
+	thunk := loader.Load(context.Background(), "a")
+	_, err := thunk()
+	assert.ErrorIs(t, err, failure)
+}
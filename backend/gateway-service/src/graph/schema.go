@@ -0,0 +1,505 @@
+// Package graph serves homes, rooms, objects, users and reservations as a
+// GraphQL schema whose resolvers call the services. Lookups go through
+// per-request loaders, so a query touching many rooms or objects makes one
+// call per service and level instead of one per item.
+package graph
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"hexagone/gateway-service/src/models"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Service names one of the services the resolvers call
+type Service int
+
+const (
+	HomeService Service = iota
+	RoomService
+	ObjectService
+	UserService
+)
+
+// Backend calls the services on behalf of the signed-in user, decoding the
+// "data" field of their responses into out
+type Backend interface {
+	Get(ctx context.Context, service Service, path string, query url.Values, out interface{}) error
+	Send(ctx context.Context, service Service, method, path string, body, out interface{}) error
+	UserID() string
+}
+
+// request is what the resolvers of one GraphQL request share
+type request struct {
+	backend       Backend
+	homes         *Loader[uint, *models.Home]
+	rooms         *Loader[uint, *models.Room]
+	roomsByHome   *Loader[uint, []models.Room]
+	objects       *Loader[string, *models.Object]
+	objectsByRoom *Loader[uint, []models.Object]
+	users         *Loader[uint, *models.User]
+	reservations  *Loader[uint, []models.Object]
+}
+
+type requestKey struct{}
+
+// NewContext prepares ctx for running a request against the schema
+func NewContext(ctx context.Context, backend Backend) context.Context {
+	r := &request{backend: backend}
+
+	r.homes = NewLoader(func(ctx context.Context, ids []uint) (map[uint]*models.Home, error) {
+		var homes []models.Home
+		if err := backend.Get(ctx, HomeService, "/homes", nil, &homes); err != nil {
+			return nil, err
+		}
+		found := map[uint]*models.Home{}
+		for i := range homes {
+			found[homes[i].ID] = &homes[i]
+		}
+		return found, nil
+	})
+	r.rooms = NewLoader(func(ctx context.Context, ids []uint) (map[uint]*models.Room, error) {
+		var rooms []models.Room
+		if err := backend.Get(ctx, RoomService, "/rooms", url.Values{"id": {joinIDs(ids)}}, &rooms); err != nil {
+			return nil, err
+		}
+		found := map[uint]*models.Room{}
+		for i := range rooms {
+			found[rooms[i].ID] = &rooms[i]
+		}
+		return found, nil
+	})
+	r.roomsByHome = NewLoader(func(ctx context.Context, homeIDs []uint) (map[uint][]models.Room, error) {
+		var rooms []models.Room
+		if err := backend.Get(ctx, RoomService, "/rooms", url.Values{"home_id": {joinIDs(homeIDs)}}, &rooms); err != nil {
+			return nil, err
+		}
+		found := map[uint][]models.Room{}
+		for _, room := range rooms {
+			found[room.HomeID] = append(found[room.HomeID], room)
+		}
+		return found, nil
+	})
+	r.objects = NewLoader(func(ctx context.Context, ids []string) (map[string]*models.Object, error) {
+		var objects []models.Object
+		if err := backend.Get(ctx, ObjectService, "/objects", url.Values{"ids": {strings.Join(ids, ",")}}, &objects); err != nil {
+			return nil, err
+		}
+		found := map[string]*models.Object{}
+		for i := range objects {
+			found[objects[i].ID] = &objects[i]
+		}
+		return found, nil
+	})
+	r.objectsByRoom = NewLoader(func(ctx context.Context, roomIDs []uint) (map[uint][]models.Object, error) {
+		var objects []models.Object
+		if err := backend.Get(ctx, ObjectService, "/objects/room", url.Values{"room_id": {joinIDs(roomIDs)}}, &objects); err != nil {
+			return nil, err
+		}
+		found := map[uint][]models.Object{}
+		for _, object := range objects {
+			if roomID, ok := parseID(object.RoomID); ok {
+				found[roomID] = append(found[roomID], object)
+			}
+		}
+		return found, nil
+	})
+	r.users = NewLoader(func(ctx context.Context, ids []uint) (map[uint]*models.User, error) {
+		// The user service only lists every user, which is still one call
+		var users []models.User
+		if err := backend.Get(ctx, UserService, "/users", nil, &users); err != nil {
+			return nil, err
+		}
+		found := map[uint]*models.User{}
+		for i := range users {
+			found[users[i].ID] = &users[i]
+		}
+		return found, nil
+	})
+	r.reservations = NewLoader(func(ctx context.Context, userIDs []uint) (map[uint][]models.Object, error) {
+		// The object service has no batch route for claims; the loader
+		// still saves asking twice for the same user
+		found := map[uint][]models.Object{}
+		for _, userID := range userIDs {
+			var objects []models.Object
+			if err := backend.Get(ctx, ObjectService, "/users/"+strconv.FormatUint(uint64(userID), 10)+"/reservations", nil, &objects); err != nil {
+				return nil, err
+			}
+			found[userID] = objects
+		}
+		return found, nil
+	})
+
+	return context.WithValue(ctx, requestKey{}, r)
+}
+
+func requestFrom(ctx context.Context) *request {
+	return ctx.Value(requestKey{}).(*request)
+}
+
+func joinIDs(ids []uint) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseID reads the numeric IDs of homes, rooms and users, which the object
+// service stores as strings
+func parseID(value interface{}) (uint, bool) {
+	var text string
+	switch value := value.(type) {
+	case string:
+		text = value
+	case int:
+		return uint(value), value > 0
+	default:
+		return 0, false
+	}
+	id, err := strconv.ParseUint(text, 10, 32)
+	return uint(id), err == nil && id > 0
+}
+
+// thunk adapts a loader thunk to the executor, turning a nil pointer into a
+// null rather than an object with no fields
+func thunk[V any](load func() (*V, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		value, err := load()
+		if err != nil || value == nil {
+			return nil, err
+		}
+		return value, nil
+	}
+}
+
+func listThunk[V any](load func() ([]V, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		values, err := load()
+		if values == nil {
+			values = []V{}
+		}
+		return values, err
+	}
+}
+
+// Reservation is the claim on an object
+type Reservation struct {
+	Status string
+	UserID string
+}
+
+// NewSchema builds the GraphQL schema
+func NewSchema() (graphql.Schema, error) {
+	homeType := graphql.NewObject(graphql.ObjectConfig{Name: "Home", Fields: graphql.Fields{}})
+	roomType := graphql.NewObject(graphql.ObjectConfig{Name: "Room", Fields: graphql.Fields{}})
+	objectType := graphql.NewObject(graphql.ObjectConfig{Name: "Object", Fields: graphql.Fields{}})
+	userType := graphql.NewObject(graphql.ObjectConfig{Name: "User", Fields: graphql.Fields{}})
+
+	reservationType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Reservation",
+		Description: "A claim on an object",
+		Fields: graphql.Fields{
+			"status": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "pending, approved or picked_up"},
+			"userId": &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"user": &graphql.Field{
+				Type: userType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					userID, ok := parseID(p.Source.(Reservation).UserID)
+					if !ok {
+						return nil, nil
+					}
+					return thunk(requestFrom(p.Context).users.Load(p.Context, userID)), nil
+				},
+			},
+		},
+	})
+
+	homeType.AddFieldConfig("id", &graphql.Field{Type: graphql.NewNonNull(graphql.ID)})
+	homeType.AddFieldConfig("name", &graphql.Field{Type: graphql.NewNonNull(graphql.String)})
+	homeType.AddFieldConfig("rooms", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(roomType))),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			home := p.Source.(*models.Home)
+			return listThunk(requestFrom(p.Context).roomsByHome.Load(p.Context, home.ID)), nil
+		},
+	})
+
+	roomType.AddFieldConfig("id", &graphql.Field{Type: graphql.NewNonNull(graphql.ID)})
+	roomType.AddFieldConfig("name", &graphql.Field{Type: graphql.NewNonNull(graphql.String)})
+	roomType.AddFieldConfig("home", &graphql.Field{
+		Type: homeType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return thunk(requestFrom(p.Context).homes.Load(p.Context, roomOf(p.Source).HomeID)), nil
+		},
+	})
+	roomType.AddFieldConfig("objects", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(objectType))),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return listThunk(requestFrom(p.Context).objectsByRoom.Load(p.Context, roomOf(p.Source).ID)), nil
+		},
+	})
+
+	objectType.AddFieldConfig("id", &graphql.Field{Type: graphql.NewNonNull(graphql.ID)})
+	objectType.AddFieldConfig("name", &graphql.Field{Type: graphql.NewNonNull(graphql.String)})
+	objectType.AddFieldConfig("type", &graphql.Field{Type: graphql.String})
+	objectType.AddFieldConfig("description", &graphql.Field{Type: graphql.String})
+	objectType.AddFieldConfig("condition", &graphql.Field{Type: graphql.String})
+	objectType.AddFieldConfig("value", &graphql.Field{Type: graphql.Float, Description: "Estimated value in euros"})
+	objectType.AddFieldConfig("tags", &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(graphql.String))})
+	objectType.AddFieldConfig("isReserved", &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)})
+	objectType.AddFieldConfig("room", &graphql.Field{
+		Type: roomType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			roomID, ok := parseID(objectOf(p.Source).RoomID)
+			if !ok {
+				return nil, nil
+			}
+			return thunk(requestFrom(p.Context).rooms.Load(p.Context, roomID)), nil
+		},
+	})
+	objectType.AddFieldConfig("home", &graphql.Field{
+		Type: homeType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			homeID, ok := parseID(objectOf(p.Source).HomeID)
+			if !ok {
+				return nil, nil
+			}
+			return thunk(requestFrom(p.Context).homes.Load(p.Context, homeID)), nil
+		},
+	})
+	objectType.AddFieldConfig("reservation", &graphql.Field{
+		Type:        reservationType,
+		Description: "The current claim on the object, null when it is available",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			object := objectOf(p.Source)
+			switch status := object.CurrentStatus(); status {
+			case "pending", "approved", "picked_up":
+				return Reservation{Status: status, UserID: object.ReservedBy}, nil
+			}
+			return nil, nil
+		},
+	})
+
+	userType.AddFieldConfig("id", &graphql.Field{Type: graphql.NewNonNull(graphql.ID)})
+	userType.AddFieldConfig("username", &graphql.Field{Type: graphql.NewNonNull(graphql.String)})
+	userType.AddFieldConfig("isAdmin", &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)})
+	userType.AddFieldConfig("reservations", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(objectType))),
+		Description: "Objects the user has claimed",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			user := p.Source.(*models.User)
+			return listThunk(requestFrom(p.Context).reservations.Load(p.Context, user.ID)), nil
+		},
+	})
+
+	idArgs := graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"homes": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(homeType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					r := requestFrom(p.Context)
+					var homes []models.Home
+					if err := r.backend.Get(p.Context, HomeService, "/homes", nil, &homes); err != nil {
+						return nil, err
+					}
+					list := make([]*models.Home, len(homes))
+					for i := range homes {
+						list[i] = &homes[i]
+						r.homes.Prime(homes[i].ID, list[i])
+					}
+					return list, nil
+				},
+			},
+			"home": &graphql.Field{
+				Type: homeType,
+				Args: idArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, ok := parseID(p.Args["id"])
+					if !ok {
+						return nil, nil
+					}
+					return thunk(requestFrom(p.Context).homes.Load(p.Context, id)), nil
+				},
+			},
+			"room": &graphql.Field{
+				Type: roomType,
+				Args: idArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, ok := parseID(p.Args["id"])
+					if !ok {
+						return nil, nil
+					}
+					return thunk(requestFrom(p.Context).rooms.Load(p.Context, id)), nil
+				},
+			},
+			"object": &graphql.Field{
+				Type: objectType,
+				Args: idArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return thunk(requestFrom(p.Context).objects.Load(p.Context, p.Args["id"].(string))), nil
+				},
+			},
+			"users": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					r := requestFrom(p.Context)
+					var users []models.User
+					if err := r.backend.Get(p.Context, UserService, "/users", nil, &users); err != nil {
+						return nil, err
+					}
+					list := make([]*models.User, len(users))
+					for i := range users {
+						list[i] = &users[i]
+						r.users.Prime(users[i].ID, list[i])
+					}
+					return list, nil
+				},
+			},
+			"user": &graphql.Field{
+				Type: userType,
+				Args: idArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, ok := parseID(p.Args["id"])
+					if !ok {
+						return nil, nil
+					}
+					return thunk(requestFrom(p.Context).users.Load(p.Context, id)), nil
+				},
+			},
+			"me": &graphql.Field{
+				Type:        userType,
+				Description: "The signed-in user",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					r := requestFrom(p.Context)
+					id, ok := parseID(r.backend.UserID())
+					if !ok {
+						return nil, nil
+					}
+					return thunk(r.users.Load(p.Context, id)), nil
+				},
+			},
+		},
+	})
+
+	createObjectInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CreateObjectInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"type":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"roomId":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.ID)},
+			"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"condition":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"value":       &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"tags":        &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createHome": &graphql.Field{
+				Type: graphql.NewNonNull(homeType),
+				Args: graphql.FieldConfigArgument{"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					r := requestFrom(p.Context)
+					var home models.Home
+					if err := r.backend.Send(p.Context, HomeService, http.MethodPost, "/homes", map[string]interface{}{"name": p.Args["name"]}, &home); err != nil {
+						return nil, err
+					}
+					r.homes.Prime(home.ID, &home)
+					return &home, nil
+				},
+			},
+			"createRoom": &graphql.Field{
+				Type: graphql.NewNonNull(roomType),
+				Args: graphql.FieldConfigArgument{
+					"homeId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"name":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					homeID, ok := parseID(p.Args["homeId"])
+					if !ok {
+						return nil, errors.New("homeId must be a home ID")
+					}
+					r := requestFrom(p.Context)
+					var room models.Room
+					if err := r.backend.Send(p.Context, RoomService, http.MethodPost, "/rooms", map[string]interface{}{"name": p.Args["name"], "home_id": homeID}, &room); err != nil {
+						return nil, err
+					}
+					r.rooms.Prime(room.ID, &room)
+					return &room, nil
+				},
+			},
+			"createObject": &graphql.Field{
+				Type: graphql.NewNonNull(objectType),
+				Args: graphql.FieldConfigArgument{"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createObjectInput)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input := p.Args["input"].(map[string]interface{})
+					body := map[string]interface{}{"room_id": input["roomId"]}
+					for _, field := range []string{"name", "type", "description", "condition", "value", "tags"} {
+						if value, ok := input[field]; ok {
+							body[field] = value
+						}
+					}
+					return sendObject(p, http.MethodPost, "/objects", body)
+				},
+			},
+			"reserveObject": &graphql.Field{
+				Type:        graphql.NewNonNull(objectType),
+				Description: "Claim an object for the signed-in user",
+				Args:        idArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return sendObject(p, http.MethodPatch, "/objects/"+url.PathEscape(p.Args["id"].(string))+"/reserve", nil)
+				},
+			},
+			"unreserveObject": &graphql.Field{
+				Type:        graphql.NewNonNull(objectType),
+				Description: "Release the claim on an object",
+				Args:        idArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return sendObject(p, http.MethodPatch, "/objects/"+url.PathEscape(p.Args["id"].(string))+"/unreserve", nil)
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// sendObject makes a change to an object and returns the object as the
+// object service left it
+func sendObject(p graphql.ResolveParams, method, path string, body interface{}) (interface{}, error) {
+	r := requestFrom(p.Context)
+	var object models.Object
+	if err := r.backend.Send(p.Context, ObjectService, method, path, body, &object); err != nil {
+		return nil, err
+	}
+	r.objects.Prime(object.ID, &object)
+	return &object, nil
+}
+
+// roomOf and objectOf accept the records as lists hold them and as loaders
+// return them
+func roomOf(source interface{}) *models.Room {
+	if room, ok := source.(models.Room); ok {
+		return &room
+	}
+	return source.(*models.Room)
+}
+
+func objectOf(source interface{}) *models.Object {
+	if object, ok := source.(models.Object); ok {
+		return &object
+	}
+	return source.(*models.Object)
+}
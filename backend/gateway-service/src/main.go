@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"hexagone/gateway-service/src/auth"
+	"hexagone/gateway-service/src/config"
+	"hexagone/gateway-service/src/middleware"
+	"hexagone/gateway-service/src/services"
+	"hexagone/shared/logging"
+	"hexagone/shared/server"
+	"hexagone/shared/tracing"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	logging.InitLogger()
+
+	// Get configuration from environment variables
+	cfg, err := config.Load()
+	if err != nil {
+		logging.Log.Fatalf("Invalid configuration: %v", err)
+	}
+	logging.Configure(cfg.Logging.Level, cfg.Logging.Format)
+	logging.Log.Info("Starting API gateway")
+
+	// Set up tracing before anything makes a call worth tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), "gateway-service", cfg.TracesExporter)
+	if err != nil {
+		logging.Log.Fatalf("Failed to set up tracing: %v", err)
+	}
+
+	upstreams, err := services.NewUpstreams(cfg.Services, cfg.ServiceToken)
+	if err != nil {
+		logging.Log.Fatalf("Failed to configure upstream services: %v", err)
+	}
+
+	if cfg.SigningKey == "" {
+		logging.Log.Warn("SESSION_SIGNING_KEY is not set, sessions will not survive a restart")
+	}
+	signer, err := auth.NewSigner(cfg.SigningKey, cfg.SessionTTL)
+	if err != nil {
+		logging.Log.Fatalf("Failed to create session signer: %v", err)
+	}
+
+	r := services.NewRouter(services.GatewayConfig{
+		Upstreams:    upstreams,
+		Signer:       signer,
+		Origins:      middleware.AllowedOrigins(),
+		SecureCookie: cfg.SecureCookie,
+		GraphQL:      cfg.GraphQL,
+	})
+
+	// Stop on SIGTERM (docker stop) or Ctrl-C, after the requests in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Start the server
+	logging.Log.Infof("Starting HTTP server on port %s", cfg.Port)
+	if err := server.Run(ctx, server.New(cfg.Port, r, cfg.Server), cfg.Server.ShutdownTimeout); err != nil {
+		logging.Log.Errorf("Server stopped: %v", err)
+	}
+
+	// Export the spans still buffered, without waiting on a collector forever
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logging.Log.Errorf("Failed to flush traces: %v", err)
+	}
+	logging.Log.Info("API gateway stopped")
+}
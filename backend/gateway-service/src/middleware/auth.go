@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"hexagone/gateway-service/src/auth"
+	sharedauth "hexagone/shared/auth"
+	"hexagone/shared/logging"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// IdentityHeader carries the ID of the signed-in user to the services. Only
+// the gateway sets it; whatever a client sends under that name is dropped.
+const IdentityHeader = sharedauth.IdentityHeader
+
+// SessionCookie holds the session token for browsers
+const SessionCookie = "meublehub_session"
+
+// ClaimsKey is where the verified claims are kept in the gin context
+const ClaimsKey = "claims"
+
+// sessionToken reads the token from the Authorization header, or from the
+// session cookie
+func sessionToken(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); header != "" {
+		if token, found := strings.CutPrefix(header, "Bearer "); found {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	token, _ := c.Cookie(SessionCookie)
+	return token
+}
+
+// StripIdentity removes any identity header sent by the client, along with
+// the internal service token the services present to each other
+func StripIdentity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, header := range []string{IdentityHeader, sharedauth.ServiceTokenHeader} {
+			if c.Request.Header.Get(header) != "" {
+				logging.RequestLog(c).WithFields(logrus.Fields{
+					"path":     c.Request.URL.Path,
+					"header":   header,
+					"clientIP": c.ClientIP(),
+				}).Warn("Dropped client-supplied identity header")
+				c.Request.Header.Del(header)
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireSession verifies the session token of the request and passes the
+// user it belongs to on to the services in the identity header
+func RequireSession(signer *auth.Signer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := sessionToken(c)
+		if token == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		claims, err := signer.Verify(token)
+		if err != nil {
+			logging.RequestLog(c).WithFields(logrus.Fields{
+				"path":  c.Request.URL.Path,
+				"error": err.Error(),
+			}).Warn("Rejected session token")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired session"})
+			c.Abort()
+			return
+		}
+
+		userID := strconv.FormatUint(uint64(claims.UserID), 10)
+		c.Set(ClaimsKey, claims)
+		c.Request.Header.Set(IdentityHeader, userID)
+		logging.AddLogFields(c, logrus.Fields{"userId": userID})
+		c.Next()
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"hexagone/shared/logging"
+	shared "hexagone/shared/middleware"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// AllowedOrigins reads the comma-separated CORS_ORIGINS, falling back to
+// the frontend addresses the services allow
+func AllowedOrigins() []string {
+	origins := []string{}
+	for _, origin := range strings.Split(os.Getenv("CORS_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		return shared.Origins
+	}
+	return origins
+}
+
+// SetupCORS answers preflights and sets the CORS headers for every route of
+// the gateway, so the services behind it do not need their own lists
+func SetupCORS(origins []string) gin.HandlerFunc {
+	return cors.New(cors.Config{
+		AllowOrigins:     origins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", logging.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", "Content-Disposition", logging.RequestIDHeader},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	})
+}
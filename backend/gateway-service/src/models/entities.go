@@ -0,0 +1,46 @@
+package models
+
+// Home mirrors the home service record
+type Home struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// Room mirrors the room service record
+type Room struct {
+	ID     uint   `json:"id"`
+	Name   string `json:"name"`
+	HomeID uint   `json:"home_id"`
+}
+
+// Object mirrors the fields of the object service record the gateway reads
+type Object struct {
+	ID                string   `json:"id"`
+	Name              string   `json:"name"`
+	Type              string   `json:"type"`
+	Description       string   `json:"description,omitempty"`
+	Condition         string   `json:"condition,omitempty"`
+	Value             float64  `json:"value,omitempty"`
+	Tags              []string `json:"tags,omitempty"`
+	IsReserved        bool     `json:"isReserved"`
+	ReservedBy        string   `json:"reservedBy"`
+	ReservationStatus string   `json:"reservationStatus,omitempty"`
+	RoomID            string   `json:"room_id"`
+	HomeID            string   `json:"home_id,omitempty"`
+}
+
+// CurrentStatus is where the claim on the object stands; objects reserved
+// before the approval workflow count as approved
+func (o Object) CurrentStatus() string {
+	if o.ReservationStatus == "" && o.IsReserved {
+		return "approved"
+	}
+	return o.ReservationStatus
+}
+
+// User mirrors the user service record, without the password and email
+type User struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	IsAdmin  bool   `json:"isAdmin"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "encoding/json"
+
+// HomeTree is a home with its rooms and their objects, put together from the
+// home, room and object services in one response. When a service fails the
+// tree is returned without its part, Partial is set and Errors says what is
+// missing.
+type HomeTree struct {
+	Home    *Home              `json:"home"` // Nil when the home service did not answer
+	Rooms   []TreeRoom         `json:"rooms"`
+	Summary ReservationSummary `json:"summary"`
+	Partial bool               `json:"partial"`
+	Errors  []TreeError        `json:"errors"`
+}
+
+// TreeRoom is a room of the home with its objects, as the object service
+// returns them
+type TreeRoom struct {
+	ID       uint               `json:"id"`
+	Name     string             `json:"name"`
+	HomeID   uint               `json:"home_id"`
+	Objects  []json.RawMessage  `json:"objects"`
+	Summary  ReservationSummary `json:"summary"`
+	Complete bool               `json:"complete"` // False when the objects could not be read
+}
+
+// ReservationSummary counts objects by where their claim stands. Rejected
+// claims leave the object available.
+type ReservationSummary struct {
+	Objects   int `json:"objects"`
+	Available int `json:"available"`
+	Pending   int `json:"pending"`
+	Approved  int `json:"approved"`
+	PickedUp  int `json:"pickedUp"`
+}
+
+// Add sums up another summary into s
+func (s *ReservationSummary) Add(other ReservationSummary) {
+	s.Objects += other.Objects
+	s.Available += other.Available
+	s.Pending += other.Pending
+	s.Approved += other.Approved
+	s.PickedUp += other.PickedUp
+}
+
+// TreeError is a part of the tree a service failed to provide
+type TreeError struct {
+	Service string `json:"service"`
+	RoomID  uint   `json:"roomId,omitempty"` // Room whose objects are missing
+	Error   string `json:"error"`
+}
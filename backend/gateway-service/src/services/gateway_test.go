@@ -0,0 +1,503 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"hexagone/gateway-service/src/auth"
+	"hexagone/gateway-service/src/config"
+	"hexagone/gateway-service/src/middleware"
+	"hexagone/gateway-service/src/services"
+	"hexagone/shared/health"
+	"hexagone/shared/logging"
+	"hexagone/shared/tracing"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// seenRequest is what a stand-in service received
+type seenRequest struct {
+	Service string `json:"service"`
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	UserID  string `json:"userId"`
+	Origin  string `json:"origin"`
+	Token   string `json:"token"`
+
+	Traceparent string `json:"traceparent"`
+	RequestID   string `json:"requestId"`
+}
+
+// fakeService answers every request with what it received, plus CORS
+// headers of its own that the gateway should drop
+func fakeService(t *testing.T, name string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if name == "user-service" && r.URL.Path == "/login" {
+			if strings.Contains(readAll(r), "wrong") {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"Invalid email or password"}`))
+				return
+			}
+			w.Write([]byte(`{"message":"Login successful","user":{"id":7,"username":"alice","email":"alice@example.com","isAdmin":true}}`))
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		json.NewEncoder(w).Encode(seenRequest{
+			Service: name,
+			Method:  r.Method,
+			Path:    r.URL.RequestURI(),
+			UserID:  r.Header.Get("X-User-ID"),
+			Origin:  r.Header.Get("Origin"),
+			Token:   r.Header.Get("X-Service-Token"),
+
+			Traceparent: r.Header.Get("traceparent"),
+			RequestID:   r.Header.Get("X-Request-ID"),
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func readAll(r *http.Request) string {
+	var body strings.Builder
+	buf := make([]byte, 512)
+	for {
+		n, err := r.Body.Read(buf)
+		body.Write(buf[:n])
+		if err != nil {
+			return body.String()
+		}
+	}
+}
+
+// testServiceToken is the token the gateway presents to the services
+const testServiceToken = "0123456789abcdef0123456789abcdef"
+
+func setupGateway(t *testing.T) (*gin.Engine, *auth.Signer) {
+	logging.InitLogger()
+	gin.SetMode(gin.TestMode)
+
+	t.Setenv("HOME_SERVICE_URL", fakeService(t, "home-service").URL)
+	t.Setenv("ROOM_SERVICE_URL", fakeService(t, "room-service").URL)
+	t.Setenv("OBJECT_SERVICE_URL", fakeService(t, "object-service").URL)
+	t.Setenv("USER_SERVICE_URL", fakeService(t, "user-service").URL)
+
+	signer, err := auth.NewSigner("test-key", time.Hour)
+	require.NoError(t, err)
+	return newGateway(t, signer), signer
+}
+
+// loadUpstreams builds the proxies of the services in the environment
+func loadUpstreams(t *testing.T) *services.Upstreams {
+	var errs []error
+	cfg := config.LoadServices(&errs)
+	require.NoError(t, errors.Join(errs...))
+	upstreams, err := services.NewUpstreams(cfg, testServiceToken)
+	require.NoError(t, err)
+	return upstreams
+}
+
+// newGateway builds the gateway for the services in the environment
+func newGateway(t *testing.T, signer *auth.Signer) *gin.Engine {
+	upstreams := loadUpstreams(t)
+	return services.NewRouter(services.GatewayConfig{
+		Upstreams: upstreams,
+		Signer:    signer,
+		Origins:   []string{"http://localhost"},
+	})
+}
+
+// recorder adds to httptest.ResponseRecorder the CloseNotify the reverse
+// proxy expects from gin's response writer
+type recorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (recorder) CloseNotify() <-chan bool { return make(chan bool) }
+
+func serve(router *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	w := recorder{httptest.NewRecorder()}
+	router.ServeHTTP(w, req)
+	return w.ResponseRecorder
+}
+
+func send(router *gin.Engine, req *http.Request) (*httptest.ResponseRecorder, seenRequest) {
+	w := serve(router, req)
+
+	var seen seenRequest
+	json.Unmarshal(w.Body.Bytes(), &seen)
+	return w, seen
+}
+
+func bearer(t *testing.T, signer *auth.Signer, userID uint) string {
+	token, _, err := signer.Issue(userID, "")
+	require.NoError(t, err)
+	return "Bearer " + token
+}
+
+func TestGatewayRouting(t *testing.T) {
+	router, signer := setupGateway(t)
+
+	tests := []struct {
+		method, path, service, upstreamPath string
+	}{
+		{"GET", "/api/homes", "home-service", "/homes"},
+		{"GET", "/api/homes/3/export?format=csv", "home-service", "/homes/3/export?format=csv"},
+		{"DELETE", "/api/homes/3", "home-service", "/homes/3"},
+		{"PUT", "/api/homes/3/settings", "object-service", "/homes/3/settings"},
+		{"GET", "/api/homes/3/pickup-slots", "object-service", "/homes/3/pickup-slots"},
+		{"GET", "/api/rooms?home_id=3", "room-service", "/rooms?home_id=3"},
+		{"GET", "/api/objects/room?room_id=4", "object-service", "/objects/room?room_id=4"},
+		{"PATCH", "/api/objects/abc/reserve", "object-service", "/objects/abc/reserve"},
+		{"GET", "/api/search?q=vase", "object-service", "/search?q=vase"},
+		{"GET", "/api/users", "user-service", "/users"},
+		{"GET", "/api/users/7", "user-service", "/users/7"},
+		{"GET", "/api/users/7/reservations", "object-service", "/users/7/reservations"},
+		{"GET", "/api/me/reservations", "object-service", "/me/reservations"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", bearer(t, signer, 7))
+
+			w, seen := send(router, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.service, seen.Service)
+			assert.Equal(t, tt.method, seen.Method)
+			assert.Equal(t, tt.upstreamPath, seen.Path)
+		})
+	}
+}
+
+func TestGatewayUnknownAndInternalRoutes(t *testing.T) {
+	router, signer := setupGateway(t)
+
+	for _, path := range []string{"/api/unknown", "/api/search/documents/room/1", "/api/homes/../search/documents/room/1"} {
+		req := httptest.NewRequest("PUT", path, nil)
+		req.Header.Set("Authorization", bearer(t, signer, 7))
+
+		w, seen := send(router, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+		assert.Empty(t, seen.Service, path)
+	}
+}
+
+func TestGatewayIdentity(t *testing.T) {
+	router, signer := setupGateway(t)
+
+	t.Run("Token sets the identity header", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/api/rooms/5", nil)
+		req.Header.Set("Authorization", bearer(t, signer, 7))
+
+		w, seen := send(router, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "7", seen.UserID)
+		assert.Equal(t, testServiceToken, seen.Token, "the services only trust the identity with the service token")
+	})
+
+	t.Run("Spoofed header is replaced", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/api/rooms/5", nil)
+		req.Header.Set("Authorization", bearer(t, signer, 7))
+		req.Header.Set("X-User-ID", "1")
+
+		_, seen := send(router, req)
+
+		assert.Equal(t, "7", seen.UserID)
+	})
+
+	t.Run("Spoofed header is dropped on public routes", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/users", strings.NewReader(`{}`))
+		req.Header.Set("X-User-ID", "1")
+		req.Header.Set("X-Service-Token", "guessed")
+
+		w, seen := send(router, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "user-service", seen.Service)
+		assert.Empty(t, seen.UserID)
+		assert.Empty(t, seen.Token, "the internal service token never comes from a client")
+	})
+
+	t.Run("Media downloads need no session", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/media/objects/abc/small.jpg?expires=1&signature=x", nil)
+
+		w, seen := send(router, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "object-service", seen.Service)
+		assert.Equal(t, "/media/objects/abc/small.jpg?expires=1&signature=x", seen.Path)
+	})
+
+	t.Run("Session cookie is accepted", func(t *testing.T) {
+		token, _, err := signer.Issue(9, "bob")
+		require.NoError(t, err)
+		req := httptest.NewRequest("GET", "/api/homes", nil)
+		req.AddCookie(&http.Cookie{Name: middleware.SessionCookie, Value: token})
+
+		_, seen := send(router, req)
+
+		assert.Equal(t, "9", seen.UserID)
+	})
+
+	rejected := map[string]string{
+		"No credentials":    "",
+		"Spoofed header":    "",
+		"Forged token":      "Bearer " + strings.Replace(strings.TrimPrefix(bearer(t, signer, 7), "Bearer "), ".", ".x", 1),
+		"Other key":         "Bearer " + mustIssue(t, "other-key", time.Hour),
+		"Expired token":     "Bearer " + mustIssue(t, "test-key", -time.Minute),
+		"Not a bearer auth": "Basic YWxpY2U6c2VjcmV0",
+	}
+	for name, authorization := range rejected {
+		t.Run(name+" is rejected", func(t *testing.T) {
+			req := httptest.NewRequest("DELETE", "/api/rooms/5", nil)
+			req.Header.Set("X-User-ID", "1")
+			if authorization != "" {
+				req.Header.Set("Authorization", authorization)
+			}
+
+			w, seen := send(router, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Empty(t, seen.Service)
+		})
+	}
+}
+
+func mustIssue(t *testing.T, key string, ttl time.Duration) string {
+	signer, err := auth.NewSigner(key, ttl)
+	require.NoError(t, err)
+	token, _, err := signer.Issue(7, "")
+	require.NoError(t, err)
+	return token
+}
+
+func TestGatewayLogin(t *testing.T) {
+	router, signer := setupGateway(t)
+
+	t.Run("Valid credentials open a session", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/login", strings.NewReader(`{"email":"alice@example.com","password":"secret"}`))
+		w := serve(router, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			User  services.SessionUser `json:"user"`
+			Token string               `json:"token"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, uint(7), response.User.ID)
+
+		claims, err := signer.Verify(response.Token)
+		require.NoError(t, err)
+		assert.Equal(t, uint(7), claims.UserID)
+
+		cookie := w.Result().Cookies()[0]
+		assert.Equal(t, middleware.SessionCookie, cookie.Name)
+		assert.Equal(t, response.Token, cookie.Value)
+		assert.True(t, cookie.HttpOnly)
+
+		// The session identifies the user
+		req = httptest.NewRequest("GET", "/api/session", nil)
+		req.Header.Set("Authorization", "Bearer "+response.Token)
+		w = serve(router, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"username":"alice"`)
+	})
+
+	t.Run("Wrong credentials are passed on", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/login", strings.NewReader(`{"email":"alice@example.com","password":"wrong"}`))
+		w := serve(router, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "Invalid email or password")
+		assert.Empty(t, w.Result().Cookies())
+	})
+}
+
+func TestGatewayCORS(t *testing.T) {
+	router, signer := setupGateway(t)
+
+	t.Run("Preflight is answered by the gateway", func(t *testing.T) {
+		req := httptest.NewRequest("OPTIONS", "/api/objects", nil)
+		req.Header.Set("Origin", "http://localhost")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		req.Header.Set("Access-Control-Request-Headers", "Authorization, Content-Type")
+
+		w, seen := send(router, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "http://localhost", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, seen.Service)
+	})
+
+	t.Run("Service CORS headers are replaced", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/objects", nil)
+		req.Header.Set("Origin", "http://localhost")
+		req.Header.Set("Authorization", bearer(t, signer, 7))
+
+		w, seen := send(router, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, seen.Origin)
+		assert.Equal(t, []string{"http://localhost"}, w.Header().Values("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Unknown origin is refused", func(t *testing.T) {
+		req := httptest.NewRequest("OPTIONS", "/api/objects", nil)
+		req.Header.Set("Origin", "http://evil.example")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+
+		w, _ := send(router, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestGatewayUpstreamDown(t *testing.T) {
+	router, signer := setupGateway(t)
+	t.Setenv("ROOM_SERVICE_URL", "http://127.0.0.1:1")
+	router = newGateway(t, signer)
+
+	req := httptest.NewRequest("GET", "/api/rooms", nil)
+	req.Header.Set("Authorization", bearer(t, signer, 7))
+	w, _ := send(router, req)
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), "room-service is unavailable")
+}
+
+func TestGatewayRequestID(t *testing.T) {
+	router, signer := setupGateway(t)
+
+	t.Run("Client ID is passed on", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/rooms", nil)
+		req.Header.Set("Authorization", bearer(t, signer, 7))
+		req.Header.Set(logging.RequestIDHeader, "frontend-1")
+
+		w, seen := send(router, req)
+
+		assert.Equal(t, "frontend-1", seen.RequestID)
+		assert.Equal(t, []string{"frontend-1"}, w.Header().Values(logging.RequestIDHeader))
+	})
+
+	t.Run("Malformed ID is replaced", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/rooms", nil)
+		req.Header.Set("Authorization", bearer(t, signer, 7))
+		req.Header.Set(logging.RequestIDHeader, "frontend 1")
+
+		w, seen := send(router, req)
+
+		assert.Len(t, seen.RequestID, 32)
+		assert.Equal(t, []string{seen.RequestID}, w.Header().Values(logging.RequestIDHeader))
+	})
+
+	t.Run("Logs carry the request, user and route", func(t *testing.T) {
+		t.Setenv("ROOM_SERVICE_URL", "http://127.0.0.1:1")
+		router := newGateway(t, signer)
+		var logs bytes.Buffer
+		logging.Log.Out = &logs
+
+		req := httptest.NewRequest("GET", "/api/rooms/5", nil)
+		req.Header.Set("Authorization", bearer(t, signer, 7))
+		req.Header.Set(logging.RequestIDHeader, "frontend-2")
+		serve(router, req)
+
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+		assert.Equal(t, "Upstream service unreachable", entry["msg"])
+		assert.Equal(t, "frontend-2", entry["requestId"])
+		assert.Equal(t, "7", entry["userId"])
+		assert.Equal(t, "/api/rooms", entry["route"])
+	})
+}
+
+func TestGatewayHealth(t *testing.T) {
+	router, signer := setupGateway(t)
+
+	// The probes need no session
+	w := serve(router, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Status string                            `json:"status"`
+		Checks map[string]health.DependencyCheck `json:"checks"`
+	}
+	w = serve(router, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, health.OK, body.Status)
+	assert.Len(t, body.Checks, 4)
+
+	t.Run("Service down", func(t *testing.T) {
+		t.Setenv("ROOM_SERVICE_URL", "http://127.0.0.1:1")
+		router := newGateway(t, signer)
+
+		w := serve(router, httptest.NewRequest("GET", "/readyz", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, health.Down, body.Status)
+		assert.Equal(t, health.Down, body.Checks["room-service"].Status)
+		assert.NotEmpty(t, body.Checks["room-service"].Error)
+		assert.Equal(t, health.OK, body.Checks["user-service"].Status)
+	})
+}
+
+func TestGatewayMetrics(t *testing.T) {
+	router, signer := setupGateway(t)
+
+	req := httptest.NewRequest("PUT", "/api/homes/41/settings", nil)
+	req.Header.Set("Authorization", bearer(t, signer, 7))
+	serve(router, req)
+	serve(router, httptest.NewRequest("GET", "/api/objects/42", nil)) // No session
+
+	w := serve(router, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Proxied requests are reported by routing table pattern, not by path
+	assert.Contains(t, w.Body.String(), `http_requests_total{method="PUT",route="/api/homes/*/settings",status="200"}`)
+	assert.Contains(t, w.Body.String(), `http_requests_total{method="GET",route="unmatched",status="401"}`)
+	assert.NotContains(t, w.Body.String(), "/api/homes/41")
+}
+
+func TestGatewayTracing(t *testing.T) {
+	router, signer := setupGateway(t)
+	_, err := tracing.Setup(context.Background(), "gateway-service", tracing.ExporterNone)
+	require.NoError(t, err)
+	spans := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	req := httptest.NewRequest("PUT", "/api/homes/41/settings", nil)
+	req.Header.Set("Authorization", bearer(t, signer, 7))
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w, seen := send(router, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 2)
+	proxied, server := ended[0], ended[1]
+
+	// The gateway continues the client's trace, under the routing table pattern
+	assert.Equal(t, "PUT /api/homes/*/settings", server.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+
+	// and the service receives it with the proxy's span as parent
+	assert.Equal(t, server.SpanContext().SpanID(), proxied.Parent().SpanID())
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+proxied.SpanContext().SpanID().String()+"-01", seen.Traceparent)
+}
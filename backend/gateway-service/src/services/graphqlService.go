@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"hexagone/gateway-service/src/graph"
+	"hexagone/gateway-service/src/middleware"
+	"hexagone/shared/logging"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/sirupsen/logrus"
+)
+
+// GraphQLRequest is the body of POST /api/graphql
+type GraphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// upstreamBackend lets the resolvers call the services as the signed-in user
+type upstreamBackend struct {
+	upstreams *Upstreams
+	userID    string
+}
+
+func (b upstreamBackend) upstream(service graph.Service) *Upstream {
+	switch service {
+	case graph.HomeService:
+		return b.upstreams.Home
+	case graph.RoomService:
+		return b.upstreams.Room
+	case graph.ObjectService:
+		return b.upstreams.Object
+	default:
+		return b.upstreams.User
+	}
+}
+
+func (b upstreamBackend) Get(ctx context.Context, service graph.Service, path string, query url.Values, out interface{}) error {
+	return getData(ctx, b.upstream(service), path, query, b.userID, out)
+}
+
+func (b upstreamBackend) Send(ctx context.Context, service graph.Service, method, path string, body, out interface{}) error {
+	return callUpstream(ctx, b.upstream(service), method, path, nil, body, b.userID, out)
+}
+
+func (b upstreamBackend) UserID() string {
+	return b.userID
+}
+
+// graphQLError answers a request that cannot run, in the shape of a GraphQL
+// response
+func graphQLError(c *gin.Context, errs []gqlerrors.FormattedError) {
+	c.JSON(http.StatusBadRequest, graphql.Result{Errors: errs})
+}
+
+// GraphQL handles POST /api/graphql. The query is parsed, validated and
+// weighed against the limits before anything is fetched; queries that go
+// past them are refused as a whole.
+func GraphQL(upstreams *Upstreams, limits graph.Limits) gin.HandlerFunc {
+	schema, err := graph.NewSchema()
+	if err != nil {
+		// The schema is fixed, so this is a programming error
+		panic("invalid GraphQL schema: " + err.Error())
+	}
+
+	return func(c *gin.Context) {
+		var input GraphQLRequest
+		if err := c.ShouldBindJSON(&input); err != nil {
+			graphQLError(c, gqlerrors.FormatErrors(err))
+			return
+		}
+
+		document, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+			Body: []byte(input.Query),
+			Name: "GraphQL request",
+		})})
+		if err != nil {
+			graphQLError(c, gqlerrors.FormatErrors(err))
+			return
+		}
+
+		if validation := graphql.ValidateDocument(&schema, document, graphql.SpecifiedRules); !validation.IsValid {
+			graphQLError(c, validation.Errors)
+			return
+		}
+
+		if err := limits.Check(&schema, document, input.OperationName); err != nil {
+			logging.RequestLog(c).WithFields(logrus.Fields{
+				"operation": input.OperationName,
+				"error":     err.Error(),
+			}).Warn("Rejected GraphQL query over the limits")
+			graphQLError(c, gqlerrors.FormatErrors(err))
+			return
+		}
+
+		backend := upstreamBackend{upstreams: upstreams, userID: c.Request.Header.Get(middleware.IdentityHeader)}
+		result := graphql.Execute(graphql.ExecuteParams{
+			Schema:        schema,
+			AST:           document,
+			OperationName: input.OperationName,
+			Args:          input.Variables,
+			Context:       graph.NewContext(c.Request.Context(), backend),
+		})
+
+		if len(result.Errors) > 0 {
+			logging.RequestLog(c).WithFields(logrus.Fields{
+				"operation": input.OperationName,
+				"errors":    len(result.Errors),
+				"first":     result.Errors[0].Message,
+			}).Warn("GraphQL query resolved with errors")
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,288 @@
+package services_test
+
+import (
+	"encoding/json"
+	"hexagone/gateway-service/src/auth"
+	"hexagone/gateway-service/src/graph"
+	"hexagone/gateway-service/src/services"
+	"hexagone/shared/logging"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// graphEstate stands in for the four services and records the calls made
+// to them
+type graphEstate struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (e *graphEstate) record(r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = append(e.calls, r.Method+" "+r.URL.RequestURI())
+}
+
+func (e *graphEstate) count(prefix string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n := 0
+	for _, call := range e.calls {
+		if strings.HasPrefix(call, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func (e *graphEstate) serve(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.record(r)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+var graphObjects = map[string]string{
+	"a": `{"id":"a","name":"Teapot","type":"kitchenware","isReserved":true,"reservedBy":"7","reservationStatus":"pending","room_id":"10","home_id":"1"}`,
+	"b": `{"id":"b","name":"Table","type":"furniture","isReserved":false,"reservedBy":"","room_id":"10","home_id":"1"}`,
+	"c": `{"id":"c","name":"Trunk","type":"furniture","isReserved":true,"reservedBy":"8","room_id":"11","home_id":"1"}`,
+	"d": `{"id":"d","name":"Surfboard","type":"sport","isReserved":false,"reservedBy":"","room_id":"20","home_id":"2"}`,
+}
+
+func setupGraphQL(t *testing.T, limits graph.Limits) (*gin.Engine, *graphEstate, string) {
+	logging.InitLogger()
+	gin.SetMode(gin.TestMode)
+	e := &graphEstate{}
+
+	t.Setenv("HOME_SERVICE_URL", e.serve(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.Write([]byte(`{"data":{"id":3,"name":"Chalet"}}`))
+			return
+		}
+		w.Write([]byte(`{"data":[{"id":1,"name":"Grandma's house"},{"id":2,"name":"Beach house"}]}`))
+	}))
+	t.Setenv("ROOM_SERVICE_URL", e.serve(t, func(w http.ResponseWriter, r *http.Request) {
+		rooms := map[string]string{
+			"10": `{"id":10,"name":"Kitchen","home_id":1}`,
+			"11": `{"id":11,"name":"Attic","home_id":1}`,
+			"20": `{"id":20,"name":"Garage","home_id":2}`,
+		}
+		var found []string
+		for _, id := range []string{"10", "11", "20"} {
+			home := map[string]string{"10": "1", "11": "1", "20": "2"}[id]
+			if strings.Contains(","+r.URL.Query().Get("home_id")+",", ","+home+",") ||
+				strings.Contains(","+r.URL.Query().Get("id")+",", ","+id+",") {
+				found = append(found, rooms[id])
+			}
+		}
+		w.Write([]byte(`{"data":[` + strings.Join(found, ",") + `]}`))
+	}))
+	t.Setenv("OBJECT_SERVICE_URL", e.serve(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/objects/room":
+			var found []string
+			for _, id := range []string{"a", "b", "c", "d"} {
+				var object struct {
+					RoomID string `json:"room_id"`
+				}
+				json.Unmarshal([]byte(graphObjects[id]), &object)
+				if strings.Contains(","+r.URL.Query().Get("room_id")+",", ","+object.RoomID+",") {
+					found = append(found, graphObjects[id])
+				}
+			}
+			w.Write([]byte(`{"data":[` + strings.Join(found, ",") + `]}`))
+		case r.URL.Path == "/objects" && r.Method == http.MethodGet:
+			var found []string
+			for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+				if object, ok := graphObjects[id]; ok {
+					found = append(found, object)
+				}
+			}
+			w.Write([]byte(`{"data":[` + strings.Join(found, ",") + `]}`))
+		case r.URL.Path == "/objects/b/reserve":
+			if r.Header.Get("X-User-ID") != "7" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"Authentication required"}`))
+				return
+			}
+			w.Write([]byte(`{"data":{"id":"b","name":"Table","isReserved":true,"reservedBy":"7","reservationStatus":"approved","room_id":"10"}}`))
+		case r.URL.Path == "/objects/a/reserve":
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":"Object is already reserved"}`))
+		case r.URL.Path == "/users/7/reservations":
+			w.Write([]byte(`{"data":[` + graphObjects["a"] + `]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"Not found"}`))
+		}
+	}))
+	t.Setenv("USER_SERVICE_URL", e.serve(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[{"id":7,"username":"alice","email":"alice@example.com","password":"hash","isAdmin":false},{"id":8,"username":"bob","isAdmin":true}]}`))
+	}))
+
+	upstreams := loadUpstreams(t)
+	signer, err := auth.NewSigner("test-key", time.Hour)
+	require.NoError(t, err)
+
+	router := services.NewRouter(services.GatewayConfig{Upstreams: upstreams, Signer: signer, Origins: []string{"http://localhost"}, GraphQL: limits})
+	return router, e, bearer(t, signer, 7)
+}
+
+type graphQLResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func runGraphQL(t *testing.T, router *gin.Engine, authorization, query string, variables map[string]interface{}) (int, graphQLResponse) {
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	req := httptest.NewRequest("POST", "/api/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	w := serve(router, req)
+
+	var response graphQLResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response
+}
+
+func TestGraphQLQueryIsBatched(t *testing.T) {
+	router, e, authorization := setupGraphQL(t, graph.Limits{})
+
+	code, response := runGraphQL(t, router, authorization, `{
+		homes {
+			name
+			rooms {
+				name
+				objects {
+					name
+					reservation { status user { username } }
+				}
+			}
+		}
+	}`, nil)
+
+	require.Equal(t, http.StatusOK, code)
+	require.Empty(t, response.Errors)
+
+	data, _ := json.Marshal(response.Data)
+	assert.JSONEq(t, `{"homes":[
+		{"name":"Grandma's house","rooms":[
+			{"name":"Kitchen","objects":[
+				{"name":"Teapot","reservation":{"status":"pending","user":{"username":"alice"}}},
+				{"name":"Table","reservation":null}]},
+			{"name":"Attic","objects":[
+				{"name":"Trunk","reservation":{"status":"approved","user":{"username":"bob"}}}]}]},
+		{"name":"Beach house","rooms":[
+			{"name":"Garage","objects":[{"name":"Surfboard","reservation":null}]}]}]}`, string(data))
+
+	// One call per service and level, whatever the number of homes and rooms
+	assert.Equal(t, 1, e.count("GET /homes"))
+	assert.Equal(t, 1, e.count("GET /rooms?home_id=1%2C2"))
+	assert.Equal(t, 1, e.count("GET /objects/room"))
+	assert.Equal(t, 1, e.count("GET /users"))
+}
+
+func TestGraphQLLookups(t *testing.T) {
+	router, e, authorization := setupGraphQL(t, graph.Limits{})
+
+	code, response := runGraphQL(t, router, authorization, `{
+		first: object(id: "a") { name room { name home { name } } }
+		second: object(id: "c") { name room { name } }
+		missing: object(id: "z") { name }
+		me { username reservations { name } }
+	}`, nil)
+
+	require.Equal(t, http.StatusOK, code)
+	require.Empty(t, response.Errors)
+	data, _ := json.Marshal(response.Data)
+	assert.JSONEq(t, `{
+		"first":{"name":"Teapot","room":{"name":"Kitchen","home":{"name":"Grandma's house"}}},
+		"second":{"name":"Trunk","room":{"name":"Attic"}},
+		"missing":null,
+		"me":{"username":"alice","reservations":[{"name":"Teapot"}]}}`, string(data))
+
+	assert.Equal(t, 1, e.count("GET /objects?ids="))
+	assert.Equal(t, 1, e.count("GET /rooms?id="))
+}
+
+func TestGraphQLMutations(t *testing.T) {
+	router, _, authorization := setupGraphQL(t, graph.Limits{})
+
+	t.Run("Reserve for the signed-in user", func(t *testing.T) {
+		code, response := runGraphQL(t, router, authorization, `mutation($id: ID!) {
+			reserveObject(id: $id) { id isReserved reservation { status user { username } } }
+		}`, map[string]interface{}{"id": "b"})
+
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, response.Errors)
+		data, _ := json.Marshal(response.Data)
+		assert.JSONEq(t, `{"reserveObject":{"id":"b","isReserved":true,"reservation":{"status":"approved","user":{"username":"alice"}}}}`, string(data))
+	})
+
+	t.Run("Service errors are reported", func(t *testing.T) {
+		code, response := runGraphQL(t, router, authorization, `mutation { reserveObject(id: "a") { id } }`, nil)
+
+		assert.Equal(t, http.StatusOK, code)
+		require.Len(t, response.Errors, 1)
+		assert.Equal(t, "409 Conflict: Object is already reserved", response.Errors[0].Message)
+	})
+
+	t.Run("Create a home", func(t *testing.T) {
+		code, response := runGraphQL(t, router, authorization, `mutation { createHome(name: "Chalet") { id name rooms { name } } }`, nil)
+
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, response.Errors)
+		data, _ := json.Marshal(response.Data)
+		assert.JSONEq(t, `{"createHome":{"id":"3","name":"Chalet","rooms":[]}}`, string(data))
+	})
+}
+
+func TestGraphQLLimits(t *testing.T) {
+	router, e, authorization := setupGraphQL(t, graph.Limits{MaxDepth: 4, MaxComplexity: 250})
+
+	tests := []struct {
+		name, query, message string
+	}{
+		{"Too deep", `{ homes { rooms { objects { room { name } } } } }`, "query depth 5 exceeds the limit of 4"},
+		{"Too deep through a fragment", `{ homes { ...deep } } fragment deep on Home { rooms { objects { room { name } } } }`, "query depth 5 exceeds the limit of 4"},
+		{"Too complex", `{ homes { rooms { objects { name type } } } }`, "query complexity 3110 exceeds the limit of 250"},
+		{"Unknown field", `{ homes { address } }`, `Cannot query field "address" on type "Home".`},
+		{"Syntax error", `{ homes {`, "Syntax Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, response := runGraphQL(t, router, authorization, tt.query, nil)
+
+			assert.Equal(t, http.StatusBadRequest, code)
+			require.NotEmpty(t, response.Errors)
+			assert.Contains(t, response.Errors[0].Message, tt.message)
+		})
+	}
+	assert.Empty(t, e.calls, "rejected queries should not reach the services")
+
+	t.Run("Within the limits", func(t *testing.T) {
+		code, response := runGraphQL(t, router, authorization, `{ homes { rooms { name } } __schema { types { name fields { name type { name ofType { name } } } } } }`, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, response.Errors)
+	})
+
+	t.Run("Session required", func(t *testing.T) {
+		code, _ := runGraphQL(t, router, "", `{ homes { name } }`, nil)
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hexagone/shared/health"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz handles GET /healthz: the process is up and serving requests
+var Healthz = health.Healthz("gateway-service")
+
+// Readyz handles GET /readyz: checks that the four services are alive, and
+// answers 503 when any of them is not. Only their liveness is checked, so
+// that one dependency going down does not mark every service unready. Each
+// service is waited on for its own timeout.
+func Readyz(upstreams *Upstreams) gin.HandlerFunc {
+	dependencies := map[string]health.Dependency{}
+	for _, upstream := range []*Upstream{upstreams.Home, upstreams.Room, upstreams.Object, upstreams.User} {
+		dependencies[upstream.Name] = health.Dependency{
+			Check:   func(ctx context.Context) error { return pingUpstream(ctx, upstream) },
+			Timeout: upstream.Timeout,
+		}
+	}
+	return health.ReadyzDependencies("gateway-service", dependencies)
+}
+
+// pingUpstream calls the /healthz of a service
+func pingUpstream(ctx context.Context, upstream *Upstream) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL.JoinPath("/healthz").String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET /healthz: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"hexagone/gateway-service/src/auth"
+	"hexagone/gateway-service/src/graph"
+	"hexagone/gateway-service/src/middleware"
+	"hexagone/shared/logging"
+	"hexagone/shared/metrics"
+	"hexagone/shared/tracing"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GatewayConfig is what the gateway router is built from
+type GatewayConfig struct {
+	Upstreams    *Upstreams
+	Signer       *auth.Signer
+	Origins      []string
+	SecureCookie bool         // Only send the session cookie over HTTPS
+	GraphQL      graph.Limits // graph.DefaultLimits when zero
+}
+
+// isPublic tells the proxied requests that need no session: signing up, and
+// downloading media, whose URLs carry their own signature
+func isPublic(r *http.Request) bool {
+	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, APIPrefix+"/media/") {
+		return true
+	}
+	return r.Method == http.MethodPost && strings.TrimSuffix(r.URL.Path, "/") == APIPrefix+"/users"
+}
+
+// authenticate requires a session on every proxied request but the public ones
+func authenticate(signer *auth.Signer) gin.HandlerFunc {
+	requireSession := middleware.RequireSession(signer)
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.Request.URL.Path, APIPrefix+"/") {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			c.Abort()
+			return
+		}
+		if isPublic(c.Request) {
+			c.Next()
+			return
+		}
+		requireSession(c)
+	}
+}
+
+// NewRouter builds the gateway: CORS for the whole API, the session
+// endpoints, and the proxy to the services for every other path
+func NewRouter(config GatewayConfig) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Logger(), gin.Recovery())
+	r.Use(metrics.Middleware())
+
+	r.Use(middleware.SetupCORS(config.Origins))
+	r.Use(middleware.StripIdentity())
+
+	// Liveness and readiness probes, outside the API
+	r.GET("/healthz", Healthz)                 // The process is up
+	r.GET("/readyz", Readyz(config.Upstreams)) // The services behind the gateway are alive
+
+	// Prometheus metrics
+	r.GET("/metrics", metrics.Handler())
+
+	// Trace the API below, the probes and scrapes above are left out
+	r.Use(tracing.Middleware())
+	// Give every request an ID and a logger, once its trace has started
+	r.Use(logging.RequestID())
+
+	// Session routes
+	r.POST(APIPrefix+"/login", Login(config.Upstreams.User, config.Signer, config.SecureCookie)) // Check credentials and open a session
+	r.POST(APIPrefix+"/logout", Logout(config.SecureCookie))                                     // Clear the session cookie
+	r.GET(APIPrefix+"/session", middleware.RequireSession(config.Signer), Me)                    // User of the current session
+
+	// Aggregates
+	r.GET(APIPrefix+"/homes/:id/tree", middleware.RequireSession(config.Signer), HomeTree(config.Upstreams)) // Home with its rooms, objects and reservation summaries
+
+	limits := config.GraphQL
+	if limits == (graph.Limits{}) {
+		limits = graph.DefaultLimits
+	}
+	r.POST(APIPrefix+"/graphql", middleware.RequireSession(config.Signer), GraphQL(config.Upstreams, limits)) // Homes, rooms, objects and users as a GraphQL schema
+
+	// Everything else is forwarded to the service owning the path
+	r.NoRoute(authenticate(config.Signer), Proxy(RoutingTable(config.Upstreams)))
+
+	return r
+}
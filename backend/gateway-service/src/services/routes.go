@@ -0,0 +1,190 @@
+package services
+
+import (
+	"hexagone/gateway-service/src/config"
+	"hexagone/gateway-service/src/middleware"
+	sharedauth "hexagone/shared/auth"
+	"hexagone/shared/httpclient"
+	"hexagone/shared/logging"
+	"hexagone/shared/metrics"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	pathpkg "path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// APIPrefix is where the gateway serves the services' routes
+const APIPrefix = "/api"
+
+// Upstream is a service behind the gateway
+type Upstream struct {
+	Name         string
+	URL          *url.URL
+	Timeout      time.Duration // Deadline of each call the gateway makes itself
+	ServiceToken string        // Sent with the identity header, which the services only trust with it
+	proxy        *httputil.ReverseProxy
+}
+
+// Upstreams are the four services, in the order of the routing table
+type Upstreams struct {
+	Home, Room, Object, User *Upstream
+}
+
+// Route sends the paths matching Pattern to Upstream. The pattern is split
+// in segments, "*" matching any one segment, and matches the paths it is a
+// prefix of. An Upstream of nil keeps the path out of the public API.
+type Route struct {
+	Pattern  string
+	Upstream *Upstream
+}
+
+// NewUpstream builds the reverse proxy of a service
+func NewUpstream(name, base, serviceToken string) (*Upstream, error) {
+	target, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+
+	upstream := &Upstream{Name: name, URL: target, Timeout: config.DefaultServiceTimeout, ServiceToken: serviceToken}
+	upstream.proxy = &httputil.ReverseProxy{
+		// Replaces the client's traceparent with the gateway's span, and its
+		// X-Request-ID with the one the gateway settled on
+		Transport: httpclient.Transport(nil),
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			r.SetXForwarded()
+			// The gateway has already answered for CORS; without an Origin
+			// the services' own CORS middleware stays out of the way
+			r.Out.Header.Del("Origin")
+			if r.Out.Header.Get(middleware.IdentityHeader) != "" {
+				r.Out.Header.Set(sharedauth.ServiceTokenHeader, serviceToken)
+			}
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			// The gateway has already set the request ID of the response
+			resp.Header.Del(logging.RequestIDHeader)
+			for header := range resp.Header {
+				if strings.HasPrefix(header, "Access-Control-") {
+					resp.Header.Del(header)
+				}
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			logging.LogFrom(r.Context()).WithFields(logrus.Fields{
+				"upstream": name,
+				"path":     r.URL.Path,
+				"error":    err.Error(),
+			}).Error("Upstream service unreachable")
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`{"error":"` + name + ` is unavailable"}`))
+		},
+	}
+	return upstream, nil
+}
+
+// NewUpstreams builds the proxies of the services at the addresses and with
+// the timeouts of the configuration. The services are told who is calling
+// along with serviceToken.
+func NewUpstreams(services config.Services, serviceToken string) (*Upstreams, error) {
+	var upstreams Upstreams
+	for _, service := range []struct {
+		target **Upstream
+		name   string
+		config config.Service
+	}{
+		{&upstreams.Home, "home-service", services.Home},
+		{&upstreams.Room, "room-service", services.Room},
+		{&upstreams.Object, "object-service", services.Object},
+		{&upstreams.User, "user-service", services.User},
+	} {
+		upstream, err := NewUpstream(service.name, service.config.URL, serviceToken)
+		if err != nil {
+			return nil, err
+		}
+		if service.config.Timeout > 0 {
+			upstream.Timeout = service.config.Timeout
+		}
+		*service.target = upstream
+	}
+	return &upstreams, nil
+}
+
+// RoutingTable maps the public API onto the services. The first matching
+// route wins, so the routes under /homes and /users that belong to the
+// object service come before the home and user services' own.
+func RoutingTable(u *Upstreams) []Route {
+	return []Route{
+		{"/homes/*/export", u.Home},
+		{"/homes/*/settings", u.Object},
+		{"/homes/*/import", u.Object},
+		{"/homes/*/pickup-slots", u.Object},
+		{"/homes/*/pickup-schedule", u.Object},
+		{"/homes/*/dispositions", u.Object},
+		{"/homes", u.Home},
+		{"/rooms", u.Room},
+		{"/search/documents", nil}, // Written by the services themselves
+		{"/objects", u.Object},
+		{"/categories", u.Object},
+		{"/search", u.Object},
+		{"/labels", u.Object},
+		{"/media", u.Object}, // Signed photo downloads of the local store
+		{"/pickup-slots", u.Object},
+		{"/pickup-bookings", u.Object},
+		{"/me", u.Object},
+		{"/users/*/reservations", u.Object},
+		{"/users/*/history", u.Object},
+		{"/users", u.User},
+	}
+}
+
+// matches reports whether path starts with the segments of pattern
+func matches(pattern, path string) bool {
+	want := strings.Split(strings.Trim(pattern, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(got) < len(want) {
+		return false
+	}
+	for i, segment := range want {
+		if segment != "*" && segment != got[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve returns the route a path of the service API belongs to
+func Resolve(routes []Route, path string) (Route, bool) {
+	for _, route := range routes {
+		if matches(route.Pattern, path) {
+			return route, route.Upstream != nil
+		}
+	}
+	return Route{}, false
+}
+
+// Proxy forwards the requests under APIPrefix to the service the routing
+// table names, without the prefix
+func Proxy(routes []Route) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Cleaned so that dot segments cannot reach around the table
+		path := pathpkg.Clean("/" + strings.TrimPrefix(c.Request.URL.Path, APIPrefix))
+		route, ok := Resolve(routes, path)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			return
+		}
+
+		c.Set(metrics.RouteKey, APIPrefix+route.Pattern)
+		logging.AddLogFields(c, logrus.Fields{"route": APIPrefix + route.Pattern})
+		c.Request.URL.Path = path
+		c.Request.URL.RawPath = ""
+		route.Upstream.proxy.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"hexagone/gateway-service/src/auth"
+	"hexagone/gateway-service/src/middleware"
+	"hexagone/shared/httpclient"
+	"hexagone/shared/logging"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// loginClient calls the user service to check credentials
+var loginClient = httpclient.New(10 * time.Second)
+
+// SessionUser is the user the user service returns on login
+type SessionUser struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	IsAdmin  bool   `json:"isAdmin"`
+}
+
+// Login handles POST /api/login: has the user service check the credentials
+// and, when they are right, opens a session. The token is returned in the
+// body for API clients and set as an HttpOnly cookie for browsers.
+func Login(users *Upstream, signer *auth.Signer, secureCookie bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+
+		req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, users.URL.JoinPath("/login").String(), bytes.NewReader(body))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build login request"})
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := loginClient.Do(req)
+		if err != nil {
+			logging.RequestLog(c).WithError(err).Error("Failed to contact user service for login")
+			c.JSON(http.StatusBadGateway, gin.H{"error": "user-service is unavailable"})
+			return
+		}
+		defer resp.Body.Close()
+
+		// Wrong credentials and invalid input are passed on as they are
+		if resp.StatusCode != http.StatusOK {
+			c.DataFromReader(resp.StatusCode, resp.ContentLength, resp.Header.Get("Content-Type"), resp.Body, nil)
+			return
+		}
+
+		var response struct {
+			Message string      `json:"message"`
+			User    SessionUser `json:"user"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil || response.User.ID == 0 {
+			logging.RequestLog(c).Error("Failed to decode login response from user service")
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Unexpected response from user-service"})
+			return
+		}
+
+		token, claims, err := signer.Issue(response.User.ID, response.User.Username)
+		if err != nil {
+			logging.RequestLog(c).WithError(err).Error("Failed to issue session token")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open session"})
+			return
+		}
+
+		expiresAt := time.Unix(claims.ExpiresAt, 0).UTC()
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(middleware.SessionCookie, token, int(time.Until(expiresAt).Seconds()), "/", "", secureCookie, true)
+
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"userID":    response.User.ID,
+			"expiresAt": expiresAt,
+		}).Info("Session opened")
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":   response.Message,
+			"user":      response.User,
+			"token":     token,
+			"expiresAt": expiresAt,
+		})
+	}
+}
+
+// Logout handles POST /api/logout: clears the session cookie. Tokens are
+// not kept anywhere, so a copied token stays valid until it expires.
+func Logout(secureCookie bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(middleware.SessionCookie, "", -1, "/", "", secureCookie, true)
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+	}
+}
+
+// Me handles GET /api/session: the user the session token belongs to
+func Me(c *gin.Context) {
+	claims := c.MustGet(middleware.ClaimsKey).(auth.Claims)
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{
+		"userId":    claims.UserID,
+		"username":  claims.Username,
+		"expiresAt": time.Unix(claims.ExpiresAt, 0).UTC(),
+	}})
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hexagone/gateway-service/src/middleware"
+	"hexagone/gateway-service/src/models"
+	"hexagone/shared/logging"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxConcurrentRooms bounds the object service calls made at once for the
+// rooms of a home
+const maxConcurrentRooms = 8
+
+var errHomeNotFound = errors.New("home not found")
+
+// fetchHome finds a home in the list of the home service, which has no
+// route for a single home
+func fetchHome(ctx context.Context, upstream *Upstream, homeID uint, userID string) (*models.Home, error) {
+	var homes []models.Home
+	if err := getData(ctx, upstream, "/homes", nil, userID, &homes); err != nil {
+		return nil, err
+	}
+	for _, home := range homes {
+		if home.ID == homeID {
+			return &home, nil
+		}
+	}
+	return nil, errHomeNotFound
+}
+
+// summarize counts the objects of a room by reservation status
+func summarize(objects []json.RawMessage) models.ReservationSummary {
+	summary := models.ReservationSummary{Objects: len(objects)}
+	for _, raw := range objects {
+		var object models.Object
+		json.Unmarshal(raw, &object)
+
+		switch object.CurrentStatus() {
+		case "pending":
+			summary.Pending++
+		case "approved":
+			summary.Approved++
+		case "picked_up":
+			summary.PickedUp++
+		default: // Unclaimed, or the claim was rejected
+			summary.Available++
+		}
+	}
+	return summary
+}
+
+// HomeTree handles GET /api/homes/:id/tree: the home, its rooms and every
+// object in them with reservation summaries, in one response. The home and
+// its rooms are read at the same time, then the objects of all rooms. A
+// service that fails or runs past its timeout leaves its part out of the
+// tree, which is then flagged as partial.
+func HomeTree(upstreams *Upstreams) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		homeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid home ID"})
+			return
+		}
+		ctx := c.Request.Context()
+		userID := c.Request.Header.Get(middleware.IdentityHeader)
+
+		tree := models.HomeTree{Rooms: []models.TreeRoom{}, Errors: []models.TreeError{}}
+		var homeErr, roomsErr error
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			tree.Home, homeErr = fetchHome(ctx, upstreams.Home, uint(homeID), userID)
+		}()
+		go func() {
+			defer wg.Done()
+			roomsErr = getData(ctx, upstreams.Room, "/rooms", url.Values{"home_id": {c.Param("id")}}, userID, &tree.Rooms)
+		}()
+		wg.Wait()
+
+		if homeErr == errHomeNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Home not found"})
+			return
+		}
+		if homeErr != nil && roomsErr != nil {
+			logging.RequestLog(c).WithFields(logrus.Fields{
+				"homeID":    homeID,
+				"homeError": homeErr.Error(),
+				"roomError": roomsErr.Error(),
+			}).Error("Failed to read home and rooms for home tree")
+			c.JSON(http.StatusBadGateway, gin.H{"error": "The home and room services are unavailable"})
+			return
+		}
+		if homeErr != nil {
+			tree.Errors = append(tree.Errors, models.TreeError{Service: upstreams.Home.Name, Error: homeErr.Error()})
+		}
+		if roomsErr != nil {
+			tree.Rooms = []models.TreeRoom{}
+			tree.Errors = append(tree.Errors, models.TreeError{Service: upstreams.Room.Name, Error: roomsErr.Error()})
+		}
+
+		roomErrs := make([]error, len(tree.Rooms))
+		slots := make(chan struct{}, maxConcurrentRooms)
+		for i := range tree.Rooms {
+			wg.Add(1)
+			go func(room *models.TreeRoom, errp *error) {
+				defer wg.Done()
+				slots <- struct{}{}
+				defer func() { <-slots }()
+
+				room.Objects = []json.RawMessage{}
+				query := url.Values{"room_id": {strconv.FormatUint(uint64(room.ID), 10)}}
+				if *errp = getData(ctx, upstreams.Object, "/objects/room", query, userID, &room.Objects); *errp != nil {
+					room.Objects = []json.RawMessage{}
+					return
+				}
+				room.Complete = true
+				room.Summary = summarize(room.Objects)
+			}(&tree.Rooms[i], &roomErrs[i])
+		}
+		wg.Wait()
+
+		for i, room := range tree.Rooms {
+			if roomErrs[i] != nil {
+				tree.Errors = append(tree.Errors, models.TreeError{Service: upstreams.Object.Name, RoomID: room.ID, Error: roomErrs[i].Error()})
+			}
+			tree.Summary.Add(room.Summary)
+		}
+		tree.Partial = len(tree.Errors) > 0
+
+		if tree.Partial {
+			logging.RequestLog(c).WithFields(logrus.Fields{
+				"homeID": homeID,
+				"errors": tree.Errors,
+			}).Warn("Home tree built with missing parts")
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": tree})
+	}
+}
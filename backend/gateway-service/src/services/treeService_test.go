@@ -0,0 +1,200 @@
+package services_test
+
+import (
+	"encoding/json"
+	"hexagone/gateway-service/src/auth"
+	"hexagone/gateway-service/src/models"
+	"hexagone/shared/logging"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// estate serves the home, rooms and objects of a small house. Room 12 is
+// slow and room 13 broken, when asked.
+type estate struct {
+	slowRoom, brokenRoom bool
+	inFlight, maxFlight  int32
+	objectUser           atomic.Value
+	objectToken          atomic.Value
+}
+
+func (e *estate) servers(t *testing.T) (home, room, object *httptest.Server) {
+	home = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[{"id":1,"name":"Grandma's house"},{"id":2,"name":"Beach house"}]}`))
+	}))
+	room = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("home_id") != "1" {
+			w.Write([]byte(`{"data":[]}`))
+			return
+		}
+		w.Write([]byte(`{"data":[{"id":10,"name":"Kitchen","home_id":1},{"id":11,"name":"Attic","home_id":1},{"id":12,"name":"Cellar","home_id":1},{"id":13,"name":"Garage","home_id":1}]}`))
+	}))
+	object = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.objectUser.Store(r.Header.Get("X-User-ID"))
+		e.objectToken.Store(r.Header.Get("X-Service-Token"))
+		flight := atomic.AddInt32(&e.inFlight, 1)
+		defer atomic.AddInt32(&e.inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&e.maxFlight)
+			if flight <= max || atomic.CompareAndSwapInt32(&e.maxFlight, max, flight) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		switch r.URL.Query().Get("room_id") {
+		case "10":
+			w.Write([]byte(`{"data":[
+				{"id":"a","name":"Teapot","isReserved":true,"reservedBy":"7","reservationStatus":"pending","room_id":"10"},
+				{"id":"b","name":"Table","isReserved":true,"reservedBy":"8","reservationStatus":"approved","room_id":"10"},
+				{"id":"c","name":"Chair","isReserved":false,"reservedBy":"","room_id":"10"}]}`))
+		case "11":
+			w.Write([]byte(`{"data":[
+				{"id":"d","name":"Trunk","isReserved":true,"reservedBy":"7","room_id":"11"},
+				{"id":"e","name":"Lamp","isReserved":true,"reservedBy":"9","reservationStatus":"picked_up","room_id":"11"}]}`))
+		case "12":
+			if e.slowRoom {
+				time.Sleep(500 * time.Millisecond)
+			}
+			w.Write([]byte(`{"data":[]}`))
+		case "13":
+			if e.brokenRoom {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"error":"Failed to fetch objects"}`))
+				return
+			}
+			w.Write([]byte(`{"data":[]}`))
+		}
+	}))
+	for _, server := range []*httptest.Server{home, room, object} {
+		t.Cleanup(server.Close)
+	}
+	return home, room, object
+}
+
+func setupTree(t *testing.T, e *estate) (*gin.Engine, string) {
+	logging.InitLogger()
+	gin.SetMode(gin.TestMode)
+
+	home, room, object := e.servers(t)
+	t.Setenv("HOME_SERVICE_URL", home.URL)
+	t.Setenv("ROOM_SERVICE_URL", room.URL)
+	t.Setenv("OBJECT_SERVICE_URL", object.URL)
+	t.Setenv("USER_SERVICE_URL", fakeService(t, "user-service").URL)
+	t.Setenv("OBJECT_SERVICE_TIMEOUT", "200ms")
+
+	signer, err := auth.NewSigner("test-key", time.Hour)
+	require.NoError(t, err)
+	return newGateway(t, signer), bearer(t, signer, 7)
+}
+
+func getTree(t *testing.T, router *gin.Engine, authorization, homeID string) (*httptest.ResponseRecorder, models.HomeTree) {
+	req := httptest.NewRequest("GET", "/api/homes/"+homeID+"/tree", nil)
+	req.Header.Set("Authorization", authorization)
+	w := serve(router, req)
+
+	var response struct {
+		Data models.HomeTree `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response.Data
+}
+
+func TestHomeTree(t *testing.T) {
+	e := &estate{}
+	router, authorization := setupTree(t, e)
+
+	w, tree := getTree(t, router, authorization, "1")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, tree.Partial)
+	assert.Empty(t, tree.Errors)
+	assert.Equal(t, "Grandma's house", tree.Home.Name)
+	require.Len(t, tree.Rooms, 4)
+
+	kitchen := tree.Rooms[0]
+	assert.Equal(t, "Kitchen", kitchen.Name)
+	assert.True(t, kitchen.Complete)
+	assert.Len(t, kitchen.Objects, 3)
+	assert.Equal(t, models.ReservationSummary{Objects: 3, Available: 1, Pending: 1, Approved: 1}, kitchen.Summary)
+	assert.Equal(t, models.ReservationSummary{Objects: 2, Approved: 1, PickedUp: 1}, tree.Rooms[1].Summary)
+	assert.Equal(t, models.ReservationSummary{Objects: 5, Available: 1, Pending: 1, Approved: 2, PickedUp: 1}, tree.Summary)
+
+	// Rooms are read at the same time, for the signed-in user
+	assert.Greater(t, atomic.LoadInt32(&e.maxFlight), int32(1))
+	assert.Equal(t, "7", e.objectUser.Load())
+	assert.Equal(t, testServiceToken, e.objectToken.Load())
+}
+
+func TestHomeTreePartial(t *testing.T) {
+	e := &estate{slowRoom: true, brokenRoom: true}
+	router, authorization := setupTree(t, e)
+
+	start := time.Now()
+	w, tree := getTree(t, router, authorization, "1")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Less(t, time.Since(start), 450*time.Millisecond, "the slow room should time out")
+	assert.True(t, tree.Partial)
+	require.Len(t, tree.Errors, 2)
+	assert.Equal(t, models.TreeError{Service: "object-service", RoomID: 12, Error: "no answer within 200ms"}, tree.Errors[0])
+	assert.Equal(t, uint(13), tree.Errors[1].RoomID)
+	assert.Contains(t, tree.Errors[1].Error, "500")
+
+	assert.True(t, tree.Rooms[0].Complete)
+	assert.False(t, tree.Rooms[2].Complete)
+	assert.False(t, tree.Rooms[3].Complete)
+	assert.Equal(t, 5, tree.Summary.Objects)
+}
+
+func TestHomeTreeServiceDown(t *testing.T) {
+	e := &estate{}
+	router, authorization := setupTree(t, e)
+	signer, err := auth.NewSigner("test-key", time.Hour)
+	require.NoError(t, err)
+
+	t.Run("Home service down", func(t *testing.T) {
+		t.Setenv("HOME_SERVICE_URL", "http://127.0.0.1:1")
+		router := newGateway(t, signer)
+
+		w, tree := getTree(t, router, authorization, "1")
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, tree.Partial)
+		assert.Nil(t, tree.Home)
+		assert.Equal(t, "home-service", tree.Errors[0].Service)
+		assert.Len(t, tree.Rooms, 4)
+	})
+
+	t.Run("Home and room services down", func(t *testing.T) {
+		t.Setenv("HOME_SERVICE_URL", "http://127.0.0.1:1")
+		t.Setenv("ROOM_SERVICE_URL", "http://127.0.0.1:1")
+		router := newGateway(t, signer)
+
+		w, _ := getTree(t, router, authorization, "1")
+
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+	})
+
+	t.Run("Unknown home", func(t *testing.T) {
+		w, _ := getTree(t, router, authorization, "99")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Invalid home ID", func(t *testing.T) {
+		w, _ := getTree(t, router, authorization, "abc")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Session required", func(t *testing.T) {
+		w, _ := getTree(t, router, "", "1")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hexagone/gateway-service/src/middleware"
+	sharedauth "hexagone/shared/auth"
+	"hexagone/shared/httpclient"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// upstreamClient makes the calls the gateway issues itself, for aggregates
+// and GraphQL; each call is bounded by the timeout of its upstream
+var upstreamClient = httpclient.New(0)
+
+// UpstreamError is a service answering with an error status
+type UpstreamError struct {
+	Status  int
+	Message string // The "error" field of the response, when there is one
+}
+
+func (e *UpstreamError) Error() string {
+	status := fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status))
+	if e.Message == "" {
+		return status
+	}
+	return status + ": " + e.Message
+}
+
+// callUpstream sends a request to a service for the signed-in user and
+// decodes the "data" field of the response into out, giving up after the
+// upstream's timeout. body is sent as JSON when not nil.
+func callUpstream(ctx context.Context, upstream *Upstream, method, path string, query url.Values, body interface{}, userID string, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, upstream.Timeout)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	target := upstream.URL.JoinPath(path)
+	target.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set(middleware.IdentityHeader, userID)
+	req.Header.Set(sharedauth.ServiceTokenHeader, upstream.ServiceToken)
+
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("no answer within %s", upstream.Timeout)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var response struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&response)
+		return &UpstreamError{Status: resp.StatusCode, Message: response.Error}
+	}
+
+	response := struct {
+		Data interface{} `json:"data"`
+	}{Data: out}
+	return json.NewDecoder(resp.Body).Decode(&response)
+}
+
+// getData reads from a service for the signed-in user
+func getData(ctx context.Context, upstream *Upstream, path string, query url.Values, userID string, out interface{}) error {
+	return callUpstream(ctx, upstream, http.MethodGet, path, query, nil, userID, out)
+}
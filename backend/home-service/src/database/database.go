@@ -2,7 +2,9 @@ package database
 
 import (
 	"hexagone/home-service/src/models"
-	"hexagone/home-service/src/utils"
+	"hexagone/shared/logging"
+	"hexagone/shared/metrics"
+	"hexagone/shared/tracing"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -11,18 +13,45 @@ import (
 var DB *gorm.DB
 
 
-func ConnectDatabase(dbPath string) {
+// ConnectDatabase opens the SQLite database and migrates its schema
+func ConnectDatabase(dbPath string) error {
 	var err error
 	DB, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
 	if err != nil {
-		utils.Log.WithField("error", err.Error()).Error("Failed to connect to database")
+		logging.Log.WithField("error", err.Error()).Error("Failed to connect to database")
+		return err
 	}
 
-	utils.Log.Info("Home database connected successfully!")
+	logging.Log.Info("Home database connected successfully!")
 
-	// Migrate the schema for Room
+	// Time every statement for the /metrics endpoint
+	if err := metrics.InstrumentGORM(DB); err != nil {
+		logging.Log.WithField("error", err.Error()).Error("Failed to instrument database")
+		return err
+	}
+
+	// Trace every statement run with a request context
+	if err := tracing.InstrumentGORM(DB); err != nil {
+		logging.Log.WithField("error", err.Error()).Error("Failed to instrument database")
+		return err
+	}
+
+	// Migrate the schema for Home
 	err = DB.AutoMigrate(&models.Home{})
 	if err != nil {
-		utils.Log.WithField("error", err.Error()).Error("Failed to connect to database")
+		logging.Log.WithField("error", err.Error()).Error("Failed to migrate database")
+	}
+	return err
+}
+
+// Close closes the connections to the database, once the server has stopped
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
 	}
+	return sqlDB.Close()
 }
@@ -1,56 +1,101 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"hexagone/home-service/src/config"
 	"hexagone/home-service/src/database"
-	"hexagone/home-service/src/middleware"
 	"hexagone/home-service/src/services"
-	"hexagone/home-service/src/utils"
-	"os"
+	"hexagone/shared/auth"
+	"hexagone/shared/logging"
+	"hexagone/shared/metrics"
+	"hexagone/shared/middleware"
+	"hexagone/shared/server"
+	"hexagone/shared/tracing"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// The logger comes first, so that configuration errors can be reported
+	logging.InitLogger()
+
 	// Get configuration from environment variables
-	port := os.Getenv("PORT")
-	if port == "" {
-		utils.Log.Error("PORT is not set in the environment variables")
+	cfg, err := config.Load()
+	if err != nil {
+		logging.Log.Fatalf("Invalid configuration: %v", err)
+	}
+	logging.Configure(cfg.Logging.Level, cfg.Logging.Format)
+	logging.Log.Info("Starting Home Service")
+
+	// Set up tracing before anything makes a call worth tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), "home-service", cfg.TracesExporter)
+	if err != nil {
+		logging.Log.Fatalf("Failed to set up tracing: %v", err)
 	}
 
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		utils.Log.Error("DB_PATH is not set in the environment variables")
+	// Connect to the database
+	if err := database.ConnectDatabase(cfg.DBPath); err != nil {
+		logging.Log.Fatalf("Failed to open the database: %v", err)
 	}
+	logging.Log.Info("Connected to SQLite")
 
-	// Initialize logger
-	utils.InitLogger()
-	
-	// Initialize database
-	database.ConnectDatabase(dbPath)
+	// Search updates to the object service carry the internal service token
+	services.ServiceToken = cfg.ServiceToken
 
 	// Set up Gin router
 	r := gin.Default()
-	utils.Log.Info("Starting Home Service")
 
+	// Count and time every request, for the /metrics endpoint
+	r.Use(metrics.Middleware())
 	r.Use(middleware.SetupCORS())
 
+	// Liveness and readiness probes
+	r.GET("/healthz", services.Healthz)                    // The process is up
+	r.GET("/readyz", services.Readyz(cfg.UserService.URL)) // The database and the services it depends on answer
+
+	// Prometheus metrics
+	r.GET("/metrics", metrics.Handler())
+
+	// Trace the routes below, the probes and scrapes above are left out
+	r.Use(tracing.Middleware())
+	// Give every request an ID and a logger, once its trace has started
+	r.Use(logging.RequestID())
+	// Only trust the user the gateway vouches for with the service token
+	r.Use(auth.TrustIdentity(cfg.ServiceToken))
+
 	// Routes
 	r.POST("/homes", services.CreateHome)
 	r.GET("/homes", services.ListHomes)
+	r.GET("/homes/:id/export", services.ExportHome) // Inventory as JSON, CSV or PDF (?format=)
 
 	adminRoutes := r.Group("/")
-    adminRoutes.Use(middleware.RequireAdmin())
+    adminRoutes.Use(auth.RequireAdmin(auth.NewUsers(cfg.UserService)))
 	adminRoutes.Use(middleware.SetupCORS())
     {
         adminRoutes.DELETE("/homes/:id", services.DeleteHome)
     }
 
 
-	utils.Log.Infof("Starting HTTP server on port %s", port)
+	// Stop on SIGTERM (docker stop) or Ctrl-C, after the requests in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	// Start the server
-	if err := r.Run(fmt.Sprintf(":%s", port)); err != nil {
-		utils.Log.Fatalf("Failed to start server: %v", err)
+	logging.Log.Infof("Starting HTTP server on port %s", cfg.Port)
+	if err := server.Run(ctx, server.New(cfg.Port, r, cfg.Server), cfg.Server.ShutdownTimeout); err != nil {
+		logging.Log.Errorf("Server stopped: %v", err)
+	}
+
+	if err := database.Close(); err != nil {
+		logging.Log.Errorf("Failed to close the database: %v", err)
+	}
+	// Export the spans still buffered, without waiting on a collector forever
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logging.Log.Errorf("Failed to flush traces: %v", err)
 	}
-}
\ No newline at end of file
+	logging.Log.Info("Home Service stopped")
+}
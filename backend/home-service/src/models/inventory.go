@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Inventory is the full record of a home: its rooms and every object in
+// them, with who received each one. It is what the estate export renders.
+type Inventory struct {
+	Home        Home            `json:"home"`
+	GeneratedAt time.Time       `json:"generatedAt"`
+	Rooms       []InventoryRoom `json:"rooms"`
+	Totals      InventoryTotals `json:"totals"`
+}
+
+// InventoryRoom is a room of the home with its objects
+type InventoryRoom struct {
+	ID      uint              `json:"id"`
+	Name    string            `json:"name"`
+	Objects []InventoryObject `json:"objects"`
+}
+
+// InventoryObject mirrors the object service record, plus the name of the
+// user the object went to
+type InventoryObject struct {
+	ID                string     `json:"id"`
+	Name              string     `json:"name"`
+	Type              string     `json:"type"`
+	Description       string     `json:"description,omitempty"`
+	Condition         string     `json:"condition,omitempty"`
+	Value             float64    `json:"value,omitempty"`
+	Tags              []string   `json:"tags,omitempty"`
+	IsReserved        bool       `json:"isReserved"`
+	ReservedBy        string     `json:"reservedBy"`
+	ReservationStatus string     `json:"reservationStatus,omitempty"`
+	Disposition       string     `json:"disposition,omitempty"`
+	CollectedBy       string     `json:"collectedBy,omitempty"`
+	CollectedAt       *time.Time `json:"collectedAt,omitempty"`
+	Recipient         string     `json:"recipient,omitempty"` // User the object went to or was promised to
+}
+
+// InventoryTotals sums up the inventory
+type InventoryTotals struct {
+	Rooms     int     `json:"rooms"`
+	Objects   int     `json:"objects"`
+	Reserved  int     `json:"reserved"`
+	Collected int     `json:"collected"`
+	Value     float64 `json:"value"`
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"fmt"
+	"hexagone/home-service/src/models"
+	"io"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Layout of the PDF inventory, in millimetres on A4 portrait
+const (
+	pdfMargin    = 15.0
+	pdfRowHeight = 6.0
+)
+
+// pdfColumns of the object table; the widths add up to the printable width
+var pdfColumns = []struct {
+	title string
+	width float64
+	align string
+}{
+	{"Object", 62, "L"},
+	{"Condition", 24, "L"},
+	{"Value", 22, "R"},
+	{"Status", 30, "L"},
+	{"Received by", 42, "L"},
+}
+
+// statusLabels spell out the reservation and disposition states
+var statusLabels = map[string]string{
+	"pending":   "Reserved (pending)",
+	"approved":  "Reserved",
+	"rejected":  "Available",
+	"picked_up": "Collected",
+	"keep":      "Kept",
+	"donate":    "To donate",
+	"sell":      "To sell",
+	"discard":   "To discard",
+}
+
+// writeInventoryPDF renders the inventory as a printable document, one
+// table per room. It only uses the core PDF fonts, so nothing is fetched
+// or installed to render it.
+func writeInventoryPDF(w io.Writer, inventory models.Inventory) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.SetTitle("Inventory - "+inventory.Home.Name, true)
+	pdf.SetCreationDate(inventory.GeneratedAt)
+	pdf.AliasNbPages("")
+
+	// The core fonts are Latin-1: translate accents rather than printing mojibake
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargin + 5)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, 5, tr(fmt.Sprintf("%s - generated %s - page %d/{nb}",
+			inventory.Home.Name, inventory.GeneratedAt.Format("2006-01-02 15:04 MST"), pdf.PageNo())), "", 0, "C", false, 0, "")
+	})
+
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 10, tr("Inventory of "+inventory.Home.Name), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 6, tr(fmt.Sprintf("%d rooms, %d objects, %d reserved, %d collected, estimated value %s",
+		inventory.Totals.Rooms, inventory.Totals.Objects, inventory.Totals.Reserved, inventory.Totals.Collected,
+		formatEuros(inventory.Totals.Value))), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	for _, room := range inventory.Rooms {
+		// Keep a room title together with at least its header and first row
+		if pdf.GetY()+10+3*pdfRowHeight > 297-pdfMargin {
+			pdf.AddPage()
+		}
+		pdf.SetFont("Helvetica", "B", 13)
+		pdf.CellFormat(0, 10, tr(fmt.Sprintf("%s (%d)", room.Name, len(room.Objects))), "", 1, "L", false, 0, "")
+
+		if len(room.Objects) == 0 {
+			pdf.SetFont("Helvetica", "I", 10)
+			pdf.CellFormat(0, pdfRowHeight, "No objects", "", 1, "L", false, 0, "")
+			pdf.Ln(2)
+			continue
+		}
+
+		writePDFHeader(pdf, tr)
+		pdf.SetFont("Helvetica", "", 9)
+		for i, object := range room.Objects {
+			if pdf.GetY()+pdfRowHeight > 297-pdfMargin {
+				pdf.AddPage()
+				writePDFHeader(pdf, tr)
+				pdf.SetFont("Helvetica", "", 9)
+			}
+
+			value := ""
+			if object.Value != 0 {
+				value = formatEuros(object.Value)
+			}
+			cells := []string{object.Name, object.Condition, value, objectStatus(object), object.Recipient}
+			fill := i%2 == 1
+			pdf.SetFillColor(242, 242, 242)
+			for j, column := range pdfColumns {
+				pdf.CellFormat(column.width, pdfRowHeight, fitText(pdf, tr, cells[j], column.width-2), "B", 0, column.align, fill, 0, "")
+			}
+			pdf.Ln(-1)
+		}
+		pdf.Ln(4)
+	}
+
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
+
+func writePDFHeader(pdf *gofpdf.Fpdf, tr func(string) string) {
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(220, 220, 220)
+	for _, column := range pdfColumns {
+		pdf.CellFormat(column.width, pdfRowHeight+1, tr(column.title), "", 0, column.align, true, 0, "")
+	}
+	pdf.Ln(-1)
+}
+
+// objectStatus is the reservation state of an object, or what will become
+// of it when nobody claimed it
+func objectStatus(object models.InventoryObject) string {
+	if object.CollectedBy != "" {
+		return statusLabels["picked_up"]
+	}
+	if label, ok := statusLabels[object.ReservationStatus]; ok && object.ReservationStatus != "rejected" {
+		return label
+	}
+	if label, ok := statusLabels[object.Disposition]; ok {
+		return label
+	}
+	return "Available"
+}
+
+// fitText translates s for the current font and shortens it to fit width
+func fitText(pdf *gofpdf.Fpdf, tr func(string) string, s string, width float64) string {
+	if pdf.GetStringWidth(tr(s)) <= width {
+		return tr(s)
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && pdf.GetStringWidth(tr(string(runes)+"...")) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return tr(strings.TrimSpace(string(runes)) + "...")
+}
+
+// formatEuros writes an amount the French way, as the notary reads it
+func formatEuros(amount float64) string {
+	return strings.Replace(fmt.Sprintf("%.2f €", amount), ".", ",", 1)
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"hexagone/home-service/src/database"
+	"hexagone/home-service/src/models"
+	"hexagone/shared/logging"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Reservation states from the object service that matter to the inventory
+const (
+	statusApproved = "approved"
+	statusPickedUp = "picked_up"
+)
+
+// ExportHome handles GET /homes/:id/export?format=json|csv|pdf: the full
+// inventory of a home, rooms from the room service and objects from the
+// object service, for the notary when the estate is settled
+func ExportHome(c *gin.Context) {
+	homeID := c.Param("id")
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" && format != "pdf" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json, csv or pdf"})
+		return
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"homeID": homeID,
+		"format": format,
+	}).Info("Exporting home inventory")
+
+	var home models.Home
+	if err := database.DB.WithContext(c.Request.Context()).First(&home, homeID).Error; err != nil {
+		logging.RequestLog(c).WithField("homeID", homeID).Warn("Home not found")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Home not found"})
+		return
+	}
+
+	inventory, err := buildInventory(c.Request.Context(), home)
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"homeID": homeID,
+			"error":  err.Error(),
+		}).Error("Failed to build home inventory")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to build inventory"})
+		return
+	}
+
+	filename := fmt.Sprintf("inventory-home-%d.%s", home.ID, format)
+	switch format {
+	case "csv":
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		if err := writeInventoryCSV(c.Writer, inventory); err != nil {
+			logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to write CSV inventory")
+		}
+	case "pdf":
+		var pdf bytes.Buffer
+		if err := writeInventoryPDF(&pdf, inventory); err != nil {
+			logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to render PDF inventory")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render PDF"})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Data(http.StatusOK, "application/pdf", pdf.Bytes())
+	default:
+		c.JSON(http.StatusOK, gin.H{"data": inventory})
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"homeID":  homeID,
+		"format":  format,
+		"objects": inventory.Totals.Objects,
+	}).Info("Home inventory exported")
+}
+
+// buildInventory gathers the rooms and objects of a home. Every room must
+// be read: a partial inventory is worse than none for the notary.
+func buildInventory(ctx context.Context, home models.Home) (models.Inventory, error) {
+	inventory := models.Inventory{Home: home, GeneratedAt: time.Now().UTC(), Rooms: []models.InventoryRoom{}}
+
+	roomBase := roomServiceURL()
+	if roomBase == "" {
+		return inventory, fmt.Errorf("room service is not configured (ROOM_PORT)")
+	}
+	objectBase := objectServiceURL()
+	if objectBase == "" {
+		return inventory, fmt.Errorf("object service is not configured (OBJECT_PORT)")
+	}
+
+	homeID := strconv.FormatUint(uint64(home.ID), 10)
+	if err := getUpstream(ctx, roomBase+"/rooms?home_id="+homeID, &inventory.Rooms); err != nil {
+		return inventory, err
+	}
+
+	usernames := fetchUsernames(ctx)
+	for i := range inventory.Rooms {
+		room := &inventory.Rooms[i]
+		room.Objects = []models.InventoryObject{}
+		roomID := strconv.FormatUint(uint64(room.ID), 10)
+		if err := getUpstream(ctx, objectBase+"/objects/room?room_id="+url.QueryEscape(roomID), &room.Objects); err != nil {
+			return inventory, err
+		}
+
+		for j := range room.Objects {
+			object := &room.Objects[j]
+			if object.ReservationStatus == "" && object.IsReserved {
+				// Reserved before the approval workflow existed
+				object.ReservationStatus = statusApproved
+			}
+			object.Recipient = recipient(*object, usernames)
+
+			inventory.Totals.Objects++
+			inventory.Totals.Value += object.Value
+			if object.IsReserved {
+				inventory.Totals.Reserved++
+			}
+			if object.CollectedBy != "" || object.ReservationStatus == statusPickedUp {
+				inventory.Totals.Collected++
+			}
+		}
+	}
+	inventory.Totals.Rooms = len(inventory.Rooms)
+
+	return inventory, nil
+}
+
+// recipient is who the object went to: the user who collected it, or the
+// one whose reservation was approved
+func recipient(object models.InventoryObject, usernames map[string]string) string {
+	userID := object.CollectedBy
+	if userID == "" && (object.ReservationStatus == statusApproved || object.ReservationStatus == statusPickedUp) {
+		userID = object.ReservedBy
+	}
+	if name, ok := usernames[userID]; ok {
+		return name
+	}
+	return userID
+}
+
+// fetchUsernames maps user IDs to usernames. The inventory falls back to
+// the raw IDs when the user service cannot be reached.
+func fetchUsernames(ctx context.Context) map[string]string {
+	usernames := map[string]string{}
+	base := userServiceURL()
+	if base == "" {
+		return usernames
+	}
+
+	var users []struct {
+		ID       uint   `json:"id"`
+		Username string `json:"username"`
+	}
+	if err := getUpstream(ctx, base+"/users", &users); err != nil {
+		logging.LogFrom(ctx).WithField("error", err.Error()).Warn("Failed to fetch users, the inventory shows user IDs")
+		return usernames
+	}
+	for _, user := range users {
+		usernames[strconv.FormatUint(uint64(user.ID), 10)] = user.Username
+	}
+	return usernames
+}
+
+// csvFormulaPrefixes start the cells spreadsheets evaluate as formulas
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvText keeps a free-text cell from being run as a formula when the file
+// is opened in a spreadsheet, by prefixing it with a quote
+func csvText(cell string) string {
+	if cell != "" && strings.ContainsRune(csvFormulaPrefixes, rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// writeInventoryCSV writes one row per object, with its room
+func writeInventoryCSV(w io.Writer, inventory models.Inventory) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{
+		"room_id", "room", "object_id", "name", "type", "condition", "value",
+		"disposition", "reservation_status", "recipient", "collected_at",
+	})
+	for _, room := range inventory.Rooms {
+		for _, object := range room.Objects {
+			value, collectedAt := "", ""
+			if object.Value != 0 {
+				value = strconv.FormatFloat(object.Value, 'f', 2, 64)
+			}
+			if object.CollectedAt != nil {
+				collectedAt = object.CollectedAt.UTC().Format(time.RFC3339)
+			}
+			writer.Write([]string{
+				strconv.FormatUint(uint64(room.ID), 10), csvText(room.Name), object.ID, csvText(object.Name), csvText(object.Type),
+				object.Condition, value, object.Disposition, object.ReservationStatus, csvText(object.Recipient), collectedAt,
+			})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
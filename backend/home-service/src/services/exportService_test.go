@@ -0,0 +1,175 @@
+package services_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"hexagone/home-service/src/database"
+	"hexagone/home-service/src/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// salonObjects are a collected, an approved and an unclaimed object
+const salonObjects = `[
+	{"id": "a", "name": "Armoire Louis XV", "type": "meuble", "condition": "good", "value": 1200.5,
+	 "isReserved": true, "reservedBy": "7", "reservationStatus": "picked_up", "collectedBy": "7",
+	 "collectedAt": "2026-03-01T10:00:00Z", "room_id": "1"},
+	{"id": "b", "name": "Théière", "type": "vaisselle", "isReserved": true, "reservedBy": "8", "room_id": "1"},
+	{"id": "c", "name": "Lampe", "type": "luminaire", "isReserved": false, "reservedBy": "",
+	 "disposition": "donate", "room_id": "1"}
+]`
+
+// fakeEstate stands in for the room, object and user services with a home
+// of two rooms: a salon with salonObjects and an empty cellar
+func fakeEstate(t *testing.T, homeID uint) {
+	t.Helper()
+	fakeEstateWith(t, homeID, salonObjects)
+}
+
+// fakeEstateWith is fakeEstate with the given objects in the salon
+func fakeEstateWith(t *testing.T, homeID uint, objects string) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rooms", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, fmt.Sprint(homeID), r.URL.Query().Get("home_id"))
+		fmt.Fprintf(w, `{"data": [{"id": 1, "name": "Salon", "home_id": %d}, {"id": 2, "name": "Cave", "home_id": %d}]}`, homeID, homeID)
+	})
+	mux.HandleFunc("/objects/room", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("room_id") != "1" {
+			fmt.Fprint(w, `{"data": []}`)
+			return
+		}
+		fmt.Fprintf(w, `{"data": %s}`, objects)
+	})
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data": [{"id": 7, "username": "camille"}, {"id": 8, "username": "hélène"}]}`)
+	})
+
+	upstream := httptest.NewServer(mux)
+	t.Cleanup(upstream.Close)
+	t.Setenv("ROOM_SERVICE_URL", upstream.URL)
+	t.Setenv("OBJECT_SERVICE_URL", upstream.URL)
+	t.Setenv("USER_SERVICE_URL", upstream.URL)
+}
+
+func createExportHome(t *testing.T) models.Home {
+	t.Helper()
+	home := models.Home{Name: "Maison de famille"}
+	require.NoError(t, database.DB.Create(&home).Error)
+	return home
+}
+
+func exportHome(id uint, format string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", fmt.Sprintf("/homes/%d/export?format=%s", id, format), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestExportHomeJSON(t *testing.T) {
+	setupTest()
+	defer clearDatabase()
+	home := createExportHome(t)
+	fakeEstate(t, home.ID)
+
+	w := exportHome(home.ID, "json")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data models.Inventory `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	inventory := response.Data
+
+	assert.Equal(t, "Maison de famille", inventory.Home.Name)
+	require.Len(t, inventory.Rooms, 2)
+	assert.Equal(t, "Salon", inventory.Rooms[0].Name)
+	assert.Empty(t, inventory.Rooms[1].Objects)
+
+	objects := inventory.Rooms[0].Objects
+	require.Len(t, objects, 3)
+	assert.Equal(t, "camille", objects[0].Recipient)
+	assert.Equal(t, "approved", objects[1].ReservationStatus, "reservations made before the workflow count as approved")
+	assert.Equal(t, "hélène", objects[1].Recipient)
+	assert.Empty(t, objects[2].Recipient)
+
+	assert.Equal(t, models.InventoryTotals{Rooms: 2, Objects: 3, Reserved: 2, Collected: 1, Value: 1200.5}, inventory.Totals)
+}
+
+func TestExportHomeCSV(t *testing.T) {
+	setupTest()
+	defer clearDatabase()
+	home := createExportHome(t)
+	fakeEstate(t, home.ID)
+
+	w := exportHome(home.ID, "csv")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+	assert.Contains(t, w.Header().Get("Content-Disposition"), fmt.Sprintf("inventory-home-%d.csv", home.ID))
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4, "a header and one row per object")
+	assert.Equal(t, []string{"1", "Salon", "a", "Armoire Louis XV", "meuble", "good", "1200.50",
+		"", "picked_up", "camille", "2026-03-01T10:00:00Z"}, records[1])
+}
+
+func TestExportHomeCSVEscapesFormulas(t *testing.T) {
+	setupTest()
+	defer clearDatabase()
+	home := createExportHome(t)
+	fakeEstateWith(t, home.ID, `[
+		{"id": "a", "name": "=HYPERLINK(\"http://example.com\")", "type": "+meuble", "room_id": "1"},
+		{"id": "b", "name": "@SUM(A1)", "type": "-1", "room_id": "1"},
+		{"id": "c", "name": "Lampe = 2 ampoules", "type": "luminaire", "room_id": "1"}
+	]`)
+
+	w := exportHome(home.ID, "csv")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, `'=HYPERLINK("http://example.com")`, records[1][3])
+	assert.Equal(t, "'+meuble", records[1][4])
+	assert.Equal(t, "'@SUM(A1)", records[2][3])
+	assert.Equal(t, "'-1", records[2][4])
+	assert.Equal(t, "Lampe = 2 ampoules", records[3][3], "only a leading sign is escaped")
+}
+
+func TestExportHomePDF(t *testing.T) {
+	setupTest()
+	defer clearDatabase()
+	home := createExportHome(t)
+	fakeEstate(t, home.ID)
+
+	w := exportHome(home.ID, "pdf")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")))
+}
+
+func TestExportHomeErrors(t *testing.T) {
+	setupTest()
+	defer clearDatabase()
+	home := createExportHome(t)
+	fakeEstate(t, home.ID)
+
+	assert.Equal(t, http.StatusBadRequest, exportHome(home.ID, "xml").Code)
+	assert.Equal(t, http.StatusNotFound, exportHome(home.ID+100, "json").Code)
+
+	// An inventory missing rooms is not an inventory
+	roomService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer roomService.Close()
+	t.Setenv("ROOM_SERVICE_URL", roomService.URL)
+	assert.Equal(t, http.StatusBadGateway, exportHome(home.ID, "json").Code)
+}
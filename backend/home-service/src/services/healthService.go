@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"hexagone/home-service/src/database"
+	"hexagone/shared/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz handles GET /healthz: the process is up and serving requests
+var Healthz = health.Healthz("home-service")
+
+// Readyz handles GET /readyz: checks the database and the user service the
+// admin routes depend on, at userServiceURL, and answers 503 when any of them
+// is down
+func Readyz(userServiceURL string) gin.HandlerFunc {
+	return health.Readyz("home-service", map[string]health.Check{
+		"sqlite":       pingDatabase,
+		"user-service": func(ctx context.Context) error { return health.PingService(ctx, userServiceURL) },
+	})
+}
+
+// pingDatabase checks that the SQLite database answers
+func pingDatabase(ctx context.Context) error {
+	if database.DB == nil {
+		return errors.New("database is not connected")
+	}
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
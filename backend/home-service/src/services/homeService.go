@@ -3,7 +3,7 @@ package services
 import (
 	"hexagone/home-service/src/database"
 	"hexagone/home-service/src/models"
-	"hexagone/home-service/src/utils"
+	"hexagone/shared/logging"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -17,21 +17,21 @@ type CreateHomeInput struct {
 func CreateHome(c *gin.Context) {
 	var input CreateHomeInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.Log.WithFields(logrus.Fields{
+		logging.RequestLog(c).WithFields(logrus.Fields{
 			"error": err.Error(),
 		}).Error("Error binding JSON in CreateHome")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	utils.Log.WithFields(logrus.Fields{
+	logging.RequestLog(c).WithFields(logrus.Fields{
 		"name": input.Name,
 	}).Info("Creating home")
 
 	// Create the home
 	home := models.Home{Name: input.Name}
-	if result := database.DB.Create(&home); result.Error != nil {
-		utils.Log.WithFields(logrus.Fields{
+	if result := database.DB.WithContext(c.Request.Context()).Create(&home); result.Error != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
 			"name":  input.Name,
 			"error": result.Error.Error(),
 		}).Error("Error creating home in the database")
@@ -39,7 +39,9 @@ func CreateHome(c *gin.Context) {
 		return
 	}
 
-	utils.Log.WithFields(logrus.Fields{
+	indexHomeForSearch(c.Request.Context(), home)
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
 		"id":   home.ID,
 		"name": home.Name,
 	}).Info("Home created successfully")
@@ -50,12 +52,12 @@ func CreateHome(c *gin.Context) {
 func DeleteHome(c *gin.Context) {
     homeID := c.Param("id")
     
-    utils.Log.WithField("homeID", homeID).Info("Attempting to delete home")
+    logging.RequestLog(c).WithField("homeID", homeID).Info("Attempting to delete home")
 
     // Delete the home
-    result := database.DB.Delete(&models.Home{}, homeID)
+    result := database.DB.WithContext(c.Request.Context()).Delete(&models.Home{}, homeID)
     if result.Error != nil {
-        utils.Log.WithFields(logrus.Fields{
+        logging.RequestLog(c).WithFields(logrus.Fields{
             "homeID": homeID,
             "error":  result.Error.Error(),
         }).Error("Failed to delete home")
@@ -64,29 +66,31 @@ func DeleteHome(c *gin.Context) {
     }
 
     if result.RowsAffected == 0 {
-        utils.Log.WithField("homeID", homeID).Warn("Home not found")
+        logging.RequestLog(c).WithField("homeID", homeID).Warn("Home not found")
         c.JSON(http.StatusNotFound, gin.H{"error": "Home not found"})
         return
     }
 
-    utils.Log.WithField("homeID", homeID).Info("Home deleted successfully")
+    unindexHomeForSearch(c.Request.Context(), homeID)
+
+    logging.RequestLog(c).WithField("homeID", homeID).Info("Home deleted successfully")
     c.JSON(http.StatusOK, gin.H{"message": "Home deleted successfully"})
 }
 
 // ListHomes handles fetching all homes
 func ListHomes(c *gin.Context) {
-	utils.Log.Info("Fetching all homes")
+	logging.RequestLog(c).Info("Fetching all homes")
 
 	var homes []models.Home
-	if err := database.DB.Find(&homes).Error; err != nil {
-		utils.Log.WithFields(logrus.Fields{
+	if err := database.DB.WithContext(c.Request.Context()).Find(&homes).Error; err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
 			"error": err.Error(),
 		}).Error("Failed to retrieve homes from the database")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve homes"})
 		return
 	}
 
-	utils.Log.WithFields(logrus.Fields{
+	logging.RequestLog(c).WithFields(logrus.Fields{
 		"count": len(homes),
 	}).Info("Homes fetched successfully")
 
@@ -5,8 +5,8 @@ import (
 	"encoding/json"
 	"hexagone/home-service/src/database"
 	"hexagone/home-service/src/models"
-	"hexagone/home-service/src/utils"
 	"hexagone/home-service/src/services"
+	"hexagone/shared/logging"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -24,7 +24,7 @@ type TestHomeInput struct {
 
 func setupTest() {
 	// Initialize logger
-	utils.InitLogger()
+	logging.InitLogger()
 	
 	// Set Gin to test mode
 	gin.SetMode(gin.TestMode)
@@ -36,6 +36,7 @@ func setupTest() {
 	router = gin.Default()
 	router.POST("/homes", services.CreateHome)
 	router.GET("/homes", services.ListHomes)
+	router.GET("/homes/:id/export", services.ExportHome)
 }
 
 func clearDatabase() {
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hexagone/home-service/src/models"
+	"hexagone/shared/auth"
+	"hexagone/shared/httpclient"
+	"hexagone/shared/logging"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The object service keeps the search index covering objects, rooms and
+// homes. Homes are pushed to it on every write; failures are only logged
+// since an admin can rebuild the index with POST /search/reindex.
+var searchClient = httpclient.New(5 * time.Second)
+
+// ServiceToken is presented to the object service, which only accepts search
+// updates from the other services
+var ServiceToken string
+
+// searchIndexURL is the object service endpoint for home documents, empty
+// when the object service is not configured
+func searchIndexURL(homeID string) string {
+	base := objectServiceURL()
+	if base == "" {
+		return ""
+	}
+	return base + "/search/documents/home/" + homeID
+}
+
+// sendSearchUpdate sends the update in the background. It stays in the trace
+// of the request that caused it, but is not cancelled when that request ends.
+func sendSearchUpdate(ctx context.Context, method, homeID string, body []byte) {
+	url := searchIndexURL(homeID)
+	if url == "" {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := doSearchUpdate(ctx, method, url, body); err != nil {
+			logging.LogFrom(ctx).WithFields(logrus.Fields{
+				"homeID": homeID,
+				"error":  err.Error(),
+			}).Warn("Failed to update search index")
+		}
+	}()
+}
+
+func doSearchUpdate(ctx context.Context, method, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(auth.ServiceTokenHeader, ServiceToken)
+
+	resp, err := searchClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s", method, url, resp.Status)
+	}
+	return nil
+}
+
+// indexHomeForSearch adds or refreshes a home in the search index
+func indexHomeForSearch(ctx context.Context, home models.Home) {
+	body, _ := json.Marshal(map[string]string{"title": home.Name})
+	sendSearchUpdate(ctx, http.MethodPut, strconv.FormatUint(uint64(home.ID), 10), body)
+}
+
+// unindexHomeForSearch removes a deleted home from the search index
+func unindexHomeForSearch(ctx context.Context, homeID string) {
+	sendSearchUpdate(ctx, http.MethodDelete, homeID, nil)
+}
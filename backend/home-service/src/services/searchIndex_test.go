@@ -0,0 +1,48 @@
+package services_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"hexagone/home-service/src/services"
+	"hexagone/shared/auth"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateHomeUpdatesSearchIndex(t *testing.T) {
+	setupTest()
+	defer clearDatabase()
+
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	objectService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer objectService.Close()
+	t.Setenv("OBJECT_SERVICE_URL", objectService.URL)
+	services.ServiceToken = "0123456789abcdef0123456789abcdef"
+
+	jsonInput, _ := json.Marshal(map[string]interface{}{"name": "Maison de famille"})
+	req := httptest.NewRequest("POST", "/homes", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	select {
+	case r := <-received:
+		assert.Equal(t, "PUT", r.Method)
+		assert.Regexp(t, `^/search/documents/home/\d+$`, r.URL.Path)
+		assert.Equal(t, services.ServiceToken, r.Header.Get(auth.ServiceTokenHeader))
+		assert.JSONEq(t, `{"title": "Maison de famille"}`, string(<-bodies))
+	case <-time.After(2 * time.Second):
+		t.Fatal("the home was not sent to the search index")
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hexagone/shared/config"
+	"hexagone/shared/httpclient"
+	"net/http"
+	"time"
+)
+
+// upstreamClient reads from the room, object and user services
+var upstreamClient = httpclient.New(10 * time.Second)
+
+func roomServiceURL() string {
+	return config.ServiceURL("ROOM_SERVICE_URL", "room-service", "ROOM_PORT")
+}
+
+func objectServiceURL() string {
+	return config.ServiceURL("OBJECT_SERVICE_URL", "object-service", "OBJECT_PORT")
+}
+
+func userServiceURL() string {
+	return config.ServiceURL("USER_SERVICE_URL", "user-service", "USER_PORT")
+}
+
+// getUpstream decodes the "data" field of a GET response into out
+func getUpstream(ctx context.Context, target string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", target, resp.Status)
+	}
+
+	response := struct {
+		Data interface{} `json:"data"`
+	}{Data: out}
+	return json.NewDecoder(resp.Body).Decode(&response)
+}
@@ -0,0 +1,50 @@
+// Package config reads the settings of the service from the environment.
+// They are loaded and checked once, before anything starts, so that a
+// missing or malformed setting stops the service with a clear message.
+package config
+
+import (
+	"errors"
+	shared "hexagone/shared/config"
+	"hexagone/shared/tracing"
+	"net"
+)
+
+// Config is the configuration of the object service
+type Config struct {
+	Port          string // PORT
+	DragonflyAddr string // DRAGONFLY_HOST:DRAGONFLY_PORT
+	Server        shared.Server
+
+	UserService    shared.UserService // where RequireAdmin checks roles
+	HomeServiceURL string             // HOME_SERVICE_URL, or the compose service on HOME_PORT
+	RoomServiceURL string             // ROOM_SERVICE_URL, or the compose service on ROOM_PORT
+	ServiceToken   string             // SERVICE_TOKEN, presented by the room and home services on internal routes
+
+	LabelBaseURL string // LABEL_BASE_URL, the frontend address phones reach, encoded in the QR labels
+
+	TracesExporter string // OTEL_TRACES_EXPORTER: none, stdout or otlp
+	Logging        shared.Logging
+}
+
+// Load reads the configuration and reports every invalid setting at once
+func Load() (*Config, error) {
+	var errs []error
+	cfg := &Config{
+		Port:          shared.Port("PORT", &errs),
+		DragonflyAddr: net.JoinHostPort(shared.Required("DRAGONFLY_HOST", &errs), shared.Port("DRAGONFLY_PORT", &errs)),
+		Server:        shared.LoadServer(&errs),
+
+		UserService:    shared.LoadUserService(&errs),
+		HomeServiceURL: shared.RequiredServiceURL("HOME_SERVICE_URL", "home-service", "HOME_PORT", &errs),
+		RoomServiceURL: shared.RequiredServiceURL("ROOM_SERVICE_URL", "room-service", "ROOM_PORT", &errs),
+		ServiceToken:   shared.ServiceToken(&errs),
+
+		LabelBaseURL: shared.BaseURL("LABEL_BASE_URL", &errs),
+
+		TracesExporter: shared.OneOf("OTEL_TRACES_EXPORTER", tracing.ExporterNone,
+			[]string{tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP}, &errs),
+		Logging: shared.LoadLogging(&errs),
+	}
+	return cfg, errors.Join(errs...)
+}
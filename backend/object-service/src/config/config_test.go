@@ -0,0 +1,71 @@
+package config_test
+
+import (
+	"hexagone/object-service/src/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("USER_PORT", "8083")
+	t.Setenv("HOME_PORT", "8081")
+	t.Setenv("ROOM_SERVICE_URL", "http://rooms.internal:9000/")
+	t.Setenv("DRAGONFLY_HOST", "dragonfly")
+	t.Setenv("DRAGONFLY_PORT", "6379")
+	t.Setenv("SERVICE_TOKEN", "0123456789abcdef0123456789abcdef")
+	t.Setenv("LABEL_BASE_URL", "http://192.168.1.20:3000/")
+	t.Setenv("HTTP_WRITE_TIMEOUT", "2m")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "8080", cfg.Port)
+	assert.Equal(t, "dragonfly:6379", cfg.DragonflyAddr)
+	assert.Equal(t, 2*time.Minute, cfg.Server.WriteTimeout)
+	assert.Equal(t, 30*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, 15*time.Second, cfg.Server.ShutdownTimeout)
+	assert.Equal(t, "none", cfg.TracesExporter)
+	assert.Equal(t, "info", cfg.Logging.Level)
+	assert.Equal(t, "json", cfg.Logging.Format)
+	assert.Equal(t, "http://user-service:8083", cfg.UserService.URL)
+	assert.Equal(t, 3*time.Second, cfg.UserService.Timeout)
+	assert.Equal(t, 30*time.Second, cfg.UserService.RoleTTL)
+	assert.Equal(t, "http://home-service:8081", cfg.HomeServiceURL)
+	assert.Equal(t, "http://rooms.internal:9000", cfg.RoomServiceURL)
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", cfg.ServiceToken)
+	assert.Equal(t, "http://192.168.1.20:3000", cfg.LabelBaseURL)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	t.Setenv("PORT", "http")
+	t.Setenv("USER_SERVICE_URL", "")
+	t.Setenv("USER_PORT", "")
+	t.Setenv("HOME_SERVICE_URL", "")
+	t.Setenv("HOME_PORT", "")
+	t.Setenv("ROOM_SERVICE_URL", "room-service:8082")
+	t.Setenv("USER_ROLE_CACHE_TTL", "soon")
+	t.Setenv("DRAGONFLY_HOST", "")
+	t.Setenv("DRAGONFLY_PORT", "6379")
+	t.Setenv("SERVICE_TOKEN", "secret")
+	t.Setenv("LABEL_BASE_URL", "inventaire.local")
+	t.Setenv("SHUTDOWN_TIMEOUT", "-1s")
+	t.Setenv("OTEL_TRACES_EXPORTER", "jaeger")
+	t.Setenv("LOG_LEVEL", "verbose")
+
+	_, err := config.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `PORT must be a port number, got "http"`)
+	assert.Contains(t, err.Error(), "USER_SERVICE_URL or USER_PORT is required")
+	assert.Contains(t, err.Error(), "HOME_SERVICE_URL or HOME_PORT is required")
+	assert.Contains(t, err.Error(), `ROOM_SERVICE_URL must be an http or https address, got "room-service:8082"`)
+	assert.Contains(t, err.Error(), `USER_ROLE_CACHE_TTL must be a positive duration such as 30s, got "soon"`)
+	assert.Contains(t, err.Error(), "DRAGONFLY_HOST is required")
+	assert.Contains(t, err.Error(), "SERVICE_TOKEN must be at least 32 characters long")
+	assert.Contains(t, err.Error(), `LABEL_BASE_URL must be an http or https address, got "inventaire.local"`)
+	assert.Contains(t, err.Error(), `SHUTDOWN_TIMEOUT must be a positive duration such as 30s, got "-1s"`)
+	assert.Contains(t, err.Error(), `OTEL_TRACES_EXPORTER must be one of none, stdout, otlp, got "jaeger"`)
+	assert.Contains(t, err.Error(), `LOG_LEVEL must be one of debug, info, warn, error, got "verbose"`)
+}
@@ -2,9 +2,9 @@ package database
 
 import (
 	"context"
-	"fmt"
-	"hexagone/object-service/src/utils"
-	"os"
+	"hexagone/shared/logging"
+	"hexagone/shared/metrics"
+	"hexagone/shared/tracing"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -12,26 +12,31 @@ import (
 var RDB *redis.Client
 var Ctx = context.Background()
 
-func ConnectDatabase() error {
-
-	host := os.Getenv("DRAGONFLY_HOST")
-	port := os.Getenv("DRAGONFLY_PORT")
-
-	if host == "" || port == "" {
-		utils.Log.Fatal("DRAGONFLY_HOST or DRAGONFLY_PORT is not set in the environment variables")
-	}
-
+// ConnectDatabase connects to DragonflyDB at addr and checks that it answers
+func ConnectDatabase(addr string) error {
 	RDB = redis.NewClient(&redis.Options{
-		Addr: fmt.Sprintf("%s:%s", host, port),
+		Addr: addr,
 	})
+	// Time every command for the /metrics endpoint
+	metrics.InstrumentRedis(RDB)
+	// and trace the commands sent while handling a request
+	tracing.InstrumentRedis(RDB)
 
 	// Test the connection
 	_, err := RDB.Ping(Ctx).Result()
 	if err != nil {
-		utils.Log.WithField("error", err.Error()).Error("Failed to connect to DragonflyDB:")
+		logging.Log.WithField("error", err.Error()).Error("Failed to connect to DragonflyDB:")
 		return err
 	} 
-	utils.Log.Info("DragonflyDB connected successfully!")
+	logging.Log.Info("DragonflyDB connected successfully!")
 	
 	return nil
 }
+
+// Close closes the connections to DragonflyDB, once the server has stopped
+func Close() error {
+	if RDB == nil {
+		return nil
+	}
+	return RDB.Close()
+}
@@ -1,55 +1,197 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"hexagone/object-service/src/config"
 	"hexagone/object-service/src/database"
-	"hexagone/object-service/src/middleware"
 	"hexagone/object-service/src/services"
-	"hexagone/object-service/src/utils"
-	"os"
+	"hexagone/object-service/src/storage"
+	"hexagone/shared/auth"
+	"hexagone/shared/logging"
+	"hexagone/shared/metrics"
+	"hexagone/shared/middleware"
+	"hexagone/shared/server"
+	"hexagone/shared/tracing"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	
-	port := os.Getenv("PORT")
-	if port == "" {
-		utils.Log.Error("PORT is not set in the environment variables")
+	// The logger comes first, so that configuration errors can be reported
+	logging.InitLogger()
+
+	cfg, err := config.Load()
+	if err != nil {
+		logging.Log.Fatalf("Invalid configuration: %v", err)
+	}
+	logging.Configure(cfg.Logging.Level, cfg.Logging.Format)
+	logging.Log.Info("Starting Object Service")
+
+	// Set up tracing before anything makes a call worth tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), "object-service", cfg.TracesExporter)
+	if err != nil {
+		logging.Log.Fatalf("Failed to set up tracing: %v", err)
 	}
 
-	utils.InitLogger()
-	utils.Log.Info("Starting Object Service")
-	
 	// Connect to DragonflyDB
-	err := database.ConnectDatabase()
-	if(err != nil) {
-		return
+	if err := database.ConnectDatabase(cfg.DragonflyAddr); err != nil {
+		logging.Log.Fatalf("Failed to connect to DragonflyDB: %v", err)
+	}
+	logging.Log.Info("Connected to DragonflyDB")
+
+	// Objects created before the room index existed are indexed once
+	if err := services.BuildRoomIndex(); err != nil {
+		logging.Log.Errorf("Failed to build the room index: %v", err)
+	}
+
+	// And so are the objects reserved before the reservation index existed
+	if err := services.BuildReservationIndex(); err != nil {
+		logging.Log.Errorf("Failed to build the reservation index: %v", err)
+	}
+
+	// QR labels link to the frontend
+	services.LabelBaseURL = cfg.LabelBaseURL
+	// Rooms and homes are read from their services
+	services.HomeServiceURL, services.RoomServiceURL = cfg.HomeServiceURL, cfg.RoomServiceURL
+
+	// Photos and other media are kept outside DragonflyDB, on disk or in an S3-compatible bucket
+	storage.Media, err = storage.NewFromEnv()
+	if err != nil {
+		logging.Log.Fatalf("Failed to initialize media storage: %v", err)
 	}
-	utils.Log.Info("Connected to DragonflyDB")
 
 	r := gin.Default()
 
+	// Count and time every request, for the /metrics endpoint
+	r.Use(metrics.Middleware())
 	r.Use(middleware.SetupCORS())
 
+	// Liveness and readiness probes
+	r.GET("/healthz", services.Healthz)                    // The process is up
+	r.GET("/readyz", services.Readyz(cfg.UserService.URL)) // The database and the services it depends on answer
+
+	// Prometheus metrics
+	r.GET("/metrics", metrics.Handler())
+
+	// Trace the routes below, the probes and scrapes above are left out
+	r.Use(tracing.Middleware())
+	// Give every request an ID and a logger, once its trace has started
+	r.Use(logging.RequestID())
+	// Only trust the user the gateway vouches for with the service token
+	r.Use(auth.TrustIdentity(cfg.ServiceToken))
+
+	// Signed download URLs of the local store point back at this service
+	if local, ok := storage.Media.(*storage.LocalStore); ok {
+		r.GET("/media/*key", gin.WrapH(http.StripPrefix("/media", local)))
+	}
+
 	// Object routes
 	r.POST("/objects", services.CreateObject)               // Add a new object
 	r.GET("/objects", services.ListObjects)                 // List all objects
-	r.GET("/objects/:roomId", services.ListObjectsByRoom)    // List object by their room id
+	r.GET("/objects/room", services.ListObjectsByRoom)      // List objects by their room id (?room_id=)
 	r.PATCH("/objects/:id/reserve", services.ReserveObject) // Reserve an object
 	r.PATCH("/objects/:id/unreserve", services.UnreserveObject) // Unreserve an object
 	r.GET("/objects/reserved", services.ListReservedObjects) // List all reserved objects
 
+	// Reservation approval workflow
+	r.PATCH("/objects/:id/approve", services.ApproveReservation) // Executor approves a pending claim
+	r.PATCH("/objects/:id/reject", services.RejectReservation)   // Executor rejects a pending claim
+	r.PATCH("/objects/:id/pickup", services.ConfirmPickup)       // Executor confirms an approved object was collected
+	r.GET("/objects/:id/history", services.GetObjectHistory)     // Reservation history of an object
+	r.GET("/homes/:id/settings", services.GetHomeSettings)       // Reservation settings of a home
+
+	// Declare-interest windows and lotteries
+	r.POST("/objects/:id/interest", services.DeclareInterest)                  // Declare interest in an object
+	r.DELETE("/objects/:id/interest", services.WithdrawInterest)               // Withdraw the caller's declaration of interest
+	r.GET("/objects/:id/lottery", services.GetLottery)                         // Window state and draw audit record
+
+	// Pickup scheduling and handover
+	r.GET("/homes/:id/pickup-slots", services.ListPickupSlots)                 // Pickup slots of a home with remaining capacity
+	r.GET("/homes/:id/pickup-schedule", services.ExportPickupSchedule)         // Day's pickup schedule (?date=YYYY-MM-DD&format=csv)
+	r.POST("/pickup-slots/:id/bookings", services.BookPickupSlot)              // Book a slot for reserved objects
+	r.DELETE("/pickup-bookings/:id", services.CancelPickupBooking)             // Cancel a booking
+	r.POST("/pickup-bookings/:id/handover", services.ConfirmHandover)          // Confirm objects were collected
+
+	// Dispositions for unclaimed objects
+	r.PATCH("/objects/:id/disposition", services.SetDisposition)               // Executor sets an object's disposition
+	r.POST("/homes/:id/dispositions", services.BulkAssignDisposition)          // Executor assigns every unreserved object after the deadline
+	r.GET("/homes/:id/dispositions", services.DispositionReport)               // Per-home disposition report (?format=csv)
+
+	// Per-user claims
+	r.GET("/users/:id/reservations", services.ListUserReservations)            // Objects claimed by a user
+	r.GET("/users/:id/history", services.GetUserHistory)                       // Reservation history of a user's claims
+	r.GET("/me/reservations", services.ListMyReservations)                     // Objects claimed by the X-User-ID user
+
+	// Object photos
+	r.POST("/objects/:id/photos", services.UploadObjectPhotos)                   // Upload photos (multipart "photos" fields)
+	r.GET("/objects/:id/photos", services.ListObjectPhotos)                      // List photos in display order
+	r.GET("/objects/:id/photos/:photoId/:rendition", services.GetPhotoRendition) // Download original, small, medium or large
+	r.PUT("/objects/:id/photos/order", services.ReorderObjectPhotos)             // Set the display order
+	r.PATCH("/objects/:id/photos/:photoId/primary", services.SetPrimaryPhoto)    // Choose the primary photo
+
+	// Object category taxonomy
+	r.GET("/categories", services.ListCategories)  // List categories (?tree=true to nest them)
+	r.GET("/categories/:id", services.GetCategory) // A category with its subcategories
+
+	// Bulk import from spreadsheets
+	r.POST("/homes/:id/import", services.ImportObjects) // Import objects from CSV or JSON (?dryRun=true)
+
+	// Search across objects, rooms and homes
+	r.GET("/search", services.Search) // Ranked results for ?q= (&kind=&home_id=&limit=)
+
+	// Called by the room and home services only, with the internal service token
+	internalRoutes := r.Group("/", auth.RequireServiceToken(cfg.ServiceToken))
+	internalRoutes.PUT("/search/documents/:kind/:id", services.PutSearchDocument)       // Room and home writes
+	internalRoutes.DELETE("/search/documents/:kind/:id", services.DeleteSearchDocument) // Room and home deletes
+
+	// QR labels for tagging objects in the house
+	r.GET("/labels", services.PrintLabels)       // PDF label sheet for a room or home (?room_id= or ?home_id=, &format=json)
+	r.GET("/labels/:code", services.LookupLabel) // Object a scanned short code belongs to
+
 	adminRoutes := r.Group("/")
-    adminRoutes.Use(middleware.RequireAdmin())
+    adminRoutes.Use(auth.RequireAdmin(auth.NewUsers(cfg.UserService)))
 	adminRoutes.Use(middleware.SetupCORS())
     {
         adminRoutes.DELETE("/objects/:id", services.DeleteObject)
+        adminRoutes.POST("/objects/bulk", services.BulkUpdateObjects)
+        adminRoutes.POST("/objects/:id/move", services.MoveObject)
+        adminRoutes.PUT("/homes/:id/settings", services.UpdateHomeSettings)
+        adminRoutes.POST("/objects/:id/lottery", services.OpenInterestWindow)
+        adminRoutes.POST("/objects/:id/lottery/draw", services.DrawLottery)
+        adminRoutes.POST("/homes/:id/pickup-slots", services.CreatePickupSlot)
+        adminRoutes.DELETE("/objects/:id/photos/:photoId", services.DeleteObjectPhoto)
+        adminRoutes.POST("/categories", services.CreateCategory)
+        adminRoutes.PATCH("/categories/:id", services.UpdateCategory)
+        adminRoutes.DELETE("/categories/:id", services.DeleteCategory)
+        adminRoutes.POST("/categories/migrate", services.MigrateObjectTypes)
+        adminRoutes.POST("/search/reindex", services.ReindexSearch)
     }
 
+	// Stop on SIGTERM (docker stop) or Ctrl-C, after the requests in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Draw lotteries whose interest window has closed
+	services.StartLotteryWorker(ctx, 30 * time.Second)
+
 	// Start the service
-	utils.Log.Infof("Starting HTTP server on port %s", port)
-	if err := r.Run(fmt.Sprintf(":%s", port)); err != nil {
-		utils.Log.Fatalf("Failed to start server: %v", err)
+	logging.Log.Infof("Starting HTTP server on port %s", cfg.Port)
+	if err := server.Run(ctx, server.New(cfg.Port, r, cfg.Server), cfg.Server.ShutdownTimeout); err != nil {
+		logging.Log.Errorf("Server stopped: %v", err)
+	}
+
+	if err := database.Close(); err != nil {
+		logging.Log.Errorf("Failed to close DragonflyDB: %v", err)
+	}
+	// Export the spans still buffered, without waiting on a collector forever
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logging.Log.Errorf("Failed to flush traces: %v", err)
 	}
+	logging.Log.Info("Object Service stopped")
 }
@@ -0,0 +1,188 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// MaxPixels bounds the decoded size of an upload so a small, highly
+// compressed file cannot exhaust memory
+const MaxPixels = 40_000_000
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type, expected JPEG, PNG or GIF")
+	ErrTooLarge        = errors.New("image dimensions are too large")
+)
+
+// Variant is a thumbnail size, bounded by MaxSize on its longest side
+type Variant struct {
+	Name    string
+	MaxSize int
+}
+
+// Variants are generated for every uploaded photo, on top of the original
+var Variants = []Variant{
+	{Name: "small", MaxSize: 160},
+	{Name: "medium", MaxSize: 480},
+	{Name: "large", MaxSize: 1024},
+}
+
+// Encoded is one rendition of an uploaded image
+type Encoded struct {
+	Name        string
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// SniffContentType detects the type of an upload from its first bytes,
+// ignoring whatever the client claimed
+func SniffContentType(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return contentType, nil
+	}
+	return contentType, ErrUnsupportedType
+}
+
+// Process decodes an uploaded image, turns it upright according to its EXIF
+// orientation and re-encodes it as an "original" rendition plus one
+// thumbnail per Variant. Re-encoding from pixels drops every metadata block
+// of the upload, including EXIF, GPS coordinates and comments.
+func Process(data []byte) ([]Encoded, error) {
+	contentType, err := SniffContentType(data)
+	if err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	var src image.Image
+	switch contentType {
+	case "image/jpeg":
+		src, err = jpeg.Decode(bytes.NewReader(data))
+		if err == nil {
+			src = applyOrientation(src, jpegOrientation(data))
+		}
+	case "image/png":
+		src, err = png.Decode(bytes.NewReader(data))
+	case "image/gif":
+		src, err = gif.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rgba := toRGBA(src)
+	asJPEG := contentType == "image/jpeg"
+
+	original, err := encode("original", rgba, asJPEG)
+	if err != nil {
+		return nil, err
+	}
+	renditions := []Encoded{original}
+
+	for _, variant := range Variants {
+		w, h := fit(rgba.Bounds().Dx(), rgba.Bounds().Dy(), variant.MaxSize)
+		encoded, err := encode(variant.Name, resize(rgba, w, h), asJPEG)
+		if err != nil {
+			return nil, err
+		}
+		renditions = append(renditions, encoded)
+	}
+	return renditions, nil
+}
+
+func encode(name string, img *image.RGBA, asJPEG bool) (Encoded, error) {
+	var buf bytes.Buffer
+	encoded := Encoded{Name: name, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+
+	if asJPEG {
+		encoded.ContentType, encoded.Extension = "image/jpeg", "jpg"
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 88}); err != nil {
+			return encoded, err
+		}
+	} else {
+		encoded.ContentType, encoded.Extension = "image/png", "png"
+		if err := png.Encode(&buf, img); err != nil {
+			return encoded, err
+		}
+	}
+
+	encoded.Data = buf.Bytes()
+	return encoded, nil
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// fit scales w×h down so its longest side is at most limit, never upscaling
+func fit(w, h, limit int) (int, int) {
+	if w <= limit && h <= limit {
+		return w, h
+	}
+	if w >= h {
+		return limit, max(1, h*limit/w)
+	}
+	return max(1, w*limit/h), limit
+}
+
+// resize downsamples with a box filter: each destination pixel is the
+// average of the source pixels it covers
+func resize(src *image.RGBA, w, h int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if w == sw && h == sh {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		y0 := y * sh / h
+		y1 := max((y+1)*sh/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := x * sw / w
+			x1 := max((x+1)*sw/w, x0+1)
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += uint32(row[i])
+					g += uint32(row[i+1])
+					b += uint32(row[i+2])
+					a += uint32(row[i+3])
+					n++
+				}
+			}
+
+			off := y*dst.Stride + x*4
+			dst.Pix[off] = uint8(r / n)
+			dst.Pix[off+1] = uint8(g / n)
+			dst.Pix[off+2] = uint8(b / n)
+			dst.Pix[off+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
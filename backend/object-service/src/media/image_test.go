@@ -0,0 +1,113 @@
+package media_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hexagone/object-service/src/media"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testImage(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	return img
+}
+
+// jpegWithExif encodes a JPEG and splices in an APP1 segment carrying an
+// orientation tag and a fake GPS payload
+func jpegWithExif(t *testing.T, w, h int, orientation uint16) []byte {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, testImage(w, h), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)      // one IFD entry
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112) // orientation
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)      // SHORT
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	tiff = append(tiff, []byte("GPS 48.8566N 2.3522E")...)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	data := encoded.Bytes()
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestProcessStripsMetadataAndAppliesOrientation(t *testing.T) {
+	upload := jpegWithExif(t, 400, 200, 6)
+	assert.Contains(t, string(upload), "GPS 48.8566N")
+
+	renditions, err := media.Process(upload)
+	assert.NoError(t, err)
+	if !assert.Len(t, renditions, 1+len(media.Variants)) {
+		return
+	}
+
+	original := renditions[0]
+	assert.Equal(t, "original", original.Name)
+	assert.Equal(t, "image/jpeg", original.ContentType)
+	assert.Equal(t, 200, original.Width, "rotated 90 degrees")
+	assert.Equal(t, 400, original.Height)
+
+	for _, rendition := range renditions {
+		assert.NotContains(t, string(rendition.Data), "Exif")
+		assert.NotContains(t, string(rendition.Data), "GPS")
+	}
+}
+
+func TestProcessThumbnailSizes(t *testing.T) {
+	var encoded bytes.Buffer
+	png.Encode(&encoded, testImage(2000, 1000))
+
+	renditions, err := media.Process(encoded.Bytes())
+	assert.NoError(t, err)
+
+	sizes := map[string][2]int{}
+	for _, rendition := range renditions {
+		assert.Equal(t, "image/png", rendition.ContentType)
+		sizes[rendition.Name] = [2]int{rendition.Width, rendition.Height}
+
+		decoded, _, err := image.Decode(bytes.NewReader(rendition.Data))
+		if assert.NoError(t, err) {
+			assert.Equal(t, rendition.Width, decoded.Bounds().Dx())
+		}
+	}
+	assert.Equal(t, [2]int{2000, 1000}, sizes["original"])
+	assert.Equal(t, [2]int{160, 80}, sizes["small"])
+	assert.Equal(t, [2]int{480, 240}, sizes["medium"])
+	assert.Equal(t, [2]int{1024, 512}, sizes["large"])
+
+	// Small images are never upscaled
+	encoded.Reset()
+	png.Encode(&encoded, testImage(100, 50))
+	renditions, _ = media.Process(encoded.Bytes())
+	for _, rendition := range renditions {
+		assert.Equal(t, 100, rendition.Width)
+	}
+}
+
+func TestProcessRejectsNonImages(t *testing.T) {
+	_, err := media.Process([]byte("%PDF-1.4 not an image"))
+	assert.ErrorIs(t, err, media.ErrUnsupportedType)
+
+	_, err = media.SniffContentType([]byte("<html><body>hi</body></html>"))
+	assert.ErrorIs(t, err, media.ErrUnsupportedType)
+}
@@ -0,0 +1,36 @@
+package models
+
+import "strings"
+
+// Category is a node of the object taxonomy, e.g. furniture → seating → chair.
+// Aliases are the free-text types that map onto it, such as "chaise" for chair.
+type Category struct {
+	ID       string      `json:"id"`
+	Name     string      `json:"name"`
+	ParentID string      `json:"parentId,omitempty"`
+	Aliases  []string    `json:"aliases,omitempty"`
+	Children []*Category `json:"children,omitempty"` // Only filled in when returning the tree
+}
+
+// NormalizeLabel lowercases a category name or free-text type and collapses
+// its whitespace so "Chair", "chair " and "CHAIR" compare equal
+func NormalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// Matches reports whether a free-text type designates this category
+func (c Category) Matches(label string) bool {
+	label = NormalizeLabel(label)
+	if label == "" {
+		return false
+	}
+	if NormalizeLabel(c.Name) == label {
+		return true
+	}
+	for _, alias := range c.Aliases {
+		if NormalizeLabel(alias) == label {
+			return true
+		}
+	}
+	return false
+}
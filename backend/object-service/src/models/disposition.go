@@ -0,0 +1,40 @@
+package models
+
+// Disposition is what happens to an object nobody in the family takes
+type Disposition string
+
+const (
+	DispositionUndecided Disposition = "undecided"
+	DispositionKeep      Disposition = "keep"
+	DispositionDonate    Disposition = "donate"
+	DispositionSell      Disposition = "sell"
+	DispositionDiscard   Disposition = "discard"
+)
+
+// Dispositions lists every outcome in report order
+var Dispositions = []Disposition{DispositionKeep, DispositionDonate, DispositionSell, DispositionDiscard, DispositionUndecided}
+
+// Valid reports whether d is a known disposition
+func (d Disposition) Valid() bool {
+	for _, known := range Dispositions {
+		if d == known {
+			return true
+		}
+	}
+	return false
+}
+
+// LeavesHouse reports whether the object goes to a charity, buyer or the tip
+// rather than staying with the family
+func (d Disposition) LeavesHouse() bool {
+	return d == DispositionDonate || d == DispositionSell || d == DispositionDiscard
+}
+
+// CurrentDisposition returns the disposition of an object, treating objects
+// created before dispositions existed as undecided
+func (o Object) CurrentDisposition() Disposition {
+	if o.Disposition == "" {
+		return DispositionUndecided
+	}
+	return o.Disposition
+}
@@ -0,0 +1,12 @@
+package models
+
+// Label is what gets printed on the sticker of an object: a short code that
+// can be typed by hand, and the deep link its QR code encodes
+type Label struct {
+	ObjectID string `json:"objectId"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	RoomID   string `json:"room_id"`
+	Code     string `json:"code"`
+	Link     string `json:"link"`
+}
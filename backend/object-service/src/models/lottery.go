@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// LotteryStatus is the state of a declare-interest window
+type LotteryStatus string
+
+const (
+	LotteryOpen   LotteryStatus = "open"   // Users can still declare interest
+	LotteryDrawn  LotteryStatus = "drawn"  // A winner has been picked
+	LotteryVoid   LotteryStatus = "void"   // The window closed without participants
+	LotteryFailed LotteryStatus = "failed" // A winner was picked but could not be given the object
+)
+
+// Participant is a user who declared interest in an object
+type Participant struct {
+	UserID string    `json:"userId"`
+	At     time.Time `json:"at"`
+}
+
+// Lottery is the declare-interest window of an object and, once closed, the
+// audit record of how its winner was drawn. Participants are sorted by user
+// ID before drawing, so the winner can be recomputed from Seed alone.
+type Lottery struct {
+	ObjectID     string        `json:"objectId"`
+	Status       LotteryStatus `json:"status"`
+	OpenedBy     string        `json:"openedBy"`
+	OpensAt      time.Time     `json:"opensAt"`
+	ClosesAt     time.Time     `json:"closesAt"`
+	Participants []Participant `json:"participants"`
+	Algorithm    string        `json:"algorithm,omitempty"`
+	Seed         int64         `json:"seed,omitempty"`
+	WinnerID     string        `json:"winnerId,omitempty"`
+	DrawnAt      *time.Time    `json:"drawnAt,omitempty"`
+	Reason       string        `json:"reason,omitempty"` // Why a failed draw did not reserve the object
+}
@@ -0,0 +1,56 @@
+package models
+
+import "strings"
+
+// Condition is the state an object is in, as assessed by whoever listed it
+type Condition string
+
+const (
+	ConditionNew      Condition = "new"
+	ConditionLikeNew  Condition = "like_new"
+	ConditionGood     Condition = "good"
+	ConditionFair     Condition = "fair"
+	ConditionPoor     Condition = "poor"
+	ConditionForParts Condition = "for_parts"
+)
+
+// Conditions lists every condition from best to worst
+var Conditions = []Condition{ConditionNew, ConditionLikeNew, ConditionGood, ConditionFair, ConditionPoor, ConditionForParts}
+
+// Valid reports whether c is a known condition
+func (c Condition) Valid() bool {
+	for _, known := range Conditions {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Dimensions of an object in centimetres, used to plan pickups
+type Dimensions struct {
+	WidthCm  float64 `json:"widthCm" binding:"gte=0"`
+	DepthCm  float64 `json:"depthCm" binding:"gte=0"`
+	HeightCm float64 `json:"heightCm" binding:"gte=0"`
+}
+
+const (
+	MaxTags      = 20
+	MaxTagLength = 40
+)
+
+// NormalizeTags lowercases and trims tags, dropping empty ones and duplicates
+// while keeping their order
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
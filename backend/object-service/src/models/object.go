@@ -1,10 +1,28 @@
 package models
 
+import "time"
+
 type Object struct {
-	ID          string `json:"id"`          // Unique identifier
-	Name        string `json:"name"`        // Name of the object
-	Type        string `json:"type"`        // Type of the object
-	IsReserved  bool   `json:"isReserved"`  // Indicates if the object is reserved
-	ReservedBy  string `json:"reservedBy"`  // User who reserved the object
-    RoomID      string `json:"room_id"`     // ID of the room this object belongs to
+	ID                string            `json:"id"`                          // Unique identifier
+	Name              string            `json:"name"`                        // Name of the object
+	Type              string            `json:"type"`                        // Type of the object
+	CategoryID        string            `json:"categoryId,omitempty"`        // Category of the object in the taxonomy
+	Description       string            `json:"description,omitempty"`       // Free-text description
+	Condition         Condition         `json:"condition,omitempty"`         // State the object is in
+	Dimensions        *Dimensions       `json:"dimensions,omitempty"`        // Size, for pickup logistics
+	WeightKg          float64           `json:"weightKg,omitempty"`          // Weight in kilograms, for pickup logistics
+	Material          string            `json:"material,omitempty"`          // Main material, e.g. oak or porcelain
+	Year              int               `json:"year,omitempty"`              // Year the object was made or bought
+	Value             float64           `json:"value,omitempty"`             // Estimated value in euros
+	Tags              []string          `json:"tags,omitempty"`              // Free-form lowercase tags
+	IsReserved        bool              `json:"isReserved"`                  // Indicates if the object is reserved
+	ReservedBy        string            `json:"reservedBy"`                  // User who reserved the object
+	ReservationStatus ReservationStatus `json:"reservationStatus,omitempty"` // Where the reservation is in the approval workflow
+	RoomID            string            `json:"room_id"`                     // ID of the room this object belongs to
+	HomeID            string            `json:"home_id,omitempty"`           // ID of the home the room belongs to
+	Disposition       Disposition       `json:"disposition,omitempty"`       // Outcome for the object if nobody claims it
+	PickupBookingID   string            `json:"pickupBookingId,omitempty"`   // Pickup slot booking the object is scheduled in
+	CollectedBy       string            `json:"collectedBy,omitempty"`       // User who took the object away
+	HandedOverBy      string            `json:"handedOverBy,omitempty"`      // User who confirmed the handover
+	CollectedAt       *time.Time        `json:"collectedAt,omitempty"`       // When the object left the house
 }
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// PhotoRendition is one stored size of a photo: the cleaned original or a thumbnail
+type PhotoRendition struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	ContentType string `json:"contentType"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Size        int    `json:"size"`
+	// SignedURL is a time-limited direct link to the media store, filled in
+	// when photos are returned and never persisted
+	SignedURL string `json:"signedUrl,omitempty"`
+}
+
+// Photo is a picture of an object. Photos are kept in display order; the
+// primary one is shown in listings.
+type Photo struct {
+	ID         string           `json:"id"`
+	ObjectID   string           `json:"objectId"`
+	Position   int              `json:"position"`
+	IsPrimary  bool             `json:"isPrimary"`
+	UploadedBy string           `json:"uploadedBy,omitempty"`
+	CreatedAt  time.Time        `json:"createdAt"`
+	Renditions []PhotoRendition `json:"renditions"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// PickupSlot is a time range during which heirs can come and collect their
+// objects from a home. Capacity is the number of bookings it accepts.
+type PickupSlot struct {
+	ID       string    `json:"id"`
+	HomeID   string    `json:"home_id"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Capacity int       `json:"capacity"`
+	Booked   int       `json:"booked"`
+}
+
+// PickupBooking reserves a place in a slot for one or more reserved objects
+type PickupBooking struct {
+	ID        string    `json:"id"`
+	SlotID    string    `json:"slotId"`
+	HomeID    string    `json:"home_id"`
+	UserID    string    `json:"userId"`
+	ObjectIDs []string  `json:"objectIds"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// ReservationStatus is the state of a claim on an object
+type ReservationStatus string
+
+const (
+	StatusAvailable ReservationStatus = ""          // Nobody has claimed the object
+	StatusPending   ReservationStatus = "pending"   // Claimed, waiting for the executor
+	StatusApproved  ReservationStatus = "approved"  // Claim accepted
+	StatusRejected  ReservationStatus = "rejected"  // Claim refused by the executor
+	StatusPickedUp  ReservationStatus = "picked_up" // Object has left the house
+)
+
+// reservationTransitions lists the states reachable from each state
+var reservationTransitions = map[ReservationStatus][]ReservationStatus{
+	StatusAvailable: {StatusPending, StatusApproved},
+	StatusPending:   {StatusApproved, StatusRejected, StatusAvailable},
+	StatusApproved:  {StatusPickedUp, StatusAvailable},
+	StatusRejected:  {StatusPending, StatusApproved},
+	StatusPickedUp:  {},
+}
+
+// CanTransition reports whether a claim may move from one state to another
+func (s ReservationStatus) CanTransition(to ReservationStatus) bool {
+	for _, next := range reservationTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentStatus returns the workflow state of an object, treating
+// reservations made before the workflow existed as approved
+func (o Object) CurrentStatus() ReservationStatus {
+	if o.ReservationStatus == StatusAvailable && o.IsReserved {
+		return StatusApproved
+	}
+	return o.ReservationStatus
+}
+
+// HomeSettings holds the per-home reservation configuration
+type HomeSettings struct {
+	HomeID          string     `json:"home_id"`
+	RequireApproval bool       `json:"requireApproval"`         // Reservations stay pending until the executor signs off
+	ExecutorID      string     `json:"executorId"`              // User allowed to approve or reject claims
+	ClaimDeadline   *time.Time `json:"claimDeadline,omitempty"` // After it, unclaimed objects can be given a disposition in bulk
+}
+
+// HistoryEntry records a single state change of an object's reservation, or
+// a move to another room. UserID is who made the change, ClaimantID whose
+// claim it affected.
+type HistoryEntry struct {
+	ObjectID   string            `json:"objectId"`
+	Action     string            `json:"action"`
+	From       ReservationStatus `json:"from"`
+	To         ReservationStatus `json:"to"`
+	UserID     string            `json:"userId"`
+	ClaimantID string            `json:"claimantId,omitempty"`
+	Comment    string            `json:"comment,omitempty"`
+	Move       *Move             `json:"move,omitempty"` // Set for moves
+	At         time.Time         `json:"at"`
+}
+
+// Move is where an object was moved from and to
+type Move struct {
+	FromRoomID string `json:"fromRoomId"`
+	FromHomeID string `json:"fromHomeId,omitempty"`
+	ToRoomID   string `json:"toRoomId"`
+	ToHomeID   string `json:"toHomeId"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// Kinds of search documents
+const (
+	SearchKindObject = "object"
+	SearchKindRoom   = "room"
+	SearchKindHome   = "home"
+)
+
+// SearchDocument is what the search index knows about an object, room or home
+type SearchDocument struct {
+	Kind        string   `json:"kind"`
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	HomeID      string   `json:"homeId,omitempty"`
+	RoomID      string   `json:"roomId,omitempty"`
+}
+
+// SearchResult is a matching document, best matches having the highest score
+type SearchResult struct {
+	SearchDocument
+	Score float64 `json:"score"`
+}
@@ -0,0 +1,88 @@
+// Package search turns free text into the normalized terms stored in the
+// search index, so that "Armoire Louis XV" and "armoire louis xv" or
+// "Théière" and "theiere" produce the same terms.
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// foldings maps accented Latin letters and ligatures to plain ASCII. It
+// covers French and the other Western European languages likely to appear
+// in object descriptions.
+var foldings = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a",
+	'ç': "c",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i",
+	'ñ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u",
+	'ý': "y", 'ÿ': "y",
+	'æ': "ae", 'œ': "oe", 'ß': "ss",
+}
+
+// stopWords are left out of the index: they match nearly everything and
+// carry no meaning on their own
+var stopWords = map[string]bool{
+	"au": true, "aux": true, "avec": true, "ce": true, "ces": true, "dans": true, "de": true,
+	"des": true, "du": true, "en": true, "et": true, "la": true, "le": true, "les": true,
+	"ou": true, "par": true, "pour": true, "sa": true, "ses": true, "son": true, "sur": true,
+	"un": true, "une": true, "the": true, "and": true, "of": true, "with": true,
+}
+
+// Fold lowercases s and strips its accents
+func Fold(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if folded, ok := foldings[r]; ok {
+			b.WriteString(folded)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// splitWords folds s and cuts it on anything that is not a letter or digit,
+// so "l'armoire" gives "l" and "armoire"
+func splitWords(s string) []string {
+	return strings.FieldsFunc(Fold(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Terms returns the distinct indexable terms of s: single letters and stop
+// words are dropped
+func Terms(s string) []string {
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, word := range splitWords(s) {
+		if len(word) < 2 || stopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// QueryTerms returns the terms to look up for a search query. The last word
+// is kept even when it is short or a stop word, since the user may still be
+// typing it ("la" on the way to "lampe").
+func QueryTerms(q string) []string {
+	words := splitWords(q)
+	if len(words) == 0 {
+		return nil
+	}
+
+	terms := Terms(strings.Join(words[:len(words)-1], " "))
+	last := words[len(words)-1]
+	for _, term := range terms {
+		if term == last {
+			return terms
+		}
+	}
+	return append(terms, last)
+}
@@ -0,0 +1,26 @@
+package search_test
+
+import (
+	"hexagone/object-service/src/search"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFold(t *testing.T) {
+	assert.Equal(t, "theiere en porcelaine", search.Fold("Théière en PORCELAINE"))
+	assert.Equal(t, "coeur d'oeuvre", search.Fold("Cœur d'Œuvre"))
+	assert.Equal(t, "garcon a l'ecole", search.Fold("Garçon à l'École"))
+}
+
+func TestTerms(t *testing.T) {
+	assert.Equal(t, []string{"armoire", "normande", "chene"}, search.Terms("L'armoire normande en chêne, armoire"))
+	assert.Empty(t, search.Terms("de la"))
+}
+
+func TestQueryTerms(t *testing.T) {
+	// The word being typed is kept even when it is a stop word
+	assert.Equal(t, []string{"vase", "la"}, search.QueryTerms("vase la"))
+	assert.Equal(t, []string{"vase", "bleu"}, search.QueryTerms("le vase bleu"))
+	assert.Nil(t, search.QueryTerms("  ,  "))
+}
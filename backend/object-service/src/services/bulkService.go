@@ -0,0 +1,303 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hexagone/object-service/src/database"
+	"hexagone/object-service/src/models"
+	"hexagone/shared/logging"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// Bulk actions on objects
+const (
+	BulkMove           = "move"
+	BulkDelete         = "delete"
+	BulkSetDisposition = "set_disposition"
+	BulkTag            = "tag"
+	BulkUntag          = "untag"
+)
+
+// Outcome of a bulk action for one object
+const (
+	BulkStatusOK       = "ok"
+	BulkStatusNotFound = "not_found"
+	BulkStatusConflict = "conflict"
+	BulkStatusInvalid  = "invalid"
+)
+
+// maxBulkAttempts bounds the retries when objects change while a bulk action
+// is being applied
+const maxBulkAttempts = 3
+
+type BulkObjectsInput struct {
+	Action      string             `json:"action" binding:"required,oneof=move delete set_disposition tag untag"`
+	IDs         []string           `json:"ids" binding:"required,min=1,max=500,dive,required"`
+	RoomID      string             `json:"roomId"`      // Target room of a move
+	Disposition models.Disposition `json:"disposition"` // Disposition to set
+	Tags        []string           `json:"tags"`        // Tags to add or remove
+}
+
+// BulkItemResult is what happened to one object of a bulk action
+type BulkItemResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkReport sums up a bulk action
+type BulkReport struct {
+	Action    string           `json:"action"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
+
+// bulkChange is an object before and after a bulk action, after being nil
+// when the object is deleted
+type bulkChange struct {
+	before models.Object
+	after  *models.Object
+	photos []models.Photo // Of a deleted object, whose blobs go once the transaction has committed
+}
+
+// bulkApply returns the change a bulk action makes to one object, nil
+// meaning the object is deleted. Pickup bookings it detaches the object from
+// are changed in bookings, queued with the objects in the transaction tx.
+type bulkApply func(tx *redis.Tx, bookings bookingChanges, object models.Object) (*models.Object, error)
+
+// errBulkItem rejects a single object without failing the whole action
+type errBulkItem struct {
+	status string
+	err    error
+}
+
+func (e errBulkItem) Error() string { return e.err.Error() }
+
+// BulkUpdateObjects handles POST /objects/bulk: moves, deletes, sets the
+// disposition of, tags or untags a list of objects. The objects that can be
+// changed are changed in a single DragonflyDB transaction, watched so that a
+// concurrent write makes it start over; the others are reported one by one.
+func BulkUpdateObjects(c *gin.Context) {
+	var input BulkObjectsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to bind input for bulk action")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	apply, ok := bulkAction(c, &input)
+	if !ok {
+		return
+	}
+
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, id := range input.IDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"action": input.Action,
+		"count":  len(ids),
+	}).Info("Applying bulk action to objects")
+
+	var results []BulkItemResult
+	var changes []bulkChange
+	transaction := func(tx *redis.Tx) error {
+		results, changes = make([]BulkItemResult, len(ids)), nil
+		bookings := bookingChanges{}
+		for i, id := range ids {
+			results[i] = BulkItemResult{ID: id, Status: BulkStatusOK}
+
+			if !isObjectKey(id) {
+				results[i].Status, results[i].Error = BulkStatusNotFound, "Object not found"
+				continue
+			}
+			val, err := tx.Get(requestCtx(c), id).Result()
+			if err == redis.Nil {
+				results[i].Status, results[i].Error = BulkStatusNotFound, "Object not found"
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			var object models.Object
+			if err := json.Unmarshal([]byte(val), &object); err != nil {
+				return err
+			}
+
+			after, err := apply(tx, bookings, object)
+			var itemErr errBulkItem
+			if errors.As(err, &itemErr) {
+				results[i].Status, results[i].Error = itemErr.status, itemErr.Error()
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			var photos []models.Photo
+			if after == nil {
+				if photos, err = prepareObjectDeletion(requestCtx(c), tx, bookings, &object); err != nil {
+					return err
+				}
+			}
+			changes = append(changes, bulkChange{before: object, after: after, photos: photos})
+		}
+
+		_, err := tx.TxPipelined(requestCtx(c), func(pipe redis.Pipeliner) error {
+			for _, change := range changes {
+				if change.after == nil {
+					queueObjectDeletion(requestCtx(c), pipe, change.before)
+					continue
+				}
+				data, err := json.Marshal(change.after)
+				if err != nil {
+					return err
+				}
+				pipe.Set(requestCtx(c), change.after.ID, data, 0)
+				unindexObjectMetadata(requestCtx(c), pipe, change.before)
+				indexObjectMetadata(requestCtx(c), pipe, *change.after)
+			}
+			return bookings.queue(requestCtx(c), pipe)
+		})
+		return err
+	}
+
+	var err error
+	for attempt := 0; attempt < maxBulkAttempts; attempt++ {
+		if err = database.RDB.Watch(requestCtx(c), transaction, ids...); err != redis.TxFailedErr {
+			break
+		}
+	}
+	if err == redis.TxFailedErr {
+		c.JSON(http.StatusConflict, gin.H{"error": "The objects kept being modified during the bulk action, nothing was changed, try again"})
+		return
+	}
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"action": input.Action,
+			"error":  err.Error(),
+		}).Error("Failed to apply bulk action")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply bulk action"})
+		return
+	}
+
+	// The search and reservation indexes and the history are kept outside
+	// the transaction
+	for _, change := range changes {
+		if change.after == nil {
+			afterObjectDeletion(requestCtx(c), change.before, change.photos)
+			continue
+		}
+		if input.Action == BulkMove && change.before.RoomID != change.after.RoomID {
+			recordMove(requestCtx(c), change.before, *change.after, c.GetHeader("X-User-ID"), "")
+		}
+		if input.Action != BulkSetDisposition {
+			indexObjectForSearch(requestCtx(c), *change.after)
+		}
+	}
+
+	report := BulkReport{Action: input.Action, Results: results}
+	for _, result := range results {
+		if result.Status == BulkStatusOK {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"action":    input.Action,
+		"succeeded": report.Succeeded,
+		"failed":    report.Failed,
+	}).Info("Bulk action applied")
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// bulkAction checks the parameters of a bulk action and returns the change
+// it makes to one object. It writes the error response and returns false
+// when the parameters are invalid.
+func bulkAction(c *gin.Context, input *BulkObjectsInput) (bulkApply, bool) {
+	switch input.Action {
+	case BulkMove:
+		if input.RoomID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "roomId is required to move objects"})
+			return nil, false
+		}
+		room, ok := resolveTargetRoom(c, input.RoomID)
+		if !ok {
+			return nil, false
+		}
+		return func(tx *redis.Tx, bookings bookingChanges, object models.Object) (*models.Object, error) {
+			if err := relocate(requestCtx(c), tx, bookings, &object, room); err != nil {
+				return nil, err
+			}
+			return &object, nil
+		}, true
+
+	case BulkDelete:
+		return func(*redis.Tx, bookingChanges, models.Object) (*models.Object, error) { return nil, nil }, true
+
+	case BulkSetDisposition:
+		if !input.Disposition.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown disposition", "allowed": models.Dispositions})
+			return nil, false
+		}
+		return func(_ *redis.Tx, _ bookingChanges, object models.Object) (*models.Object, error) {
+			if object.IsReserved && input.Disposition.LeavesHouse() {
+				return nil, errBulkItem{BulkStatusConflict, errors.New("Reserved objects go to the family member who claimed them")}
+			}
+			object.Disposition = input.Disposition
+			return &object, nil
+		}, true
+
+	default: // BulkTag and BulkUntag
+		tags := models.NormalizeTags(input.Tags)
+		if len(tags) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tags are required to tag or untag objects"})
+			return nil, false
+		}
+		for _, tag := range tags {
+			if len([]rune(tag)) > models.MaxTagLength {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("tag %q is longer than %d characters", tag, models.MaxTagLength)})
+				return nil, false
+			}
+		}
+
+		if input.Action == BulkUntag {
+			removed := map[string]bool{}
+			for _, tag := range tags {
+				removed[tag] = true
+			}
+			return func(_ *redis.Tx, _ bookingChanges, object models.Object) (*models.Object, error) {
+				kept := []string{}
+				for _, tag := range object.Tags {
+					if !removed[tag] {
+						kept = append(kept, tag)
+					}
+				}
+				object.Tags = kept
+				return &object, nil
+			}, true
+		}
+		return func(_ *redis.Tx, _ bookingChanges, object models.Object) (*models.Object, error) {
+			object.Tags = models.NormalizeTags(append(object.Tags, tags...))
+			if len(object.Tags) > models.MaxTags {
+				return nil, errBulkItem{BulkStatusInvalid, fmt.Errorf("an object can have at most %d tags", models.MaxTags)}
+			}
+			return &object, nil
+		}, true
+	}
+}
@@ -0,0 +1,135 @@
+package services_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"hexagone/object-service/src/models"
+	"hexagone/object-service/src/services"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bulkUpdate(t *testing.T, input map[string]interface{}, expectedCode int) services.BulkReport {
+	w := performRequest("POST", "/objects/bulk", input, "")
+	require.Equal(t, expectedCode, w.Code, w.Body.String())
+
+	var response map[string]services.BulkReport
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response["data"]
+}
+
+func mustLoadObject(t *testing.T, id string) models.Object {
+	val, err := mr.Get(id)
+	require.NoError(t, err)
+	var object models.Object
+	require.NoError(t, json.Unmarshal([]byte(val), &object))
+	return object
+}
+
+func TestBulkMoveAndDelete(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+	chair := createTestObject(t, map[string]interface{}{"name": "Chaise", "type": "furniture", "room_id": "garage", "tags": []string{"seating"}})
+	bike := createTestObject(t, map[string]interface{}{"name": "Vélo", "type": "sport", "room_id": "garage"})
+	performRequest("PATCH", "/objects/"+bike.ID+"/reserve", nil, "user1")
+	rooms := fakeRoomService(t)
+	defer rooms.Close()
+
+	report := bulkUpdate(t, map[string]interface{}{
+		"action": "move", "roomId": "1", "ids": []string{chair.ID, bike.ID, "missing", chair.ID},
+	}, http.StatusOK)
+	assert.Equal(t, 2, report.Succeeded)
+	assert.Equal(t, 1, report.Failed)
+	require.Len(t, report.Results, 3, "duplicate IDs are applied once")
+	assert.Equal(t, services.BulkStatusNotFound, report.Results[2].Status)
+
+	moved := mustLoadObject(t, bike.ID)
+	assert.Equal(t, "1", moved.RoomID)
+	assert.Equal(t, "1", moved.HomeID, "the home follows the target room")
+	assert.True(t, moved.IsReserved, "moving keeps the reservation")
+
+	bulkUpdate(t, map[string]interface{}{"action": "move", "roomId": "42", "ids": []string{chair.ID}}, http.StatusUnprocessableEntity)
+	bulkUpdate(t, map[string]interface{}{"action": "move", "ids": []string{chair.ID}}, http.StatusBadRequest)
+
+	report = bulkUpdate(t, map[string]interface{}{"action": "delete", "ids": []string{chair.ID, bike.ID}}, http.StatusOK)
+	assert.Equal(t, 2, report.Succeeded)
+	assert.False(t, mr.Exists(chair.ID))
+	assert.False(t, mr.Exists(bike.ID))
+	assert.Empty(t, mustMembersOrNil(t, "tag:seating:objects"))
+	assert.Empty(t, mustMembersOrNil(t, "user:user1:reservations"))
+
+	performRequest("PUT", "/homes/1/settings", map[string]interface{}{"executorId": "executor"}, "")
+	report = bulkUpdate(t, map[string]interface{}{"action": "delete", "ids": []string{"home:1:settings"}}, http.StatusOK)
+	assert.Equal(t, services.BulkStatusNotFound, report.Results[0].Status, "only objects can be changed")
+	assert.True(t, mr.Exists("home:1:settings"))
+}
+
+func TestBulkMoveReleasesBooking(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	slot := createTestSlot(t, "1", 2)
+	chair := reserveTestObject(t, "Chaise", "1", "heir1")
+	table := reserveTestObject(t, "Table", "1", "heir1")
+	w := performRequest("POST", "/pickup-slots/"+slot.ID+"/bookings", map[string]interface{}{
+		"objectIds": []string{chair.ID, table.ID},
+	}, "heir1")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response map[string]models.PickupBooking
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	rooms := fakeRoomService(t)
+	defer rooms.Close()
+	resp, err := http.Post(rooms.URL+"/rooms", "application/json", bytes.NewBufferString(`{"name": "Cave", "home_id": 2}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	report := bulkUpdate(t, map[string]interface{}{"action": "move", "roomId": "2", "ids": []string{chair.ID, table.ID}}, http.StatusOK)
+	assert.Equal(t, 2, report.Succeeded)
+
+	// Both objects leave the booking, which is released once
+	assert.Empty(t, mustLoadObject(t, chair.ID).PickupBookingID)
+	assert.Empty(t, mustLoadObject(t, table.ID).PickupBookingID)
+	assert.False(t, mr.Exists("pickup:booking:"+response["data"].ID))
+	booked, _ := mr.Get("pickup:slot:" + slot.ID + ":booked")
+	assert.Equal(t, "0", booked)
+}
+
+func TestBulkDispositionAndTags(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	lamp := createTestObject(t, map[string]interface{}{"name": "Lampe", "type": "lighting", "room_id": "room1", "tags": []string{"art deco"}})
+	vase := createTestObject(t, map[string]interface{}{"name": "Vase", "type": "decor", "room_id": "room1"})
+	performRequest("PATCH", "/objects/"+vase.ID+"/reserve", nil, "user1")
+
+	report := bulkUpdate(t, map[string]interface{}{"action": "set_disposition", "disposition": "donate", "ids": []string{lamp.ID, vase.ID}}, http.StatusOK)
+	assert.Equal(t, services.BulkStatusOK, report.Results[0].Status)
+	assert.Equal(t, services.BulkStatusConflict, report.Results[1].Status, "reserved objects stay with their claimant")
+	assert.Equal(t, models.DispositionDonate, mustLoadObject(t, lamp.ID).Disposition)
+	assert.Equal(t, models.DispositionUndecided, mustLoadObject(t, vase.ID).CurrentDisposition())
+
+	bulkUpdate(t, map[string]interface{}{"action": "set_disposition", "disposition": "burn", "ids": []string{lamp.ID}}, http.StatusBadRequest)
+
+	report = bulkUpdate(t, map[string]interface{}{"action": "tag", "tags": []string{"Fragile", "art deco"}, "ids": []string{lamp.ID, vase.ID}}, http.StatusOK)
+	assert.Equal(t, 2, report.Succeeded)
+	assert.Equal(t, []string{"art deco", "fragile"}, mustLoadObject(t, lamp.ID).Tags)
+	assert.ElementsMatch(t, []string{lamp.ID, vase.ID}, mustMembers(t, "tag:fragile:objects"))
+
+	bulkUpdate(t, map[string]interface{}{"action": "untag", "tags": []string{"art deco"}, "ids": []string{lamp.ID}}, http.StatusOK)
+	assert.Equal(t, []string{"fragile"}, mustLoadObject(t, lamp.ID).Tags)
+	assert.Equal(t, []string{vase.ID}, mustMembers(t, "tag:art deco:objects"))
+
+	bulkUpdate(t, map[string]interface{}{"action": "tag", "ids": []string{lamp.ID}}, http.StatusBadRequest)
+	bulkUpdate(t, map[string]interface{}{"action": "paint", "ids": []string{lamp.ID}}, http.StatusBadRequest)
+	bulkUpdate(t, map[string]interface{}{"action": "delete", "ids": []string{}}, http.StatusBadRequest)
+}
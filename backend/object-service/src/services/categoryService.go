@@ -0,0 +1,549 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hexagone/object-service/src/database"
+	"hexagone/object-service/src/models"
+	"hexagone/shared/logging"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// categoriesKey is the set of every category ID
+const categoriesKey = "categories:ids"
+
+func categoryKey(categoryID string) string {
+	return "category:" + categoryID
+}
+
+// categoryObjectsKey is the set of objects filed directly under a category
+func categoryObjectsKey(categoryID string) string {
+	return "category:" + categoryID + ":objects"
+}
+
+// loadCategories returns the whole taxonomy keyed by category ID
+func loadCategories(ctx context.Context) (map[string]models.Category, error) {
+	categories := map[string]models.Category{}
+
+	ids, err := database.RDB.SMembers(ctx, categoriesKey).Result()
+	if err != nil || len(ids) == 0 {
+		return categories, err
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = categoryKey(id)
+	}
+	values, err := database.RDB.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, value := range values {
+		val, ok := value.(string)
+		if !ok {
+			logging.LogFrom(ctx).WithField("categoryID", ids[i]).Warn("Indexed category no longer exists, skipping")
+			continue
+		}
+
+		var category models.Category
+		if err := json.Unmarshal([]byte(val), &category); err != nil {
+			logging.LogFrom(ctx).WithField("categoryID", ids[i]).Warn("Failed to unmarshal category data, skipping")
+			continue
+		}
+		categories[category.ID] = category
+	}
+	return categories, nil
+}
+
+func saveCategory(ctx context.Context, category models.Category) error {
+	category.Children = nil
+	data, err := json.Marshal(category)
+	if err != nil {
+		return err
+	}
+
+	pipe := database.RDB.TxPipeline()
+	pipe.Set(ctx, categoryKey(category.ID), data, 0)
+	pipe.SAdd(ctx, categoriesKey, category.ID)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// categoryDescendants returns a category ID followed by the IDs of every
+// category below it
+func categoryDescendants(categories map[string]models.Category, categoryID string) []string {
+	ids := []string{categoryID}
+	for i := 0; i < len(ids); i++ {
+		for _, category := range categories {
+			if category.ParentID == ids[i] {
+				ids = append(ids, category.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// matchCategory finds the single category a free-text type designates
+func matchCategory(categories map[string]models.Category, label string) (models.Category, bool) {
+	var match models.Category
+	found := 0
+	for _, category := range categories {
+		if category.Matches(label) {
+			match = category
+			found++
+		}
+	}
+	return match, found == 1
+}
+
+// validateCategory checks a new or updated category against the rest of the
+// taxonomy: its parent must exist without creating a cycle, and its name and
+// aliases must not designate another category
+func validateCategory(categories map[string]models.Category, category models.Category) error {
+	if models.NormalizeLabel(category.Name) == "" {
+		return errors.New("name is required")
+	}
+
+	if category.ParentID != "" {
+		if _, ok := categories[category.ParentID]; !ok {
+			return errors.New("parent category not found")
+		}
+		for _, id := range categoryDescendants(categories, category.ID) {
+			if id == category.ParentID {
+				return errors.New("a category cannot be moved below itself")
+			}
+		}
+	}
+
+	for _, other := range categories {
+		if other.ID == category.ID {
+			continue
+		}
+		for _, label := range append([]string{category.Name}, category.Aliases...) {
+			if other.Matches(label) {
+				return fmt.Errorf("%q already designates category %q", label, other.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// categoryTree nests categories under their parents, siblings sorted by name
+func categoryTree(categories map[string]models.Category) []*models.Category {
+	nodes := map[string]*models.Category{}
+	for id, category := range categories {
+		category.Children = nil
+		nodes[id] = &category
+	}
+
+	roots := []*models.Category{}
+	for _, node := range nodes {
+		if parent, ok := nodes[node.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	var sortNodes func([]*models.Category)
+	sortNodes = func(list []*models.Category) {
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+		for _, node := range list {
+			sortNodes(node.Children)
+		}
+	}
+	sortNodes(roots)
+	return roots
+}
+
+// ListCategories returns the taxonomy as a flat list, or nested with ?tree=true
+func ListCategories(c *gin.Context) {
+	categories, err := loadCategories(requestCtx(c))
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to load categories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load categories"})
+		return
+	}
+
+	if c.Query("tree") == "true" {
+		c.JSON(http.StatusOK, gin.H{"data": categoryTree(categories)})
+		return
+	}
+
+	list := make([]models.Category, 0, len(categories))
+	for _, category := range categories {
+		list = append(list, category)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// GetCategory returns a category with its subtree
+func GetCategory(c *gin.Context) {
+	categoryID := c.Param("id")
+
+	categories, err := loadCategories(requestCtx(c))
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to load categories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load categories"})
+		return
+	}
+	if _, ok := categories[categoryID]; !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	// The category is the only root of its subtree since its parent is left out
+	subtree := map[string]models.Category{}
+	for _, id := range categoryDescendants(categories, categoryID) {
+		subtree[id] = categories[id]
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": categoryTree(subtree)[0]})
+}
+
+type CategoryInput struct {
+	Name     string   `json:"name" binding:"required"`
+	ParentID string   `json:"parentId"`
+	Aliases  []string `json:"aliases"`
+}
+
+// CreateCategory adds a category to the taxonomy
+func CreateCategory(c *gin.Context) {
+	var input CategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to bind JSON input for category creation")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	categories, err := loadCategories(requestCtx(c))
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to load categories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load categories"})
+		return
+	}
+
+	category := models.Category{
+		ID:       uuid.New().String(),
+		Name:     input.Name,
+		ParentID: input.ParentID,
+		Aliases:  models.NormalizeTags(input.Aliases),
+	}
+	if err := validateCategory(categories, category); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := saveCategory(requestCtx(c), category); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"categoryID": category.ID,
+			"error":      err.Error(),
+		}).Error("Failed to store category")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store category"})
+		return
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"categoryID": category.ID,
+		"name":       category.Name,
+		"parentID":   category.ParentID,
+	}).Info("Category created")
+
+	c.JSON(http.StatusOK, gin.H{"data": category})
+}
+
+type UpdateCategoryInput struct {
+	Name     *string  `json:"name"`
+	ParentID *string  `json:"parentId"`
+	Aliases  []string `json:"aliases"`
+}
+
+// UpdateCategory renames a category, moves it under another parent or
+// replaces its aliases
+func UpdateCategory(c *gin.Context) {
+	categoryID := c.Param("id")
+
+	var input UpdateCategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"categoryID": categoryID,
+			"error":      err.Error(),
+		}).Error("Failed to bind input for category update")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	categories, err := loadCategories(requestCtx(c))
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to load categories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load categories"})
+		return
+	}
+	category, ok := categories[categoryID]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	previousName := category.Name
+	if input.Name != nil {
+		category.Name = *input.Name
+	}
+	if input.ParentID != nil {
+		category.ParentID = *input.ParentID
+	}
+	if input.Aliases != nil {
+		category.Aliases = models.NormalizeTags(input.Aliases)
+	}
+	if err := validateCategory(categories, category); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := saveCategory(requestCtx(c), category); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"categoryID": categoryID,
+			"error":      err.Error(),
+		}).Error("Failed to store category")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store category"})
+		return
+	}
+
+	// Objects that took their type from the category follow the new name
+	if category.Name != previousName {
+		renamed, err := renameObjectTypes(requestCtx(c), categoryID, previousName, category.Name)
+		if err != nil {
+			logging.RequestLog(c).WithFields(logrus.Fields{
+				"categoryID": categoryID,
+				"renamed":    renamed,
+				"error":      err.Error(),
+			}).Error("Failed to rename the type of the category's objects")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Category renamed, but failed to rename the type of its objects"})
+			return
+		}
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"categoryID": categoryID,
+			"renamed":    renamed,
+		}).Info("Object types renamed with their category")
+	}
+
+	logging.RequestLog(c).WithField("categoryID", categoryID).Info("Category updated")
+	c.JSON(http.StatusOK, gin.H{"data": category})
+}
+
+// DeleteCategory removes a category that has neither subcategories nor objects
+func DeleteCategory(c *gin.Context) {
+	categoryID := c.Param("id")
+
+	categories, err := loadCategories(requestCtx(c))
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to load categories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load categories"})
+		return
+	}
+	if _, ok := categories[categoryID]; !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	if len(categoryDescendants(categories, categoryID)) > 1 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Category still has subcategories"})
+		return
+	}
+	count, err := database.RDB.SCard(requestCtx(c), categoryObjectsKey(categoryID)).Result()
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to count category objects")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Category is still used by %d objects", count)})
+		return
+	}
+
+	pipe := database.RDB.TxPipeline()
+	pipe.Del(requestCtx(c), categoryKey(categoryID), categoryObjectsKey(categoryID))
+	pipe.SRem(requestCtx(c), categoriesKey, categoryID)
+	if _, err := pipe.Exec(requestCtx(c)); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"categoryID": categoryID,
+			"error":      err.Error(),
+		}).Error("Failed to delete category")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+
+	logging.RequestLog(c).WithField("categoryID", categoryID).Info("Category deleted")
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+}
+
+// resolveObjectCategory fills in the category of a new object from its
+// categoryId, or from its free-text type when no category is given. It
+// returns a warning for the client when the type matches no category.
+func resolveObjectCategory(ctx context.Context, input *CreateObjectInput) (string, error) {
+	categories, err := loadCategories(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if input.CategoryID != "" {
+		category, ok := categories[input.CategoryID]
+		if !ok {
+			return "", errUnknownCategory
+		}
+		if input.Type == "" {
+			input.Type = category.Name
+		}
+		return "", nil
+	}
+
+	input.CategoryID = categoryForType(categories, input.Type)
+	return unmatchedTypeWarning(categories, input.Type, input.CategoryID), nil
+}
+
+var errUnknownCategory = errors.New("category not found")
+
+// categoryForType returns the ID of the category a free-text type files an
+// object under, or "" when it matches none and the object stays
+// uncategorized until MigrateObjectTypes picks it up
+func categoryForType(categories map[string]models.Category, label string) string {
+	category, ok := matchCategory(categories, label)
+	if !ok {
+		return ""
+	}
+	return category.ID
+}
+
+// unmatchedTypeWarning tells the client that an object was left outside an
+// existing taxonomy, so that its type can be added to a category as an alias
+func unmatchedTypeWarning(categories map[string]models.Category, label, categoryID string) string {
+	if len(categories) == 0 || label == "" || categoryID != "" {
+		return ""
+	}
+	return fmt.Sprintf("Type %q matches no category, the object is left uncategorized", label)
+}
+
+// renameObjectTypes gives the objects of a category whose type is the
+// category's old name the new name, and returns how many were renamed. Each
+// object is checked again as it is written, in case it was changed meanwhile.
+func renameObjectTypes(ctx context.Context, categoryID, from, to string) (int, error) {
+	objectIDs, err := database.RDB.SMembers(ctx, categoryObjectsKey(categoryID)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	renamed := 0
+	for _, objectID := range objectIDs {
+		changed := false
+		_, object, err := updateObject(ctx, objectID, func(_ *redis.Tx, _ bookingChanges, object *models.Object) error {
+			changed = object.CategoryID == categoryID && models.NormalizeLabel(object.Type) == models.NormalizeLabel(from)
+			if changed {
+				object.Type = to
+			}
+			return nil
+		})
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return renamed, err
+		}
+		if !changed {
+			continue
+		}
+
+		indexObjectForSearch(ctx, object)
+		renamed++
+	}
+	return renamed, nil
+}
+
+// MigrationReport tells how free-text types were mapped onto the taxonomy
+type MigrationReport struct {
+	DryRun    bool           `json:"dryRun"`
+	Mapped    map[string]int `json:"mapped"`    // Objects per category ID
+	Unmatched map[string]int `json:"unmatched"` // Objects per free-text type without a category
+	Changed   int            `json:"changed"`   // Objects left alone because they were changed or deleted meanwhile
+}
+
+// MigrateObjectTypes files every uncategorized object under the category its
+// free-text type designates. With ?dryRun=true nothing is written.
+func MigrateObjectTypes(c *gin.Context) {
+	dryRun := c.Query("dryRun") == "true"
+
+	categories, err := loadCategories(requestCtx(c))
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to load categories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load categories"})
+		return
+	}
+
+	objects, err := fetchObjects(requestCtx(c), func(obj models.Object) bool { return obj.CategoryID == "" })
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to fetch objects")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch objects"})
+		return
+	}
+
+	report := MigrationReport{DryRun: dryRun, Mapped: map[string]int{}, Unmatched: map[string]int{}}
+	for _, object := range objects {
+		category, ok := matchCategory(categories, object.Type)
+		if !ok {
+			report.Unmatched[object.Type]++
+			continue
+		}
+
+		if !dryRun {
+			// The object is filed only if it is still uncategorized with the
+			// same type when it is written
+			_, _, err := updateObject(requestCtx(c), object.ID, func(_ *redis.Tx, _ bookingChanges, current *models.Object) error {
+				if current.CategoryID != "" || current.Type != object.Type {
+					return errRefused{http.StatusConflict, "Object was changed during the migration"}
+				}
+				current.CategoryID = category.ID
+				return nil
+			})
+			var refused errRefused
+			if err == redis.Nil || err == redis.TxFailedErr || errors.As(err, &refused) {
+				report.Changed++
+				continue
+			}
+			if err != nil {
+				logging.RequestLog(c).WithFields(logrus.Fields{
+					"objectID": object.ID,
+					"error":    err.Error(),
+				}).Error("Failed to save migrated object")
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save object", "data": report})
+				return
+			}
+		}
+		report.Mapped[category.ID]++
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"dryRun":    dryRun,
+		"mapped":    sumCounts(report.Mapped),
+		"unmatched": sumCounts(report.Unmatched),
+	}).Info("Object types migrated to categories")
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+func sumCounts(counts map[string]int) int {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}
@@ -0,0 +1,156 @@
+package services_test
+
+import (
+	"encoding/json"
+	"hexagone/object-service/src/models"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestCategory(t *testing.T, input map[string]interface{}) models.Category {
+	w := performRequest("POST", "/categories", input, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]models.Category
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response["data"]
+}
+
+func TestCategoryTaxonomy(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	furniture := createTestCategory(t, map[string]interface{}{"name": "Furniture"})
+	seating := createTestCategory(t, map[string]interface{}{"name": "Seating", "parentId": furniture.ID})
+	chair := createTestCategory(t, map[string]interface{}{"name": "Chair", "parentId": seating.ID, "aliases": []string{"Chaise"}})
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		body         map[string]interface{}
+		expectedCode int
+	}{
+		{"Unknown Parent", "POST", "/categories", map[string]interface{}{"name": "Lamp", "parentId": "nonexistent"}, http.StatusBadRequest},
+		{"Name Already Used", "POST", "/categories", map[string]interface{}{"name": "chair"}, http.StatusBadRequest},
+		{"Alias Already Used", "POST", "/categories", map[string]interface{}{"name": "Stool", "aliases": []string{"CHAISE"}}, http.StatusBadRequest},
+		{"Move Below Own Descendant", "PATCH", "/categories/" + furniture.ID, map[string]interface{}{"parentId": chair.ID}, http.StatusBadRequest},
+		{"Delete With Subcategories", "DELETE", "/categories/" + seating.ID, nil, http.StatusConflict},
+		{"Update Unknown Category", "PATCH", "/categories/nonexistent", map[string]interface{}{"name": "x"}, http.StatusNotFound},
+		{"Add Alias", "PATCH", "/categories/" + seating.ID, map[string]interface{}{"aliases": []string{"seat"}}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body interface{}
+			if tt.body != nil {
+				body = tt.body
+			}
+			w := performRequest(tt.method, tt.url, body, "")
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+
+	w := performRequest("GET", "/categories?tree=true", nil, "")
+	var tree map[string][]models.Category
+	json.Unmarshal(w.Body.Bytes(), &tree)
+	if assert.Len(t, tree["data"], 1) && assert.Len(t, tree["data"][0].Children, 1) {
+		assert.Equal(t, "Chair", tree["data"][0].Children[0].Children[0].Name)
+	}
+
+	// Objects are filed by category ID, or by a free-text type matching a name or alias
+	createTestObject(t, map[string]interface{}{"name": "Fauteuil Voltaire", "categoryId": seating.ID, "room_id": "room1"})
+	createTestObject(t, map[string]interface{}{"name": "Chaise paillée", "type": "chaise", "room_id": "room1"})
+
+	w = performRequest("POST", "/objects", map[string]interface{}{"name": "x", "categoryId": "nonexistent", "room_id": "room1"}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest("POST", "/objects", map[string]interface{}{"name": "Table", "type": "table", "room_id": "room1"}, "")
+	assert.Equal(t, http.StatusOK, w.Code, "a type outside the taxonomy is accepted")
+	var unmatched struct {
+		Data    models.Object `json:"data"`
+		Warning string        `json:"warning"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &unmatched)
+	assert.Empty(t, unmatched.Data.CategoryID, "and the object stays uncategorized")
+	assert.Contains(t, unmatched.Warning, "matches no category")
+
+	names := func(url string) []string {
+		w := performRequest("GET", url, nil, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response map[string][]models.Object
+		json.Unmarshal(w.Body.Bytes(), &response)
+		names := []string{}
+		for _, object := range response["data"] {
+			names = append(names, object.Name)
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{"Fauteuil Voltaire", "Chaise paillée"}, names("/objects?category="+furniture.ID), "descendants are included")
+	assert.ElementsMatch(t, []string{"Chaise paillée"}, names("/objects?category="+chair.ID))
+
+	w = performRequest("DELETE", "/categories/"+chair.ID, nil, "")
+	assert.Equal(t, http.StatusConflict, w.Code, "category still used by an object")
+
+	// Objects that took their type from a category follow its new name
+	w = performRequest("PATCH", "/categories/"+seating.ID, map[string]interface{}{"name": "Sièges"}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest("GET", "/objects?category="+seating.ID, nil, "")
+	var renamed map[string][]models.Object
+	json.Unmarshal(w.Body.Bytes(), &renamed)
+	types := map[string]string{}
+	for _, object := range renamed["data"] {
+		types[object.Name] = object.Type
+	}
+	assert.Equal(t, map[string]string{"Fauteuil Voltaire": "Sièges", "Chaise paillée": "chaise"}, types)
+}
+
+func TestMigrateObjectTypes(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	// Objects created before the taxonomy existed
+	createTestObject(t, map[string]interface{}{"name": "Chaise 1", "type": "chair", "room_id": "room1"})
+	createTestObject(t, map[string]interface{}{"name": "Chaise 2", "type": "Chaise ", "room_id": "room1"})
+	createTestObject(t, map[string]interface{}{"name": "Banc", "type": "seating", "room_id": "room1"})
+	createTestObject(t, map[string]interface{}{"name": "Vase", "type": "decoration", "room_id": "room1"})
+
+	seating := createTestCategory(t, map[string]interface{}{"name": "Seating"})
+	chair := createTestCategory(t, map[string]interface{}{"name": "Chair", "parentId": seating.ID, "aliases": []string{"chaise"}})
+
+	var response map[string]struct {
+		DryRun    bool           `json:"dryRun"`
+		Mapped    map[string]int `json:"mapped"`
+		Unmatched map[string]int `json:"unmatched"`
+	}
+
+	w := performRequest("POST", "/categories/migrate?dryRun=true", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.True(t, response["data"].DryRun)
+	assert.Equal(t, map[string]int{chair.ID: 2, seating.ID: 1}, response["data"].Mapped)
+	assert.Equal(t, map[string]int{"decoration": 1}, response["data"].Unmatched)
+	assert.Empty(t, mustMembersOrNil(t, "category:"+chair.ID+":objects"), "a dry run writes nothing")
+
+	w = performRequest("POST", "/categories/migrate", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, mustMembersOrNil(t, "category:"+chair.ID+":objects"), 2)
+
+	// Running it again only looks at objects still uncategorized
+	w = performRequest("POST", "/categories/migrate", nil, "")
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Empty(t, response["data"].Mapped)
+	assert.Equal(t, map[string]int{"decoration": 1}, response["data"].Unmatched)
+}
+
+func mustMembersOrNil(t *testing.T, key string) []string {
+	if !mr.Exists(key) {
+		return nil
+	}
+	return mustMembers(t, key)
+}
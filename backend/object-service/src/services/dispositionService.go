@@ -0,0 +1,263 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"hexagone/object-service/src/models"
+	"hexagone/shared/logging"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// requireHomeExecutor checks that the request comes from the executor of a
+// home and returns the home's settings, writing the error response and
+// returning false otherwise
+func requireHomeExecutor(c *gin.Context, homeID string) (models.HomeSettings, bool) {
+	userID := c.GetHeader("X-User-ID")
+	if userID == "" {
+		logging.RequestLog(c).WithField("homeID", homeID).Warn("No user ID found in header")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return models.HomeSettings{}, false
+	}
+
+	settings, err := loadHomeSettings(requestCtx(c), homeID)
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"homeID": homeID,
+			"error":  err.Error(),
+		}).Error("Failed to load home settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load home settings"})
+		return settings, false
+	}
+
+	if homeID == "" || settings.ExecutorID != userID {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"homeID": homeID,
+			"userID": userID,
+		}).Warn("Non-executor attempted to manage a home")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the executor of this home can do this"})
+		return settings, false
+	}
+	return settings, true
+}
+
+type SetDispositionInput struct {
+	Disposition models.Disposition `json:"disposition" binding:"required"`
+}
+
+// SetDisposition decides what happens to a single object if nobody claims it
+func SetDisposition(c *gin.Context) {
+	objectID := c.Param("id")
+	var input SetDispositionInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to bind input for disposition")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !input.Disposition.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown disposition", "allowed": models.Dispositions})
+		return
+	}
+
+	object, err := loadObject(requestCtx(c), objectID)
+	if err == redis.Nil {
+		logging.RequestLog(c).WithField("objectID", objectID).Warn("Object not found")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+		return
+	}
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to load object")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process object data"})
+		return
+	}
+
+	if _, ok := requireHomeExecutor(c, object.HomeID); !ok {
+		return
+	}
+
+	// The object is read again in the transaction, in case it was claimed
+	// or moved to another home since the executor was checked
+	_, object, err = updateObject(requestCtx(c), objectID, func(_ *redis.Tx, _ bookingChanges, current *models.Object) error {
+		if current.HomeID != object.HomeID {
+			return errRefused{http.StatusConflict, "Object was moved to another home, try again"}
+		}
+		if current.IsReserved && input.Disposition.LeavesHouse() {
+			return errRefused{http.StatusConflict, "Reserved objects go to the family member who claimed them"}
+		}
+		current.Disposition = input.Disposition
+		return nil
+	})
+	if err != nil {
+		respondUpdateError(c, objectID, err, "Failed to update object in database")
+		return
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"objectID":    objectID,
+		"disposition": object.Disposition,
+	}).Info("Object disposition updated")
+
+	c.JSON(http.StatusOK, gin.H{"data": object})
+}
+
+type BulkDispositionInput struct {
+	Disposition models.Disposition `json:"disposition" binding:"required"`
+	Overwrite   bool               `json:"overwrite"` // Also replace dispositions already decided
+}
+
+// BulkAssignDisposition gives every object of a home that is still unreserved
+// once the claiming deadline set in the home settings has passed the same
+// disposition
+func BulkAssignDisposition(c *gin.Context) {
+	homeID := c.Param("id")
+	var input BulkDispositionInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"homeID": homeID,
+			"error":  err.Error(),
+		}).Error("Failed to bind input for bulk disposition")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !input.Disposition.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown disposition", "allowed": models.Dispositions})
+		return
+	}
+
+	settings, ok := requireHomeExecutor(c, homeID)
+	if !ok {
+		return
+	}
+
+	if settings.ClaimDeadline == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "No claiming deadline is set for this home"})
+		return
+	}
+	if time.Now().Before(*settings.ClaimDeadline) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The claiming deadline has not passed yet"})
+		return
+	}
+
+	objects, err := fetchObjects(requestCtx(c), func(o models.Object) bool {
+		if o.HomeID != homeID || o.IsReserved {
+			return false
+		}
+		return input.Overwrite || o.CurrentDisposition() == models.DispositionUndecided
+	})
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to fetch keys from DragonflyDB")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch objects"})
+		return
+	}
+
+	// Each object is checked again as it is written, and left alone when it
+	// was claimed, moved or decided in the meantime
+	updated := []models.Object{}
+	conflicts := []BulkItemResult{}
+	for _, candidate := range objects {
+		_, object, err := updateObject(requestCtx(c), candidate.ID, func(_ *redis.Tx, _ bookingChanges, object *models.Object) error {
+			if object.HomeID != homeID {
+				return errRefused{http.StatusConflict, "Object was moved to another home"}
+			}
+			if object.IsReserved {
+				return errRefused{http.StatusConflict, "Object was reserved in the meantime"}
+			}
+			if !input.Overwrite && object.CurrentDisposition() != models.DispositionUndecided {
+				return errRefused{http.StatusConflict, "Object disposition was decided in the meantime"}
+			}
+			object.Disposition = input.Disposition
+			return nil
+		})
+
+		var refused errRefused
+		switch {
+		case err == nil:
+			updated = append(updated, object)
+		case err == redis.Nil:
+			// Deleted since it was listed
+		case errors.As(err, &refused):
+			conflicts = append(conflicts, BulkItemResult{ID: candidate.ID, Status: BulkStatusConflict, Error: refused.message})
+		case err == redis.TxFailedErr:
+			conflicts = append(conflicts, BulkItemResult{ID: candidate.ID, Status: BulkStatusConflict, Error: "The object kept being modified"})
+		default:
+			logging.RequestLog(c).WithFields(logrus.Fields{
+				"objectID": candidate.ID,
+				"error":    err.Error(),
+			}).Error("Failed to update object disposition")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update object disposition", "data": updated, "count": len(updated)})
+			return
+		}
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"homeID":      homeID,
+		"disposition": input.Disposition,
+		"count":       len(updated),
+		"conflicts":   len(conflicts),
+	}).Info("Bulk disposition assigned")
+
+	c.JSON(http.StatusOK, gin.H{"data": updated, "count": len(updated), "conflicts": conflicts})
+}
+
+// DispositionReport lists the objects of a home grouped by disposition, with
+// counts per outcome, as JSON or CSV (?format=csv)
+func DispositionReport(c *gin.Context) {
+	homeID := c.Param("id")
+
+	objects, err := fetchObjects(requestCtx(c), func(o models.Object) bool { return o.HomeID == homeID })
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to fetch keys from DragonflyDB")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch objects"})
+		return
+	}
+
+	counts := map[models.Disposition]int{}
+	items := map[models.Disposition][]models.Object{}
+	for _, disposition := range models.Dispositions {
+		counts[disposition] = 0
+		items[disposition] = []models.Object{}
+	}
+	for _, object := range objects {
+		disposition := object.CurrentDisposition()
+		counts[disposition]++
+		items[disposition] = append(items[disposition], object)
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"homeID": homeID,
+		"count":  len(objects),
+	}).Info("Disposition report generated")
+
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, gin.H{"data": gin.H{"homeId": homeID, "counts": counts, "items": items}})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=dispositions-%s.csv", homeID))
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"disposition", "object_id", "name", "type", "room_id", "reserved_by"})
+	for _, disposition := range models.Dispositions {
+		for _, object := range items[disposition] {
+			writer.Write([]string{string(disposition), object.ID, object.Name, object.Type, object.RoomID, object.ReservedBy})
+		}
+	}
+	writer.Flush()
+}
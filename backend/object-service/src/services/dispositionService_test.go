@@ -0,0 +1,108 @@
+package services_test
+
+import (
+	"encoding/json"
+	"hexagone/object-service/src/models"
+	"hexagone/object-service/src/services"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetDisposition(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	performRequest("PUT", "/homes/1/settings", map[string]interface{}{"executorId": "executor"}, "")
+	vase := createTestObject(t, map[string]interface{}{
+		"name": "Vase", "type": "decoration", "room_id": "room1",
+	})
+	chair := reserveTestObject(t, "Chaise", "1", "heir1")
+
+	tests := []struct {
+		name         string
+		objectID     string
+		disposition  string
+		userID       string
+		expectedCode int
+	}{
+		{"Unknown Disposition", vase.ID, "burn", "executor", http.StatusBadRequest},
+		{"Not Executor", vase.ID, "donate", "heir1", http.StatusForbidden},
+		{"Reserved Object", chair.ID, "sell", "executor", http.StatusConflict},
+		{"Unknown Object", "nonexistent", "sell", "executor", http.StatusNotFound},
+		{"Valid Disposition", vase.ID, "donate", "executor", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := performRequest("PATCH", "/objects/"+tt.objectID+"/disposition", map[string]interface{}{"disposition": tt.disposition}, tt.userID)
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+
+	// Objects set aside for donation can no longer be claimed
+	w := performRequest("PATCH", "/objects/"+vase.ID+"/reserve", nil, "heir2")
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestBulkDispositionAndReport(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	performRequest("PUT", "/homes/1/settings", map[string]interface{}{"executorId": "executor"}, "")
+	reserveTestObject(t, "Chaise", "1", "heir1")
+	lamp := createTestObject(t, map[string]interface{}{"name": "Lampe", "type": "lighting", "room_id": "room1"})
+	createTestObject(t, map[string]interface{}{"name": "Tapis", "type": "decoration", "room_id": "room1"})
+	createTestObject(t, map[string]interface{}{"name": "Poste", "type": "electronics", "room_id": "room1"})
+	createTestObject(t, map[string]interface{}{"name": "Autre", "type": "decoration", "room_id": "room9"})
+
+	performRequest("PATCH", "/objects/"+lamp.ID+"/disposition", map[string]interface{}{"disposition": "sell"}, "executor")
+
+	w := performRequest("POST", "/homes/1/dispositions", map[string]interface{}{"disposition": "donate"}, "executor")
+	assert.Equal(t, http.StatusConflict, w.Code, "the home has no deadline yet")
+
+	performRequest("PUT", "/homes/1/settings", map[string]interface{}{
+		"executorId": "executor", "claimDeadline": "2999-01-01T00:00:00Z",
+	}, "")
+	w = performRequest("POST", "/homes/1/dispositions", map[string]interface{}{
+		"disposition": "donate", "deadline": "2026-01-01T00:00:00Z",
+	}, "executor")
+	assert.Equal(t, http.StatusConflict, w.Code, "the stored deadline must have passed, whatever the request says")
+
+	performRequest("PUT", "/homes/1/settings", map[string]interface{}{
+		"executorId": "executor", "claimDeadline": "2026-01-01T00:00:00Z",
+	}, "")
+	w = performRequest("POST", "/homes/1/dispositions", map[string]interface{}{"disposition": "donate"}, "executor")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var bulk struct {
+		Count     int                       `json:"count"`
+		Conflicts []services.BulkItemResult `json:"conflicts"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &bulk)
+	assert.Equal(t, 2, bulk.Count, "only unreserved, undecided objects of the home are assigned")
+	assert.Empty(t, bulk.Conflicts)
+
+	w = performRequest("GET", "/homes/1/dispositions", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var report struct {
+		Data struct {
+			Counts map[models.Disposition]int             `json:"counts"`
+			Items  map[models.Disposition][]models.Object `json:"items"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &report)
+	assert.Equal(t, 2, report.Data.Counts[models.DispositionDonate])
+	assert.Equal(t, 1, report.Data.Counts[models.DispositionSell])
+	assert.Equal(t, 1, report.Data.Counts[models.DispositionUndecided], "the reserved object keeps its disposition")
+	assert.Equal(t, 0, report.Data.Counts[models.DispositionDiscard])
+	assert.Len(t, report.Data.Items[models.DispositionSell], 1)
+
+	w = performRequest("GET", "/homes/1/dispositions?format=csv", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "donate,")
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"hexagone/object-service/src/database"
+	"hexagone/shared/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz handles GET /healthz: the process is up and serving requests
+var Healthz = health.Healthz("object-service")
+
+// Readyz handles GET /readyz: checks DragonflyDB and the user service the
+// admin routes depend on, at userServiceURL, and answers 503 when any of them
+// is down
+func Readyz(userServiceURL string) gin.HandlerFunc {
+	return health.Readyz("object-service", map[string]health.Check{
+		"dragonfly":    pingDragonfly,
+		"user-service": func(ctx context.Context) error { return health.PingService(ctx, userServiceURL) },
+	})
+}
+
+// pingDragonfly checks that DragonflyDB answers
+func pingDragonfly(ctx context.Context) error {
+	if database.RDB == nil {
+		return errors.New("DragonflyDB is not connected")
+	}
+	return database.RDB.Ping(ctx).Err()
+}
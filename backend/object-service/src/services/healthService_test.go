@@ -0,0 +1,90 @@
+package services_test
+
+import (
+	"encoding/json"
+	"hexagone/object-service/src/services"
+	"hexagone/shared/health"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type readiness struct {
+	Status string                            `json:"status"`
+	Checks map[string]health.DependencyCheck `json:"checks"`
+}
+
+func probe(t *testing.T, r *gin.Engine, path string) (int, readiness) {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+	var body readiness
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func TestHealth(t *testing.T) {
+	require.NoError(t, setupTestServer())
+	defer cleanupTest()
+
+	userStatus := http.StatusOK
+	userService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/healthz", r.URL.Path)
+		w.WriteHeader(userStatus)
+	}))
+	defer userService.Close()
+
+	r := gin.New()
+	r.GET("/healthz", services.Healthz)
+	r.GET("/readyz", services.Readyz(userService.URL))
+
+	code, body := probe(t, r, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.OK, body.Status)
+
+	t.Run("Ready", func(t *testing.T) {
+		code, body := probe(t, r, "/readyz")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, health.OK, body.Status)
+		assert.Equal(t, health.OK, body.Checks["dragonfly"].Status)
+		assert.Equal(t, health.OK, body.Checks["user-service"].Status)
+	})
+
+	t.Run("User service down", func(t *testing.T) {
+		userStatus = http.StatusInternalServerError
+		defer func() { userStatus = http.StatusOK }()
+
+		code, body := probe(t, r, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, health.Down, body.Status)
+		assert.Equal(t, health.OK, body.Checks["dragonfly"].Status)
+		assert.Equal(t, health.Down, body.Checks["user-service"].Status)
+		assert.Equal(t, "GET /healthz: 500 Internal Server Error", body.Checks["user-service"].Error)
+	})
+
+	t.Run("User service not configured", func(t *testing.T) {
+		unconfigured := gin.New()
+		unconfigured.GET("/readyz", services.Readyz(""))
+
+		code, body := probe(t, unconfigured, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "service address is not configured", body.Checks["user-service"].Error)
+	})
+
+	t.Run("Dragonfly down", func(t *testing.T) {
+		mr.Close()
+
+		code, body := probe(t, r, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, health.Down, body.Checks["dragonfly"].Status)
+		assert.NotEmpty(t, body.Checks["dragonfly"].Error)
+
+		// Liveness does not depend on DragonflyDB
+		code, _ = probe(t, r, "/healthz")
+		assert.Equal(t, http.StatusOK, code)
+	})
+}
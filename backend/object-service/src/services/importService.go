@@ -0,0 +1,448 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hexagone/object-service/src/database"
+	"hexagone/object-service/src/models"
+	"hexagone/object-service/src/search"
+	"hexagone/shared/logging"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxImportSize = 5 << 20
+	maxImportRows = 5000
+)
+
+// importColumns maps accepted column names, folded, to the field they fill.
+// French names are accepted since most spreadsheets we get are in French.
+var importColumns = map[string]string{
+	"room": "room", "room_name": "room", "piece": "room",
+	"name": "name", "object": "name", "object_name": "name", "nom": "name", "objet": "name",
+	"type": "type", "category": "type", "categorie": "type",
+	"description": "description", "notes": "description",
+	"value": "value", "estimated_value": "value", "valeur": "value", "prix": "value",
+}
+
+// importsKey maps the fingerprint of every imported row of a home to the
+// object created from it, so that re-running an import creates nothing twice
+func importsKey(homeID string) string {
+	return "home:" + homeID + ":imports"
+}
+
+// ImportRow is one object to import
+type ImportRow struct {
+	Line        int     `json:"line"` // Line in the CSV file, or position in the JSON array
+	Room        string  `json:"room"`
+	Name        string  `json:"name"`
+	Type        string  `json:"type"`
+	Description string  `json:"description,omitempty"`
+	Value       float64 `json:"value,omitempty"`
+}
+
+type ImportError struct {
+	Line  int    `json:"line"`
+	Field string `json:"field,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportReport tells what an import did or, for a dry run, would do
+type ImportReport struct {
+	DryRun       bool          `json:"dryRun"`
+	Rows         int           `json:"rows"`
+	Created      int           `json:"created"`
+	Skipped      int           `json:"skipped"` // Rows already imported by an earlier run
+	RoomsCreated []string      `json:"roomsCreated"`
+	Errors       []ImportError `json:"errors"`
+	Warnings     []ImportError `json:"warnings"` // Rows imported without a category
+}
+
+// readImportRecords reads the uploaded rows as column → value records, from
+// CSV (comma or semicolon separated) or from a JSON array of objects. It also
+// returns the line number of the first record: 2 in CSV files, after the
+// header, and 1 in JSON arrays.
+func readImportRecords(c *gin.Context) ([]map[string]string, int, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	var body io.Reader = c.Request.Body
+	isJSON := strings.HasPrefix(c.ContentType(), "application/json")
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, 0, errors.New("no file in the \"file\" form field")
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, 0, err
+		}
+		defer file.Close()
+		body = file
+		isJSON = strings.EqualFold(filepath.Ext(header.Filename), ".json")
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if isJSON {
+		records, err := readJSONRecords(data)
+		return records, 1, err
+	}
+	records, err := readCSVRecords(string(data))
+	return records, 2, err
+}
+
+func readJSONRecords(data []byte) ([]map[string]string, error) {
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("invalid JSON: expected an array of objects: %v", err)
+	}
+
+	records := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		records[i] = map[string]string{}
+		for column, value := range row {
+			switch v := value.(type) {
+			case string:
+				records[i][column] = v
+			case float64:
+				records[i][column] = strconv.FormatFloat(v, 'f', -1, 64)
+			case nil:
+			default:
+				records[i][column] = fmt.Sprint(v)
+			}
+		}
+	}
+	return records, nil
+}
+
+func readCSVRecords(data string) ([]map[string]string, error) {
+	data = strings.TrimPrefix(data, "\ufeff") // Excel adds a byte order mark
+
+	reader := csv.NewReader(strings.NewReader(data))
+	reader.FieldsPerRecord = -1
+	header, _, _ := strings.Cut(data, "\n")
+	if strings.Count(header, ";") > strings.Count(header, ",") {
+		reader.Comma = ';'
+	}
+
+	lines, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("the file is empty")
+	}
+
+	records := make([]map[string]string, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		record := map[string]string{}
+		for i, value := range line {
+			if i < len(lines[0]) {
+				record[lines[0][i]] = value
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// parseImportValue reads an amount such as "1200", "1 200,50" or "80 €"
+func parseImportValue(raw string) (float64, error) {
+	raw = strings.NewReplacer(" ", "", "\u00a0", "", "\u202f", "", "€", "").Replace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseFloat(strings.Replace(raw, ",", ".", 1), 64)
+	if err != nil || value < 0 {
+		return 0, errors.New("must be a positive amount")
+	}
+	return value, nil
+}
+
+// parseImportRows validates records into rows, numbering them from firstLine
+func parseImportRows(records []map[string]string, firstLine int) ([]ImportRow, []ImportError) {
+	rows := []ImportRow{}
+	rowErrors := []ImportError{}
+
+	for i, record := range records {
+		line := firstLine + i
+		fields := map[string]string{}
+		for column, value := range record {
+			column = strings.ReplaceAll(models.NormalizeLabel(search.Fold(column)), " ", "_")
+			if field, ok := importColumns[column]; ok {
+				fields[field] = strings.TrimSpace(value)
+			}
+		}
+		if fields["room"] == "" && fields["name"] == "" && fields["type"] == "" && fields["description"] == "" && fields["value"] == "" {
+			continue // Blank spreadsheet line
+		}
+
+		row := ImportRow{
+			Line:        line,
+			Room:        fields["room"],
+			Name:        fields["name"],
+			Type:        fields["type"],
+			Description: fields["description"],
+		}
+		for _, field := range []string{"room", "name", "type"} {
+			if fields[field] == "" {
+				rowErrors = append(rowErrors, ImportError{Line: line, Field: field, Error: "is required"})
+			}
+		}
+		if len([]rune(row.Description)) > 2000 {
+			rowErrors = append(rowErrors, ImportError{Line: line, Field: "description", Error: "is longer than 2000 characters"})
+		}
+		value, err := parseImportValue(fields["value"])
+		if err != nil {
+			rowErrors = append(rowErrors, ImportError{Line: line, Field: "value", Error: err.Error()})
+		}
+		row.Value = value
+
+		rows = append(rows, row)
+	}
+	return rows, rowErrors
+}
+
+// importFingerprints identifies each row by its content. Identical rows,
+// such as a set of matching chairs, are told apart by their occurrence.
+func importFingerprints(rows []ImportRow) []string {
+	fingerprints := make([]string, len(rows))
+	occurrences := map[string]int{}
+	for i, row := range rows {
+		sum := sha256.Sum256([]byte(strings.Join([]string{
+			roomLabel(row.Room),
+			models.NormalizeLabel(search.Fold(row.Name)),
+			models.NormalizeLabel(search.Fold(row.Type)),
+			row.Description,
+			strconv.FormatFloat(row.Value, 'f', -1, 64),
+		}, "\x00")))
+		key := hex.EncodeToString(sum[:])
+		occurrences[key]++
+		fingerprints[i] = fmt.Sprintf("%s:%d", key, occurrences[key])
+	}
+	return fingerprints
+}
+
+// roomLabel compares room names regardless of case, accents and spacing
+func roomLabel(name string) string {
+	return models.NormalizeLabel(search.Fold(name))
+}
+
+// importObject stores an object imported from a row along with the row's
+// fingerprint, in one transaction watching the home's imports. It stores
+// nothing and returns false when the row's object was stored meanwhile, by a
+// concurrent run of the same import.
+func importObject(ctx context.Context, homeID, fingerprint string, object models.Object) (bool, error) {
+	stored := false
+	transaction := func(tx *redis.Tx) error {
+		objectID, err := tx.HGet(ctx, importsKey(homeID), fingerprint).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if objectID != "" {
+			exists, err := tx.Exists(ctx, objectID).Result()
+			if err != nil || exists == 1 {
+				return err
+			}
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, importsKey(homeID), fingerprint, object.ID)
+			return queueObjectInsert(ctx, pipe, object)
+		})
+		stored = err == nil
+		return err
+	}
+
+	var err error
+	for attempt := 0; attempt < maxObjectAttempts; attempt++ {
+		if err = database.RDB.Watch(ctx, transaction, importsKey(homeID)); err != redis.TxFailedErr {
+			break
+		}
+	}
+	if stored {
+		indexObjectForSearch(ctx, object)
+	}
+	return stored, err
+}
+
+// ImportObjects creates the objects of a spreadsheet in a home, creating
+// missing rooms in the room service. With ?dryRun=true it only validates
+// the rows and reports what would be created.
+func ImportObjects(c *gin.Context) {
+	homeID := c.Param("id")
+	dryRun := c.Query("dryRun") == "true"
+
+	numericHomeID, err := strconv.ParseUint(homeID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid home id"})
+		return
+	}
+
+	_, err = fetchHome(requestCtx(c), homeID)
+	if err == errHomeNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Home not found"})
+		return
+	}
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"homeID": homeID,
+			"error":  err.Error(),
+		}).Error("Failed to check the home")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to check the home"})
+		return
+	}
+
+	records, firstLine, err := readImportRecords(c)
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"homeID": homeID,
+			"error":  err.Error(),
+		}).Warn("Failed to read import file")
+		c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if len(records) > maxImportRows {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("At most %d rows per import", maxImportRows)})
+		return
+	}
+
+	categories, err := loadCategories(requestCtx(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load categories"})
+		return
+	}
+
+	rows, rowErrors := parseImportRows(records, firstLine)
+	categoryIDs := make([]string, len(rows))
+	warnings := []ImportError{}
+	for i, row := range rows {
+		categoryIDs[i] = categoryForType(categories, row.Type)
+		if warning := unmatchedTypeWarning(categories, row.Type, categoryIDs[i]); warning != "" {
+			warnings = append(warnings, ImportError{Line: row.Line, Field: "type", Error: warning})
+		}
+	}
+	report := ImportReport{DryRun: dryRun, Rows: len(rows), RoomsCreated: []string{}, Errors: rowErrors, Warnings: warnings}
+
+	if len(rowErrors) > 0 {
+		status := http.StatusUnprocessableEntity
+		if dryRun {
+			status = http.StatusOK
+		}
+		c.JSON(status, gin.H{"data": report, "error": "Some rows are invalid; nothing was imported"})
+		return
+	}
+
+	rooms, err := fetchRooms(requestCtx(c), homeID)
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"homeID": homeID,
+			"error":  err.Error(),
+		}).Error("Failed to fetch rooms")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch rooms"})
+		return
+	}
+	roomIDs := map[string]string{}
+	for _, room := range rooms {
+		roomIDs[roomLabel(room.Name)] = strconv.FormatUint(uint64(room.ID), 10)
+	}
+
+	// Leave out rows imported by an earlier run whose object still exists
+	fingerprints := importFingerprints(rows)
+	pending := []int{}
+	for i, fingerprint := range fingerprints {
+		objectID, err := database.RDB.HGet(requestCtx(c), importsKey(homeID), fingerprint).Result()
+		if err != nil && err != redis.Nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read previous imports"})
+			return
+		}
+		if objectID != "" && database.RDB.Exists(requestCtx(c), objectID).Val() == 1 {
+			report.Skipped++
+			continue
+		}
+		pending = append(pending, i)
+
+		label := roomLabel(rows[i].Room)
+		if _, ok := roomIDs[label]; !ok {
+			roomIDs[label] = "" // Created below
+			report.RoomsCreated = append(report.RoomsCreated, rows[i].Room)
+		}
+	}
+
+	if dryRun {
+		report.Created = len(pending)
+		c.JSON(http.StatusOK, gin.H{"data": report})
+		return
+	}
+
+	for _, name := range report.RoomsCreated {
+		room, err := createRoom(requestCtx(c), uint(numericHomeID), name)
+		if err != nil {
+			logging.RequestLog(c).WithFields(logrus.Fields{
+				"homeID": homeID,
+				"room":   name,
+				"error":  err.Error(),
+			}).Error("Failed to create room")
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to create room " + name})
+			return
+		}
+		roomIDs[roomLabel(name)] = strconv.FormatUint(uint64(room.ID), 10)
+	}
+
+	for _, i := range pending {
+		row := rows[i]
+		object := models.Object{
+			ID:          uuid.New().String(),
+			Name:        row.Name,
+			Type:        row.Type,
+			RoomID:      roomIDs[roomLabel(row.Room)],
+			HomeID:      homeID,
+			Description: row.Description,
+			Value:       row.Value,
+			Disposition: models.DispositionUndecided,
+			CategoryID:  categoryIDs[i],
+		}
+
+		stored, err := importObject(requestCtx(c), homeID, fingerprints[i], object)
+		if err != nil {
+			logging.RequestLog(c).WithFields(logrus.Fields{
+				"homeID": homeID,
+				"line":   row.Line,
+				"error":  err.Error(),
+			}).Error("Failed to store imported object")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store objects", "data": report})
+			return
+		}
+		if !stored {
+			report.Skipped++
+			continue
+		}
+		report.Created++
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"homeID":       homeID,
+		"created":      report.Created,
+		"skipped":      report.Skipped,
+		"roomsCreated": len(report.RoomsCreated),
+	}).Info("Objects imported")
+	objectsCreated.WithLabelValues("import").Add(float64(report.Created))
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
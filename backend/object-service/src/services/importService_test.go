@@ -0,0 +1,224 @@
+package services_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hexagone/object-service/src/models"
+	"hexagone/object-service/src/services"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRoomService stands in for the room service, starting with a kitchen in
+// home 1; rooms can be listed, created and read by ID
+func fakeRoomService(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	rooms := []map[string]interface{}{{"id": 1, "name": "Cuisine", "home_id": 1}}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Method == "POST" {
+			var input map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&input)
+			room := map[string]interface{}{"id": len(rooms) + 1, "name": input["name"], "home_id": input["home_id"]}
+			rooms = append(rooms, room)
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": room})
+			return
+		}
+		if id := strings.TrimPrefix(r.URL.Path, "/rooms/"); id != r.URL.Path {
+			for _, room := range rooms {
+				if fmt.Sprint(room["id"]) == id {
+					json.NewEncoder(w).Encode(map[string]interface{}{"data": room})
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": rooms})
+	}))
+	services.RoomServiceURL = server.URL
+	return server
+}
+
+// fakeHomeService stands in for the home service, which lists homes 1 and 2
+func fakeHomeService(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": [{"id": 1, "name": "Maison de famille"}, {"id": 2, "name": "Chalet"}]}`))
+	}))
+	services.HomeServiceURL = server.URL
+	return server
+}
+
+type importResponse struct {
+	Data struct {
+		DryRun       bool                   `json:"dryRun"`
+		Rows         int                    `json:"rows"`
+		Created      int                    `json:"created"`
+		Skipped      int                    `json:"skipped"`
+		RoomsCreated []string               `json:"roomsCreated"`
+		Errors       []services.ImportError `json:"errors"`
+		Warnings     []services.ImportError `json:"warnings"`
+	} `json:"data"`
+}
+
+func importCSV(url, csv string) (*httptest.ResponseRecorder, importResponse) {
+	req := httptest.NewRequest("POST", url, strings.NewReader(csv))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response importResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response
+}
+
+func TestImportObjectsFromCSV(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+	defer fakeHomeService(t).Close()
+	defer fakeRoomService(t).Close()
+
+	// French spreadsheet export: semicolons, decimal commas, accented headers
+	csv := "Pièce;Nom;Type;Description;Valeur\n" +
+		"cuisine;Théière;vaisselle;Porcelaine de Limoges;45,50\n" +
+		"Salon;Chaise;chair;;20\n" +
+		"Salon;Chaise;chair;;20\n" +
+		";;;;\n" +
+		"Grenier;Malle;rangement;;\n"
+
+	w, response := importCSV("/homes/1/import?dryRun=true", csv)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, response.Data.DryRun)
+	assert.Equal(t, 4, response.Data.Rows)
+	assert.Equal(t, 4, response.Data.Created)
+	assert.Equal(t, []string{"Salon", "Grenier"}, response.Data.RoomsCreated)
+	w = performRequest("GET", "/objects", nil, "")
+	assert.JSONEq(t, `{"data": []}`, w.Body.String(), "a dry run writes nothing")
+
+	w, response = importCSV("/homes/1/import", csv)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 4, response.Data.Created)
+
+	w = performRequest("GET", "/objects/room?room_id=1", nil, "")
+	var objects map[string][]models.Object
+	json.Unmarshal(w.Body.Bytes(), &objects)
+	if assert.Len(t, objects["data"], 1, "accents and case are ignored when matching rooms") {
+		assert.Equal(t, "Théière", objects["data"][0].Name)
+		assert.Equal(t, 45.5, objects["data"][0].Value)
+		assert.Equal(t, "1", objects["data"][0].HomeID)
+	}
+
+	// Re-running the same file creates nothing, even for the two identical chairs
+	w, response = importCSV("/homes/1/import", csv)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, response.Data.Created)
+	assert.Equal(t, 4, response.Data.Skipped)
+	assert.Empty(t, response.Data.RoomsCreated)
+
+	// A third chair added to the spreadsheet is imported on its own
+	w, response = importCSV("/homes/1/import", csv+"Salon;Chaise;chair;;20\n")
+	assert.Equal(t, 1, response.Data.Created)
+	assert.Equal(t, 4, response.Data.Skipped)
+}
+
+func TestImportObjectsValidation(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+	defer fakeHomeService(t).Close()
+	defer fakeRoomService(t).Close()
+
+	csv := "room,name,type,value\n" +
+		"Salon,Fauteuil,chair,100\n" +
+		"Salon,,chair,abc\n"
+
+	w, response := importCSV("/homes/1/import?dryRun=true", csv)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Len(t, response.Data.Errors, 2) {
+		assert.Equal(t, 3, response.Data.Errors[0].Line)
+		assert.Equal(t, "name", response.Data.Errors[0].Field)
+		assert.Equal(t, "value", response.Data.Errors[1].Field)
+	}
+
+	w, _ = importCSV("/homes/1/import", csv)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = performRequest("GET", "/objects", nil, "")
+	assert.JSONEq(t, `{"data": []}`, w.Body.String(), "nothing is imported when a row is invalid")
+
+	w, _ = importCSV("/homes/abc/import", csv)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = importCSV("/homes/9/import", csv)
+	assert.Equal(t, http.StatusNotFound, w.Code, "the home must exist in the home service")
+
+	// JSON rows, uploaded as a file
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", "inventaire.json")
+	part.Write([]byte(`[{"room": "Salon", "name": "Fauteuil", "type": "chair", "value": 100}]`))
+	writer.Close()
+	req := httptest.NewRequest("POST", "/homes/1/import", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 1, response.Data.Created)
+
+	// Once a taxonomy exists, rows whose type designates none of its
+	// categories are imported uncategorized, with a warning
+	createTestCategory(t, map[string]interface{}{"name": "Chair"})
+	w, response = importCSV("/homes/1/import?dryRun=true", "room,name,type\nSalon,Chaise,chair\nSalon,Table,table\n")
+	assert.Empty(t, response.Data.Errors)
+	if assert.Len(t, response.Data.Warnings, 1) {
+		assert.Equal(t, 3, response.Data.Warnings[0].Line)
+		assert.Equal(t, "type", response.Data.Warnings[0].Field)
+	}
+}
+
+func TestConcurrentImportsCreateOnce(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+	defer fakeHomeService(t).Close()
+	defer fakeRoomService(t).Close()
+
+	csv := "room,name,type\nCuisine,Théière,vaisselle\nCuisine,Bol,vaisselle\nCuisine,Bol,vaisselle\n"
+
+	var wg sync.WaitGroup
+	created := make(chan int, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w, response := importCSV("/homes/1/import", csv)
+			assert.Equal(t, http.StatusOK, w.Code)
+			created <- response.Data.Created
+		}()
+	}
+	wg.Wait()
+	close(created)
+
+	total := 0
+	for n := range created {
+		total += n
+	}
+	assert.Equal(t, 3, total, "each row is imported by one run only")
+	w := performRequest("GET", "/objects", nil, "")
+	var objects map[string][]models.Object
+	json.Unmarshal(w.Body.Bytes(), &objects)
+	assert.Len(t, objects["data"], 3)
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"hexagone/object-service/src/database"
+	"hexagone/object-service/src/models"
+	"hexagone/shared/logging"
+	"io"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Short codes leave out 0/O and 1/I/L so a code read off a worn sticker can
+// be typed back without guessing
+const (
+	labelAlphabet   = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+	labelCodeLength = 8
+)
+
+// Label sheet layout, in millimetres: A4 with 3 x 7 labels of 70 x 42.3,
+// the common sticker sheet format
+const (
+	labelColumns = 3
+	labelRows    = 7
+	labelWidth   = 70.0
+	labelHeight  = 42.3
+	labelPadding = 3.0
+)
+
+// labelKey maps a short code to its object ID
+func labelKey(code string) string {
+	return "label:" + code
+}
+
+// objectLabelKey holds the short code of an object
+func objectLabelKey(objectID string) string {
+	return "object:" + objectID + ":label"
+}
+
+// LabelBaseURL is the frontend address the QR codes point to, set from the
+// configuration at startup
+var LabelBaseURL string
+
+// labelLink is the deep link encoded in the QR code of an object
+func labelLink(code string) string {
+	return LabelBaseURL + "/o/" + code
+}
+
+// formatLabelCode splits a code in two groups for reading, e.g. "K7QM-4XPD"
+func formatLabelCode(code string) string {
+	return code[:labelCodeLength/2] + "-" + code[labelCodeLength/2:]
+}
+
+// normalizeLabelCode accepts a code as printed or as typed by hand:
+// lowercase, with or without the dash and spaces
+func normalizeLabelCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func newLabelCode() (string, error) {
+	code := make([]byte, labelCodeLength)
+	max := big.NewInt(int64(len(labelAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = labelAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// ensureLabelCode returns the short code of an object, assigning one the
+// first time a label is printed for it. Codes never change afterwards, so
+// a sticker stays valid however many times the sheet is printed.
+func ensureLabelCode(ctx context.Context, objectID string) (string, error) {
+	code, err := database.RDB.Get(ctx, objectLabelKey(objectID)).Result()
+	if err == nil {
+		return code, nil
+	}
+	if err != redis.Nil {
+		return "", err
+	}
+
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := newLabelCode()
+		if err != nil {
+			return "", err
+		}
+		claimed, err := database.RDB.SetNX(ctx, labelKey(code), objectID, 0).Result()
+		if err != nil {
+			return "", err
+		}
+		if !claimed {
+			continue // Code already taken by another object
+		}
+
+		assigned, err := database.RDB.SetNX(ctx, objectLabelKey(objectID), code, 0).Result()
+		if err != nil {
+			return "", err
+		}
+		if !assigned {
+			// Another request labelled the object first: keep its code
+			database.RDB.Del(ctx, labelKey(code))
+			return database.RDB.Get(ctx, objectLabelKey(objectID)).Result()
+		}
+		return code, nil
+	}
+	return "", fmt.Errorf("no free label code for object %s", objectID)
+}
+
+// unindexObjectLabel frees the short code of a deleted object
+func unindexObjectLabel(ctx context.Context, pipe redis.Pipeliner, objectID string) {
+	if code, err := database.RDB.Get(ctx, objectLabelKey(objectID)).Result(); err == nil {
+		pipe.Del(ctx, labelKey(code))
+	}
+	pipe.Del(ctx, objectLabelKey(objectID))
+}
+
+// PrintLabels handles GET /labels?room_id= or ?home_id=: a PDF sheet of QR
+// labels for every object of a room or home, or the labels as JSON with
+// ?format=json
+func PrintLabels(c *gin.Context) {
+	roomID := c.Query("room_id")
+	homeID := c.Query("home_id")
+	if (roomID == "") == (homeID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of room_id or home_id is required"})
+		return
+	}
+	format := c.DefaultQuery("format", "pdf")
+	if format != "pdf" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be pdf or json"})
+		return
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"roomID": roomID,
+		"homeID": homeID,
+	}).Info("Printing object labels")
+
+	// Every object carries the home of its room, set when it is created or
+	// moved, so a home's objects are found without asking the room service
+	keep := func(obj models.Object) bool { return obj.RoomID == roomID }
+	if homeID != "" {
+		keep = func(obj models.Object) bool { return obj.HomeID == homeID }
+	}
+
+	objects, err := fetchObjects(requestCtx(c), keep)
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to fetch objects")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch objects"})
+		return
+	}
+	if len(objects) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No objects to label"})
+		return
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].RoomID != objects[j].RoomID {
+			return objects[i].RoomID < objects[j].RoomID
+		}
+		return objects[i].Name < objects[j].Name
+	})
+
+	labels := make([]models.Label, 0, len(objects))
+	for _, object := range objects {
+		code, err := ensureLabelCode(requestCtx(c), object.ID)
+		if err != nil {
+			logging.RequestLog(c).WithFields(logrus.Fields{
+				"objectID": object.ID,
+				"error":    err.Error(),
+			}).Error("Failed to assign a label code")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign label codes"})
+			return
+		}
+		labels = append(labels, models.Label{
+			ObjectID: object.ID,
+			Name:     object.Name,
+			Type:     object.Type,
+			RoomID:   object.RoomID,
+			Code:     code,
+			Link:     labelLink(code),
+		})
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, gin.H{"data": labels})
+		return
+	}
+
+	var sheet bytes.Buffer
+	if err := writeLabelSheet(&sheet, labels); err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to render label sheet")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render labels"})
+		return
+	}
+
+	logging.RequestLog(c).WithField("labels", len(labels)).Info("Label sheet rendered")
+	c.Header("Content-Disposition", `attachment; filename="labels.pdf"`)
+	c.Data(http.StatusOK, "application/pdf", sheet.Bytes())
+}
+
+// LookupLabel handles GET /labels/:code: the object a scanned or typed
+// short code belongs to
+func LookupLabel(c *gin.Context) {
+	code := normalizeLabelCode(c.Param("code"))
+
+	objectID, err := database.RDB.Get(requestCtx(c), labelKey(code)).Result()
+	if err == redis.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown label code"})
+		return
+	}
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"code":  code,
+			"error": err.Error(),
+		}).Error("Failed to look up label code")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up label code"})
+		return
+	}
+
+	object, err := loadObject(requestCtx(c), objectID)
+	if err == redis.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+		return
+	}
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to load labelled object")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load object"})
+		return
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"code":     code,
+		"objectID": objectID,
+	}).Info("Label code resolved")
+	c.JSON(http.StatusOK, gin.H{"data": object})
+}
+
+// writeLabelSheet lays the labels out on as many pages as needed. The QR
+// codes are drawn as vector squares so they stay sharp at any print size.
+func writeLabelSheet(w io.Writer, labels []models.Label) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetTitle("Object labels", true)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	_, pageHeight := pdf.GetPageSize()
+	top := (pageHeight - labelRows*labelHeight) / 2
+	qrSize := labelHeight - 2*labelPadding
+	textX := labelPadding + qrSize + labelPadding
+	textWidth := labelWidth - textX - labelPadding
+
+	for i, label := range labels {
+		slot := i % (labelColumns * labelRows)
+		if slot == 0 {
+			pdf.AddPage()
+		}
+		x := float64(slot%labelColumns) * labelWidth
+		y := top + float64(slot/labelColumns)*labelHeight
+
+		qr, err := qrcode.New(label.Link, qrcode.Medium)
+		if err != nil {
+			return err
+		}
+		drawQRCode(pdf, qr.Bitmap(), x+labelPadding, y+labelPadding, qrSize)
+
+		pdf.SetFont("Helvetica", "B", 9)
+		lineY := y + labelPadding + 2
+		for _, line := range wrapLabelText(pdf, tr, label.Name, textWidth, 3) {
+			pdf.Text(x+textX, lineY, line)
+			lineY += 4
+		}
+		pdf.SetFont("Helvetica", "", 8)
+		for _, line := range wrapLabelText(pdf, tr, label.Type, textWidth, 1) {
+			pdf.Text(x+textX, lineY+1, line)
+		}
+
+		pdf.SetFont("Courier", "B", 11)
+		pdf.Text(x+textX, y+labelHeight-labelPadding-1, formatLabelCode(label.Code))
+	}
+
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
+
+// drawQRCode draws the modules of a QR code, quiet zone included, in a
+// size x size square
+func drawQRCode(pdf *gofpdf.Fpdf, bitmap [][]bool, x, y, size float64) {
+	module := size / float64(len(bitmap))
+	pdf.SetFillColor(0, 0, 0)
+	for row, cells := range bitmap {
+		for col, dark := range cells {
+			if dark {
+				pdf.Rect(x+float64(col)*module, y+float64(row)*module, module, module, "F")
+			}
+		}
+	}
+}
+
+// wrapLabelText breaks s into at most maxLines lines of the given width,
+// shortening the last one when the text does not fit
+func wrapLabelText(pdf *gofpdf.Fpdf, tr func(string) string, s string, width float64, maxLines int) []string {
+	lines := []string{}
+	current := ""
+	words := strings.Fields(s)
+	for i, word := range words {
+		candidate := strings.TrimSpace(current + " " + word)
+		if current == "" || pdf.GetStringWidth(tr(candidate)) <= width {
+			current = candidate
+			continue
+		}
+		if len(lines) == maxLines-1 {
+			current = strings.Join(append([]string{current}, words[i:]...), " ")
+			break
+		}
+		lines = append(lines, tr(current))
+		current = word
+	}
+	if current == "" {
+		return lines
+	}
+
+	if pdf.GetStringWidth(tr(current)) > width {
+		runes := []rune(current)
+		for len(runes) > 0 && pdf.GetStringWidth(tr(string(runes)+"...")) > width {
+			runes = runes[:len(runes)-1]
+		}
+		current = strings.TrimSpace(string(runes)) + "..."
+	}
+	return append(lines, tr(current))
+}
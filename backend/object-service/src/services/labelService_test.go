@@ -0,0 +1,93 @@
+package services_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"hexagone/object-service/src/models"
+	"hexagone/object-service/src/services"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fetchLabels(t *testing.T, url string) []models.Label {
+	w := performRequest("GET", url, nil, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response map[string][]models.Label
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response["data"]
+}
+
+func TestPrintLabels(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+	createTestObject(t, map[string]interface{}{"name": "Théière", "type": "vaisselle", "room_id": "1"})
+	createTestObject(t, map[string]interface{}{"name": "Armoire", "type": "meuble", "room_id": "1"})
+	createTestObject(t, map[string]interface{}{"name": "Horloge", "type": "décoration", "room_id": "attic"})
+	createTestObject(t, map[string]interface{}{"name": "Vélo", "type": "sport", "room_id": "garage"})
+	services.LabelBaseURL = "https://inventaire.example"
+
+	labels := fetchLabels(t, "/labels?room_id=1&format=json")
+	require.Len(t, labels, 2)
+	assert.Equal(t, "Armoire", labels[0].Name, "labels are sorted by name within a room")
+	for _, label := range labels {
+		assert.Regexp(t, `^[2-9A-HJKMNP-Z]{8}$`, label.Code)
+		assert.Equal(t, "https://inventaire.example/o/"+label.Code, label.Link)
+	}
+
+	// Reprinting keeps the codes already stuck on the objects
+	assert.Equal(t, labels, fetchLabels(t, "/labels?room_id=1&format=json"))
+
+	// A home covers the objects of its rooms, whatever room they are in
+	names := []string{}
+	for _, label := range fetchLabels(t, "/labels?home_id=1&format=json") {
+		names = append(names, label.Name)
+	}
+	assert.ElementsMatch(t, []string{"Armoire", "Théière", "Horloge"}, names)
+	assert.Equal(t, http.StatusNotFound, performRequest("GET", "/labels?home_id=2&format=json", nil, "").Code)
+
+	w := performRequest("GET", "/labels?home_id=1", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")))
+
+	assert.Equal(t, http.StatusBadRequest, performRequest("GET", "/labels", nil, "").Code)
+	assert.Equal(t, http.StatusBadRequest, performRequest("GET", "/labels?room_id=1&home_id=1", nil, "").Code)
+	assert.Equal(t, http.StatusNotFound, performRequest("GET", "/labels?room_id=cellar", nil, "").Code)
+}
+
+func TestLookupLabel(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	object := createTestObject(t, map[string]interface{}{"name": "Armoire", "type": "meuble", "room_id": "room1"})
+	labels := fetchLabels(t, "/labels?room_id=room1&format=json")
+	require.Len(t, labels, 1)
+	code := labels[0].Code
+
+	// Codes are accepted as printed, with the dash, or typed in lowercase
+	for _, typed := range []string{code, code[:4] + "-" + code[4:], strings.ToLower(code)} {
+		w := performRequest("GET", "/labels/"+typed, nil, "")
+		require.Equal(t, http.StatusOK, w.Code, typed)
+
+		var response map[string]models.Object
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, object.ID, response["data"].ID)
+	}
+
+	assert.Equal(t, http.StatusNotFound, performRequest("GET", "/labels/ZZZZZZZZ", nil, "").Code)
+
+	// Deleting the object frees its code
+	performRequest("DELETE", "/objects/"+object.ID, nil, "")
+	assert.Equal(t, http.StatusNotFound, performRequest("GET", "/labels/"+code, nil, "").Code)
+	assert.False(t, mr.Exists("label:"+code))
+	assert.False(t, mr.Exists("object:"+object.ID+":label"))
+}
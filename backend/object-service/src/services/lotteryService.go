@@ -0,0 +1,559 @@
+package services
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hexagone/object-service/src/database"
+	"hexagone/object-service/src/models"
+	"hexagone/shared/logging"
+	"hexagone/shared/tracing"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// lotteryAlgorithm documents how a winner is derived from the recorded seed
+// so anyone can replay a draw from its audit record
+const lotteryAlgorithm = "participants sorted by userId; winner = participants[rand.New(rand.NewSource(seed)).Intn(len(participants))] (Go math/rand)"
+
+// lotteryWindowsKey is a sorted set of objects with an open window, scored by closing time
+const lotteryWindowsKey = "lottery:windows"
+
+var errLotteryBusy = errors.New("lottery draw already in progress")
+
+func lotteryKey(objectID string) string {
+	return "object:" + objectID + ":lottery"
+}
+
+func interestKey(objectID string) string {
+	return "object:" + objectID + ":interest"
+}
+
+func lotteryLockKey(objectID string) string {
+	return "object:" + objectID + ":lottery:lock"
+}
+
+// lotteryHistoryKey lists the closed lotteries of an object, oldest first,
+// kept when a new window replaces them
+func lotteryHistoryKey(objectID string) string {
+	return "object:" + objectID + ":lotteries"
+}
+
+// loadLottery returns the lottery of an object, or redis.Nil if no window was ever opened
+func loadLottery(ctx context.Context, objectID string) (models.Lottery, error) {
+	var lottery models.Lottery
+
+	val, err := database.RDB.Get(ctx, lotteryKey(objectID)).Result()
+	if err != nil {
+		return lottery, err
+	}
+
+	err = json.Unmarshal([]byte(val), &lottery)
+	return lottery, err
+}
+
+func saveLottery(ctx context.Context, lottery models.Lottery) error {
+	data, err := json.Marshal(lottery)
+	if err != nil {
+		return err
+	}
+
+	return database.RDB.Set(ctx, lotteryKey(lottery.ObjectID), data, 0).Err()
+}
+
+// loadLotteryHistory returns the closed lotteries a new window replaced, oldest first
+func loadLotteryHistory(ctx context.Context, objectID string) ([]models.Lottery, error) {
+	vals, err := database.RDB.LRange(ctx, lotteryHistoryKey(objectID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	history := []models.Lottery{}
+	for _, val := range vals {
+		var lottery models.Lottery
+		if err := json.Unmarshal([]byte(val), &lottery); err != nil {
+			logging.LogFrom(ctx).WithField("objectID", objectID).Warn("Failed to unmarshal past lottery, skipping")
+			continue
+		}
+		history = append(history, lottery)
+	}
+	return history, nil
+}
+
+// hasOpenLottery reports whether an object is currently collecting declarations of interest
+func hasOpenLottery(ctx context.Context, objectID string) (bool, error) {
+	lottery, err := loadLottery(ctx, objectID)
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return lottery.Status == models.LotteryOpen, nil
+}
+
+// loadParticipants returns the users who declared interest, in registration order
+func loadParticipants(ctx context.Context, objectID string) ([]models.Participant, error) {
+	members, err := database.RDB.ZRangeWithScores(ctx, interestKey(objectID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	participants := []models.Participant{}
+	for _, member := range members {
+		participants = append(participants, models.Participant{
+			UserID: member.Member.(string),
+			At:     time.Unix(0, int64(member.Score)).UTC(),
+		})
+	}
+	return participants, nil
+}
+
+// PickLotteryWinner replays a draw: it returns the user ID selected by seed
+// among the given participants, following lotteryAlgorithm
+func PickLotteryWinner(seed int64, participants []models.Participant) string {
+	if len(participants) == 0 {
+		return ""
+	}
+
+	sorted := make([]models.Participant, len(participants))
+	copy(sorted, participants)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].UserID < sorted[j].UserID })
+
+	return sorted[rand.New(rand.NewSource(seed)).Intn(len(sorted))].UserID
+}
+
+func newLotterySeed() (int64, error) {
+	var buf [8]byte
+	if _, err := crand.Read(buf[:]); err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(buf[:]) >> 1), nil
+}
+
+// drawLottery closes the window of an object and reserves it for the winner.
+// When the object cannot be reserved the lottery is closed as failed, with
+// the reason. Drawing an already closed lottery returns its existing record.
+func drawLottery(ctx context.Context, objectID string) (models.Lottery, error) {
+	acquired, err := database.RDB.SetNX(ctx, lotteryLockKey(objectID), "1", 30*time.Second).Result()
+	if err != nil {
+		return models.Lottery{}, err
+	}
+	if !acquired {
+		return models.Lottery{}, errLotteryBusy
+	}
+	defer database.RDB.Del(ctx, lotteryLockKey(objectID))
+
+	lottery, err := loadLottery(ctx, objectID)
+	if err != nil {
+		return lottery, err
+	}
+	if lottery.Status != models.LotteryOpen {
+		return lottery, nil
+	}
+
+	participants, err := loadParticipants(ctx, objectID)
+	if err != nil {
+		return lottery, err
+	}
+	sort.Slice(participants, func(i, j int) bool { return participants[i].UserID < participants[j].UserID })
+
+	drawnAt := time.Now().UTC()
+	lottery.Participants = participants
+	lottery.DrawnAt = &drawnAt
+	lottery.Status = models.LotteryVoid
+
+	if len(participants) > 0 {
+		seed, err := newLotterySeed()
+		if err != nil {
+			return lottery, err
+		}
+		lottery.Status = models.LotteryDrawn
+		lottery.Algorithm = lotteryAlgorithm
+		lottery.Seed = seed
+		lottery.WinnerID = PickLotteryWinner(seed, participants)
+
+		// A winner who cannot be given the object still closes the window,
+		// so that the draw is not retried on every tick of the worker
+		if err := reserveForWinner(ctx, lottery); err != nil {
+			lottery.Status = models.LotteryFailed
+			lottery.Reason = err.Error()
+			logging.LogFrom(ctx).WithFields(logrus.Fields{
+				"objectID": objectID,
+				"winnerID": lottery.WinnerID,
+				"error":    err.Error(),
+			}).Error("Failed to reserve the object for the lottery winner")
+		}
+	}
+
+	if err := saveLottery(ctx, lottery); err != nil {
+		return lottery, err
+	}
+	database.RDB.ZRem(ctx, lotteryWindowsKey, objectID)
+	database.RDB.Del(ctx, interestKey(objectID))
+
+	logging.LogFrom(ctx).WithFields(logrus.Fields{
+		"objectID":     objectID,
+		"status":       lottery.Status,
+		"participants": len(participants),
+		"seed":         lottery.Seed,
+		"winnerID":     lottery.WinnerID,
+	}).Info("Lottery drawn")
+
+	return lottery, nil
+}
+
+// reserveForWinner gives the object to the lottery winner, still going
+// through executor approval when the home requires it
+func reserveForWinner(ctx context.Context, lottery models.Lottery) error {
+	var from, status models.ReservationStatus
+	_, object, err := updateObject(ctx, lottery.ObjectID, func(_ *redis.Tx, _ bookingChanges, object *models.Object) error {
+		if object.CurrentDisposition().LeavesHouse() {
+			return fmt.Errorf("object is set aside to %s", object.Disposition)
+		}
+
+		settings, err := loadHomeSettings(ctx, object.HomeID)
+		if err != nil {
+			return err
+		}
+		status = models.StatusApproved
+		if settings.RequireApproval {
+			status = models.StatusPending
+		}
+
+		from = object.CurrentStatus()
+		if !from.CanTransition(status) {
+			return fmt.Errorf("object cannot be reserved while %s", describeStatus(from))
+		}
+
+		object.IsReserved = true
+		object.ReservedBy = lottery.WinnerID
+		object.ReservationStatus = status
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	indexReservation(ctx, lottery.WinnerID, object.ID)
+	recordHistory(ctx, object.ID, models.HistoryEntry{
+		Action:     "lottery",
+		From:       from,
+		To:         status,
+		UserID:     lottery.WinnerID,
+		ClaimantID: lottery.WinnerID,
+		Comment:    fmt.Sprintf("Won lottery among %d participants (seed %d)", len(lottery.Participants), lottery.Seed),
+	})
+	reservationsMade.WithLabelValues("lottery").Inc()
+	return nil
+}
+
+// DrawDueLotteries draws every lottery whose window has closed
+func DrawDueLotteries() {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	objectIDs, err := database.RDB.ZRangeByScore(database.Ctx, lotteryWindowsKey, &redis.ZRangeBy{Min: "-inf", Max: now}).Result()
+	if err != nil {
+		logging.Log.WithField("error", err.Error()).Error("Failed to fetch closed lottery windows")
+		return
+	}
+
+	for _, objectID := range objectIDs {
+		ctx, span := tracing.StartJob(database.Ctx, "lottery draw")
+		span.SetAttributes(attribute.String("object.id", objectID))
+		if _, err := drawLottery(ctx, objectID); err != nil && err != errLotteryBusy {
+			span.SetStatus(codes.Error, err.Error())
+			logging.Log.WithFields(logrus.Fields{
+				"objectID": objectID,
+				"error":    err.Error(),
+			}).Error("Failed to draw lottery")
+		}
+		span.End()
+	}
+}
+
+// StartLotteryWorker periodically draws lotteries whose window has closed,
+// until ctx is done
+func StartLotteryWorker(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				DrawDueLotteries()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+type OpenInterestWindowInput struct {
+	DurationMinutes int `json:"durationMinutes" binding:"required,min=1"`
+}
+
+// OpenInterestWindow starts collecting declarations of interest on an object
+func OpenInterestWindow(c *gin.Context) {
+	objectID := c.Param("id")
+	var input OpenInterestWindowInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to bind input for interest window")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	object, err := loadObject(requestCtx(c), objectID)
+	if err != nil {
+		logging.RequestLog(c).WithField("objectID", objectID).Warn("Object not found")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+		return
+	}
+
+	if object.IsReserved {
+		c.JSON(http.StatusConflict, gin.H{"error": "Object is already reserved"})
+		return
+	}
+
+	now := time.Now().UTC()
+	lottery := models.Lottery{
+		ObjectID:     objectID,
+		Status:       models.LotteryOpen,
+		OpenedBy:     c.GetHeader("X-User-ID"),
+		OpensAt:      now,
+		ClosesAt:     now.Add(time.Duration(input.DurationMinutes) * time.Minute),
+		Participants: []models.Participant{},
+	}
+	data, err := json.Marshal(lottery)
+	if err != nil {
+		logging.RequestLog(c).WithField("objectID", objectID).Error("Failed to marshal lottery")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open interest window"})
+		return
+	}
+
+	// A closed lottery is moved to the object's past lotteries rather than
+	// overwritten, so that its draw can still be audited
+	err = database.RDB.Watch(requestCtx(c), func(tx *redis.Tx) error {
+		previous, err := tx.Get(requestCtx(c), lotteryKey(objectID)).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if previous != "" {
+			var closed models.Lottery
+			if err := json.Unmarshal([]byte(previous), &closed); err != nil {
+				return err
+			}
+			if closed.Status == models.LotteryOpen {
+				return errRefused{http.StatusConflict, "An interest window is already open for this object"}
+			}
+		}
+
+		_, err = tx.TxPipelined(requestCtx(c), func(pipe redis.Pipeliner) error {
+			if previous != "" {
+				pipe.RPush(requestCtx(c), lotteryHistoryKey(objectID), previous)
+			}
+			pipe.Del(requestCtx(c), interestKey(objectID))
+			pipe.Set(requestCtx(c), lotteryKey(objectID), data, 0)
+			pipe.ZAdd(requestCtx(c), lotteryWindowsKey, redis.Z{Score: float64(lottery.ClosesAt.Unix()), Member: objectID})
+			return nil
+		})
+		return err
+	}, lotteryKey(objectID))
+	var refused errRefused
+	if errors.As(err, &refused) {
+		c.JSON(refused.code, gin.H{"error": refused.message})
+		return
+	}
+	if err == redis.TxFailedErr {
+		c.JSON(http.StatusConflict, gin.H{"error": "The lottery was modified while opening the window, try again"})
+		return
+	}
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to store lottery")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open interest window"})
+		return
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"objectID": objectID,
+		"closesAt": lottery.ClosesAt,
+	}).Info("Interest window opened")
+
+	c.JSON(http.StatusOK, gin.H{"data": lottery})
+}
+
+// DeclareInterest registers the calling user as a candidate for an object's lottery
+func DeclareInterest(c *gin.Context) {
+	objectID := c.Param("id")
+	userID := c.GetHeader("X-User-ID")
+
+	if userID == "" {
+		logging.RequestLog(c).WithField("objectID", objectID).Warn("No user ID found in header")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	lottery, err := loadLottery(requestCtx(c), objectID)
+	if err == redis.Nil || (err == nil && lottery.Status != models.LotteryOpen) {
+		c.JSON(http.StatusConflict, gin.H{"error": "No interest window is open for this object"})
+		return
+	}
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to load lottery")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load lottery"})
+		return
+	}
+
+	now := time.Now().UTC()
+	if !now.Before(lottery.ClosesAt) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The interest window has closed"})
+		return
+	}
+
+	added, err := database.RDB.ZAddNX(requestCtx(c), interestKey(objectID), redis.Z{Score: float64(now.UnixNano()), Member: userID}).Result()
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to record interest")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record interest"})
+		return
+	}
+	if added == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Interest already declared"})
+		return
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"objectID": objectID,
+		"userID":   userID,
+	}).Info("Interest declared")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Interest declared", "closesAt": lottery.ClosesAt})
+}
+
+// WithdrawInterest removes the calling user from an open lottery
+func WithdrawInterest(c *gin.Context) {
+	objectID := c.Param("id")
+	userID := c.GetHeader("X-User-ID")
+
+	if userID == "" {
+		logging.RequestLog(c).WithField("objectID", objectID).Warn("No user ID found in header")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	open, err := hasOpenLottery(requestCtx(c), objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load lottery"})
+		return
+	}
+	if !open {
+		c.JSON(http.StatusConflict, gin.H{"error": "No interest window is open for this object"})
+		return
+	}
+
+	removed, err := database.RDB.ZRem(requestCtx(c), interestKey(objectID), userID).Result()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw interest"})
+		return
+	}
+	if removed == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You have not declared interest in this object"})
+		return
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"objectID": objectID,
+		"userID":   userID,
+	}).Info("Interest withdrawn")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Interest withdrawn"})
+}
+
+// GetLottery returns the interest window of an object, including the draw
+// audit record once it has closed
+func GetLottery(c *gin.Context) {
+	objectID := c.Param("id")
+
+	lottery, err := loadLottery(requestCtx(c), objectID)
+	if err == redis.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No lottery for this object"})
+		return
+	}
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to load lottery")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load lottery"})
+		return
+	}
+
+	if lottery.Status == models.LotteryOpen {
+		participants, err := loadParticipants(requestCtx(c), objectID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load participants"})
+			return
+		}
+		lottery.Participants = participants
+	}
+
+	history, err := loadLotteryHistory(requestCtx(c), objectID)
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to load past lotteries")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load lottery"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": lottery, "history": history})
+}
+
+// DrawLottery closes an interest window early and draws its winner
+func DrawLottery(c *gin.Context) {
+	objectID := c.Param("id")
+
+	lottery, err := drawLottery(requestCtx(c), objectID)
+	if err == redis.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No lottery for this object"})
+		return
+	}
+	if err == errLotteryBusy {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to draw lottery")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to draw lottery"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": lottery})
+}
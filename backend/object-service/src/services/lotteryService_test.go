@@ -0,0 +1,217 @@
+package services_test
+
+import (
+	"encoding/json"
+	"hexagone/object-service/src/models"
+	"hexagone/object-service/src/services"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLottery(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	object := createTestObject(t, map[string]interface{}{
+		"name": "Horloge comtoise", "type": "furniture", "room_id": "room1",
+	})
+	url := "/objects/" + object.ID
+
+	w := performRequest("POST", url+"/lottery", map[string]interface{}{"durationMinutes": 60}, "admin")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest("POST", url+"/lottery", map[string]interface{}{"durationMinutes": 60}, "admin")
+	assert.Equal(t, http.StatusConflict, w.Code, "a second window cannot be opened")
+
+	for _, userID := range []string{"heir3", "heir1", "heir2", "heir4"} {
+		w = performRequest("POST", url+"/interest", nil, userID)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	w = performRequest("POST", url+"/interest", nil, "heir1")
+	assert.Equal(t, http.StatusConflict, w.Code, "interest can only be declared once")
+
+	w = performRequest("POST", url+"/interest", map[string]interface{}{"userId": "heir5"}, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "the participant is the caller, not a user named in the body")
+
+	w = performRequest("DELETE", url+"/interest", nil, "heir5")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest("DELETE", url+"/interest", nil, "heir4")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest("PATCH", url+"/reserve", nil, "heir1")
+	assert.Equal(t, http.StatusConflict, w.Code, "direct reservations are blocked while the window is open")
+
+	w = performRequest("GET", url+"/lottery", nil, "")
+	var open map[string]models.Lottery
+	json.Unmarshal(w.Body.Bytes(), &open)
+	assert.Equal(t, models.LotteryOpen, open["data"].Status)
+	assert.Len(t, open["data"].Participants, 3)
+
+	w = performRequest("POST", url+"/lottery/draw", nil, "admin")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var drawn map[string]models.Lottery
+	json.Unmarshal(w.Body.Bytes(), &drawn)
+	lottery := drawn["data"]
+	assert.Equal(t, models.LotteryDrawn, lottery.Status)
+	assert.Len(t, lottery.Participants, 3)
+	assert.NotEmpty(t, lottery.Algorithm)
+
+	// The draw is reproducible from the recorded seed and participants
+	assert.Equal(t, services.PickLotteryWinner(lottery.Seed, lottery.Participants), lottery.WinnerID)
+
+	// Drawing again returns the same record
+	w = performRequest("POST", url+"/lottery/draw", nil, "admin")
+	var again map[string]models.Lottery
+	json.Unmarshal(w.Body.Bytes(), &again)
+	assert.Equal(t, lottery.WinnerID, again["data"].WinnerID)
+	assert.Equal(t, lottery.Seed, again["data"].Seed)
+
+	w = performRequest("GET", "/objects/reserved", nil, "")
+	var reserved map[string][]models.Object
+	json.Unmarshal(w.Body.Bytes(), &reserved)
+	if assert.Len(t, reserved["data"], 1) {
+		assert.Equal(t, lottery.WinnerID, reserved["data"][0].ReservedBy)
+	}
+
+	w = performRequest("POST", url+"/interest", nil, "heir5")
+	assert.Equal(t, http.StatusConflict, w.Code, "the window is closed after the draw")
+}
+
+func TestLotteryWithoutParticipants(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	object := createTestObject(t, map[string]interface{}{
+		"name": "Tapis", "type": "decoration", "room_id": "room1",
+	})
+	url := "/objects/" + object.ID
+
+	w := performRequest("POST", url+"/lottery", map[string]interface{}{"durationMinutes": 0}, "admin")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	performRequest("POST", url+"/lottery", map[string]interface{}{"durationMinutes": 5}, "admin")
+	w = performRequest("POST", url+"/lottery/draw", nil, "admin")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]models.Lottery
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, models.LotteryVoid, response["data"].Status)
+	assert.Empty(t, response["data"].WinnerID)
+
+	w = performRequest("PATCH", url+"/reserve", nil, "heir1")
+	assert.Equal(t, http.StatusOK, w.Code, "the object is available again after a void lottery")
+}
+
+func TestLotteryWinnerCannotReserve(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	object := createTestObject(t, map[string]interface{}{
+		"name": "Miroir", "type": "decoration", "room_id": "room1",
+	})
+	url := "/objects/" + object.ID
+
+	performRequest("POST", url+"/lottery", map[string]interface{}{"durationMinutes": 5}, "admin")
+	performRequest("POST", url+"/interest", nil, "heir1")
+	mr.Del(object.ID)
+
+	w := performRequest("POST", url+"/lottery/draw", nil, "admin")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]models.Lottery
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, models.LotteryFailed, response["data"].Status)
+	assert.NotEmpty(t, response["data"].Reason)
+	assert.Equal(t, "heir1", response["data"].WinnerID)
+
+	// The window is closed, so the worker does not draw it again
+	members, _ := mr.ZMembers("lottery:windows")
+	assert.NotContains(t, members, object.ID)
+	assert.False(t, mr.Exists("object:"+object.ID+":lottery:lock"))
+}
+
+func TestPickLotteryWinnerIgnoresRegistrationOrder(t *testing.T) {
+	a := []models.Participant{{UserID: "b"}, {UserID: "a"}, {UserID: "c"}}
+	b := []models.Participant{{UserID: "c"}, {UserID: "b"}, {UserID: "a"}}
+
+	for seed := int64(0); seed < 20; seed++ {
+		assert.Equal(t, services.PickLotteryWinner(seed, a), services.PickLotteryWinner(seed, b))
+	}
+	assert.Empty(t, services.PickLotteryWinner(1, nil))
+}
+
+func TestLotteryWinnerOfObjectLeavingHouse(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	performRequest("PUT", "/homes/1/settings", map[string]interface{}{"executorId": "executor"}, "")
+	object := createTestObject(t, map[string]interface{}{
+		"name": "Buffet", "type": "furniture", "room_id": "room1",
+	})
+	url := "/objects/" + object.ID
+
+	performRequest("POST", url+"/lottery", map[string]interface{}{"durationMinutes": 5}, "admin")
+	performRequest("POST", url+"/interest", nil, "heir1")
+	w := performRequest("PATCH", url+"/disposition", map[string]interface{}{"disposition": "donate"}, "executor")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest("POST", url+"/lottery/draw", nil, "admin")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]models.Lottery
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, models.LotteryFailed, response["data"].Status)
+	assert.Contains(t, response["data"].Reason, "donate")
+
+	stored := mustLoadObject(t, object.ID)
+	assert.False(t, stored.IsReserved)
+}
+
+func TestLotteryReopenKeepsHistory(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	object := createTestObject(t, map[string]interface{}{
+		"name": "Commode", "type": "furniture", "room_id": "room1",
+	})
+	url := "/objects/" + object.ID
+
+	performRequest("POST", url+"/lottery", map[string]interface{}{"durationMinutes": 5}, "admin")
+	w := performRequest("POST", url+"/lottery/draw", nil, "admin")
+	var void map[string]models.Lottery
+	json.Unmarshal(w.Body.Bytes(), &void)
+	assert.Equal(t, models.LotteryVoid, void["data"].Status)
+
+	w = performRequest("POST", url+"/lottery", map[string]interface{}{"durationMinutes": 5}, "admin")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest("GET", url+"/lottery", nil, "")
+	var response struct {
+		Data    models.Lottery   `json:"data"`
+		History []models.Lottery `json:"history"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, models.LotteryOpen, response.Data.Status)
+	if assert.Len(t, response.History, 1) {
+		assert.Equal(t, models.LotteryVoid, response.History[0].Status)
+		assert.Equal(t, void["data"].Seed, response.History[0].Seed)
+	}
+
+	w = performRequest("DELETE", url, nil, "admin")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, mr.Exists("object:"+object.ID+":lotteries"))
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hexagone/object-service/src/database"
+	"hexagone/object-service/src/models"
+	"hexagone/shared/logging"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// tagIndexKey is the set of objects carrying a tag
+func tagIndexKey(tag string) string {
+	return "tag:" + tag + ":objects"
+}
+
+// conditionIndexKey is the set of objects in a given condition
+func conditionIndexKey(condition models.Condition) string {
+	return "condition:" + string(condition) + ":objects"
+}
+
+// validateObjectMetadata checks what binding tags cannot express and
+// normalizes the tags in place
+func validateObjectMetadata(input *CreateObjectInput) error {
+	if input.Condition != "" && !input.Condition.Valid() {
+		return fmt.Errorf("condition must be one of %v", models.Conditions)
+	}
+	if input.Year > time.Now().Year() {
+		return fmt.Errorf("year cannot be in the future")
+	}
+
+	input.Tags = models.NormalizeTags(input.Tags)
+	if len(input.Tags) > models.MaxTags {
+		return fmt.Errorf("an object can have at most %d tags", models.MaxTags)
+	}
+	for _, tag := range input.Tags {
+		if len([]rune(tag)) > models.MaxTagLength {
+			return fmt.Errorf("tag %q is longer than %d characters", tag, models.MaxTagLength)
+		}
+	}
+	return nil
+}
+
+// indexObjectMetadata adds an object to the room, tag, condition and category indexes
+func indexObjectMetadata(ctx context.Context, pipe redis.Pipeliner, object models.Object) {
+	if object.RoomID != "" {
+		pipe.SAdd(ctx, roomObjectsKey(object.RoomID), object.ID)
+	}
+	for _, tag := range object.Tags {
+		pipe.SAdd(ctx, tagIndexKey(tag), object.ID)
+	}
+	if object.Condition != "" {
+		pipe.SAdd(ctx, conditionIndexKey(object.Condition), object.ID)
+	}
+	if object.CategoryID != "" {
+		pipe.SAdd(ctx, categoryObjectsKey(object.CategoryID), object.ID)
+	}
+}
+
+// unindexObjectMetadata removes an object from the room, tag, condition and category indexes
+func unindexObjectMetadata(ctx context.Context, pipe redis.Pipeliner, object models.Object) {
+	if object.RoomID != "" {
+		pipe.SRem(ctx, roomObjectsKey(object.RoomID), object.ID)
+	}
+	for _, tag := range object.Tags {
+		pipe.SRem(ctx, tagIndexKey(tag), object.ID)
+	}
+	if object.Condition != "" {
+		pipe.SRem(ctx, conditionIndexKey(object.Condition), object.ID)
+	}
+	if object.CategoryID != "" {
+		pipe.SRem(ctx, categoryObjectsKey(object.CategoryID), object.ID)
+	}
+}
+
+// hasMetadataFilter reports whether a listing was asked to filter by
+// ?tag=, ?condition= or ?category=
+func hasMetadataFilter(c *gin.Context) bool {
+	return len(c.QueryArray("tag")) > 0 || len(c.QueryArray("condition")) > 0 || c.Query("category") != ""
+}
+
+// filteredObjectIDs resolves ?tag= (every tag must match), ?condition= (any
+// condition may match) and ?category= (the category or any of its
+// descendants) against the indexes
+func filteredObjectIDs(c *gin.Context, categories map[string]models.Category) ([]string, error) {
+	var ids []string
+	filtered := false
+
+	// narrow keeps the IDs found so far that are also in members
+	narrow := func(members []string) {
+		if !filtered {
+			ids, filtered = members, true
+			return
+		}
+		in := map[string]bool{}
+		for _, id := range members {
+			in[id] = true
+		}
+		matching := []string{}
+		for _, id := range ids {
+			if in[id] {
+				matching = append(matching, id)
+			}
+		}
+		ids = matching
+	}
+
+	if tags := models.NormalizeTags(c.QueryArray("tag")); len(tags) > 0 {
+		keys := make([]string, len(tags))
+		for i, tag := range tags {
+			keys[i] = tagIndexKey(tag)
+		}
+		members, err := database.RDB.SInter(requestCtx(c), keys...).Result()
+		if err != nil {
+			return nil, err
+		}
+		narrow(members)
+	}
+
+	if conditions := c.QueryArray("condition"); len(conditions) > 0 {
+		keys := make([]string, len(conditions))
+		for i, condition := range conditions {
+			keys[i] = conditionIndexKey(models.Condition(condition))
+		}
+		members, err := database.RDB.SUnion(requestCtx(c), keys...).Result()
+		if err != nil {
+			return nil, err
+		}
+		narrow(members)
+	}
+
+	if categoryID := c.Query("category"); categoryID != "" {
+		descendants := categoryDescendants(categories, categoryID)
+		keys := make([]string, len(descendants))
+		for i, id := range descendants {
+			keys[i] = categoryObjectsKey(id)
+		}
+		members, err := database.RDB.SUnion(requestCtx(c), keys...).Result()
+		if err != nil {
+			return nil, err
+		}
+		narrow(members)
+	}
+	return ids, nil
+}
+
+// listFilteredObjects answers a filtered listing from the indexes rather
+// than scanning every key, keeping objects matching keep
+func listFilteredObjects(c *gin.Context, keep func(models.Object) bool) {
+	for _, condition := range c.QueryArray("condition") {
+		if !models.Condition(condition).Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("condition must be one of %v", models.Conditions)})
+			return
+		}
+	}
+
+	categories, err := loadCategories(requestCtx(c))
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to load categories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load categories"})
+		return
+	}
+	if categoryID := c.Query("category"); categoryID != "" {
+		if _, ok := categories[categoryID]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Category not found"})
+			return
+		}
+	}
+
+	ids, err := filteredObjectIDs(c, categories)
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to read object indexes")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch objects"})
+		return
+	}
+
+	indexed, err := loadObjects(requestCtx(c), ids)
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to fetch indexed objects")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch objects"})
+		return
+	}
+
+	objects := []models.Object{}
+	for _, obj := range indexed {
+		if keep(obj) {
+			objects = append(objects, obj)
+		}
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"tags":       c.QueryArray("tag"),
+		"conditions": c.QueryArray("condition"),
+		"category":   c.Query("category"),
+		"count":      len(objects),
+	}).Info("Filtered objects fetched successfully")
+
+	c.JSON(http.StatusOK, gin.H{"data": objects})
+}
@@ -0,0 +1,135 @@
+package services_test
+
+import (
+	"encoding/json"
+	"hexagone/object-service/src/models"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateObjectMetadataValidation(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	base := func(extra map[string]interface{}) map[string]interface{} {
+		input := map[string]interface{}{"name": "Buffet", "type": "furniture", "room_id": "room1"}
+		for k, v := range extra {
+			input[k] = v
+		}
+		return input
+	}
+
+	tests := []struct {
+		name         string
+		input        map[string]interface{}
+		expectedCode int
+	}{
+		{"Unknown Condition", base(map[string]interface{}{"condition": "pristine"}), http.StatusBadRequest},
+		{"Negative Weight", base(map[string]interface{}{"weightKg": -3}), http.StatusBadRequest},
+		{"Negative Dimension", base(map[string]interface{}{"dimensions": map[string]interface{}{"widthCm": -1}}), http.StatusBadRequest},
+		{"Future Year", base(map[string]interface{}{"year": 3000}), http.StatusBadRequest},
+		{"Too Many Tags", base(map[string]interface{}{"tags": []string{
+			"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u",
+		}}), http.StatusBadRequest},
+		{"Valid Metadata", base(map[string]interface{}{
+			"description": "Buffet deux corps en chêne massif",
+			"condition":   "good",
+			"dimensions":  map[string]interface{}{"widthCm": 140, "depthCm": 50, "heightCm": 210},
+			"weightKg":    85.5,
+			"material":    "oak",
+			"year":        1925,
+			"tags":        []string{"Art Deco", "art  deco", "heavy"},
+		}), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := performRequest("POST", "/objects", tt.input, "")
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+
+	w := performRequest("GET", "/objects", nil, "")
+	var response map[string][]models.Object
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if assert.Len(t, response["data"], 1) {
+		object := response["data"][0]
+		assert.Equal(t, models.ConditionGood, object.Condition)
+		assert.Equal(t, []string{"art deco", "heavy"}, object.Tags, "tags are normalized and deduplicated")
+		assert.Equal(t, 210.0, object.Dimensions.HeightCm)
+		assert.Equal(t, 85.5, object.WeightKg)
+		assert.Equal(t, 1925, object.Year)
+	}
+}
+
+func TestFilterObjectsByMetadata(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	createTestObject(t, map[string]interface{}{
+		"name": "Fauteuil", "type": "furniture", "room_id": "room1", "condition": "good", "tags": []string{"art deco", "seating"},
+	})
+	createTestObject(t, map[string]interface{}{
+		"name": "Chaise", "type": "furniture", "room_id": "room2", "condition": "poor", "tags": []string{"seating"},
+	})
+	lamp := createTestObject(t, map[string]interface{}{
+		"name": "Lampe", "type": "lighting", "room_id": "room1", "condition": "good", "tags": []string{"art deco"},
+	})
+
+	tests := []struct {
+		name          string
+		url           string
+		expectedCode  int
+		expectedNames []string
+	}{
+		{"By Tag", "/objects?tag=seating", http.StatusOK, []string{"Chaise", "Fauteuil"}},
+		{"Tag Is Case Insensitive", "/objects?tag=Art%20Deco", http.StatusOK, []string{"Fauteuil", "Lampe"}},
+		{"Every Tag Must Match", "/objects?tag=seating&tag=art%20deco", http.StatusOK, []string{"Fauteuil"}},
+		{"By Condition", "/objects?condition=good", http.StatusOK, []string{"Fauteuil", "Lampe"}},
+		{"Any Condition May Match", "/objects?condition=good&condition=poor", http.StatusOK, []string{"Chaise", "Fauteuil", "Lampe"}},
+		{"Tag And Condition", "/objects?tag=seating&condition=poor", http.StatusOK, []string{"Chaise"}},
+		{"Within A Room", "/objects/room?room_id=room1&tag=seating", http.StatusOK, []string{"Fauteuil"}},
+		{"Unknown Tag", "/objects?tag=baroque", http.StatusOK, []string{}},
+		{"Unknown Condition", "/objects?condition=pristine", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := performRequest("GET", tt.url, nil, "")
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedNames == nil {
+				return
+			}
+
+			var response map[string][]models.Object
+			json.Unmarshal(w.Body.Bytes(), &response)
+			names := []string{}
+			for _, object := range response["data"] {
+				names = append(names, object.Name)
+			}
+			assert.ElementsMatch(t, tt.expectedNames, names)
+		})
+	}
+
+	// Deleted objects leave the indexes
+	performRequest("DELETE", "/objects/"+lamp.ID, nil, "")
+	assert.Len(t, mustMembers(t, "condition:good:objects"), 1)
+	w := performRequest("GET", "/objects?tag=art%20deco", nil, "")
+	var response map[string][]models.Object
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Len(t, response["data"], 1)
+}
+
+func mustMembers(t *testing.T, key string) []string {
+	members, err := mr.Members(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return members
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Domain events counted on /metrics, next to the HTTP and DragonflyDB
+// metrics of the metrics package
+var (
+	objectsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "objects_created_total",
+		Help: "Objects added to the inventory, by source: api or import.",
+	}, []string{"source"})
+
+	reservationsMade = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "reservations_made_total",
+		Help: "Objects reserved by a family member, by how: request or lottery.",
+	}, []string{"via"})
+
+	reservationsReleased = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "reservations_released_total",
+		Help: "Reservations given back, by reason: unreserved or rejected.",
+	}, []string{"reason"})
+)
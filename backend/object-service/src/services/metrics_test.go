@@ -0,0 +1,67 @@
+package services_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"hexagone/object-service/src/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metricValue scrapes /metrics for the value of one series, 0 when absent
+func metricValue(t *testing.T, series string) float64 {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if value, found := strings.CutPrefix(line, series+" "); found {
+			number, err := strconv.ParseFloat(value, 64)
+			require.NoError(t, err)
+			return number
+		}
+	}
+	return 0
+}
+
+func TestDomainMetrics(t *testing.T) {
+	require.NoError(t, setupTestServer())
+	defer cleanupTest()
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "7")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	created := metricValue(t, `objects_created_total{source="api"}`)
+	made := metricValue(t, `reservations_made_total{via="request"}`)
+	released := metricValue(t, `reservations_released_total{reason="unreserved"}`)
+
+	w := send("POST", "/objects", `{"name":"Clock","type":"decor","room_id":"room1"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data models.Object `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	id := response.Data.ID
+
+	send("POST", "/objects", `{"type":"decor"}`) // Rejected, not counted
+	require.Equal(t, http.StatusOK, send("PATCH", "/objects/"+id+"/reserve", "").Code)
+	send("PATCH", "/objects/"+id+"/reserve", "") // Already reserved, not counted
+	require.Equal(t, http.StatusOK, send("PATCH", "/objects/"+id+"/unreserve", "").Code)
+
+	assert.Equal(t, created+1, metricValue(t, `objects_created_total{source="api"}`))
+	assert.Equal(t, made+1, metricValue(t, `reservations_made_total{via="request"}`))
+	assert.Equal(t, released+1, metricValue(t, `reservations_released_total{reason="unreserved"}`))
+	assert.Positive(t, metricValue(t, `redis_command_duration_seconds_count{command="get",status="ok"}`))
+}
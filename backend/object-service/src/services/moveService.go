@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hexagone/object-service/src/database"
+	"hexagone/object-service/src/models"
+	"hexagone/shared/logging"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// roomIndexBuiltKey marks that objects created before the room index
+// existed have been indexed
+const roomIndexBuiltKey = "room-index:built"
+
+// roomObjectsKey is the set of objects in a room
+func roomObjectsKey(roomID string) string {
+	return "room:" + roomID + ":objects"
+}
+
+// BuildRoomIndex adds the objects created before the room index existed to
+// it. It only does work the first time it runs against a database.
+func BuildRoomIndex() error {
+	first, err := database.RDB.SetNX(database.Ctx, roomIndexBuiltKey, time.Now().UTC().Format(time.RFC3339), 0).Result()
+	if err != nil || !first {
+		return err
+	}
+
+	objects, err := fetchObjects(database.Ctx, func(models.Object) bool { return true })
+	if err == nil {
+		pipe := database.RDB.Pipeline()
+		for _, object := range objects {
+			if object.RoomID != "" {
+				pipe.SAdd(database.Ctx, roomObjectsKey(object.RoomID), object.ID)
+			}
+		}
+		_, err = pipe.Exec(database.Ctx)
+	}
+	if err != nil {
+		// Try again on the next start
+		database.RDB.Del(database.Ctx, roomIndexBuiltKey)
+		return err
+	}
+
+	logging.Log.WithField("count", len(objects)).Info("Objects indexed by room")
+	return nil
+}
+
+// relocate puts an object in another room, and in that room's home. A
+// pickup booking only holds at the house it was made for, so the object
+// leaves it when it changes home; its reservation is kept. The booking is
+// changed in bookings, which the caller queues in its transaction.
+func relocate(ctx context.Context, tx *redis.Tx, bookings bookingChanges, object *models.Object, room Room) error {
+	homeID := fmt.Sprint(room.HomeID)
+	if object.HomeID != homeID {
+		if err := bookings.detach(ctx, tx, object); err != nil {
+			return err
+		}
+	}
+	object.RoomID = fmt.Sprint(room.ID)
+	object.HomeID = homeID
+	return nil
+}
+
+// recordMove appends a move to the history of an object
+func recordMove(ctx context.Context, before, after models.Object, userID, comment string) {
+	recordHistory(ctx, after.ID, models.HistoryEntry{
+		Action:  "move",
+		From:    before.CurrentStatus(),
+		To:      after.CurrentStatus(),
+		UserID:  userID,
+		Comment: comment,
+		Move: &models.Move{
+			FromRoomID: before.RoomID,
+			FromHomeID: before.HomeID,
+			ToRoomID:   after.RoomID,
+			ToHomeID:   after.HomeID,
+		},
+	})
+}
+
+// resolveTargetRoom checks with the room service that the room objects are
+// moved to exists, writing the error response and returning false otherwise
+func resolveTargetRoom(c *gin.Context, roomID string) (Room, bool) {
+	room, err := fetchRoom(requestCtx(c), roomID)
+	if err == errRoomNotFound {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Target room does not exist"})
+		return room, false
+	}
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"roomID": roomID,
+			"error":  err.Error(),
+		}).Error("Failed to check the target room")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to check the target room"})
+		return room, false
+	}
+	return room, true
+}
+
+type MoveObjectInput struct {
+	RoomID  string `json:"roomId" binding:"required"`
+	Comment string `json:"comment"`
+}
+
+// MoveObject handles POST /objects/:id/move: puts an object in another room,
+// possibly of another home, and records the move in its history
+func MoveObject(c *gin.Context) {
+	objectID := c.Param("id")
+	userID := c.GetHeader("X-User-ID")
+	var input MoveObjectInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to bind input for move")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !isObjectKey(objectID) {
+		logging.RequestLog(c).WithField("objectID", objectID).Warn("Object not found")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+		return
+	}
+
+	room, ok := resolveTargetRoom(c, input.RoomID)
+	if !ok {
+		return
+	}
+
+	var before, after models.Object
+	err := database.RDB.Watch(requestCtx(c), func(tx *redis.Tx) error {
+		val, err := tx.Get(requestCtx(c), objectID).Result()
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(val), &before); err != nil {
+			return err
+		}
+
+		after = before
+		bookings := bookingChanges{}
+		if err := relocate(requestCtx(c), tx, bookings, &after, room); err != nil {
+			return err
+		}
+		data, err := json.Marshal(after)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(requestCtx(c), func(pipe redis.Pipeliner) error {
+			pipe.Set(requestCtx(c), objectID, data, 0)
+			unindexObjectMetadata(requestCtx(c), pipe, before)
+			indexObjectMetadata(requestCtx(c), pipe, after)
+			return bookings.queue(requestCtx(c), pipe)
+		})
+		return err
+	}, objectID)
+	if err == redis.Nil {
+		logging.RequestLog(c).WithField("objectID", objectID).Warn("Object not found")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+		return
+	}
+	if err == redis.TxFailedErr {
+		c.JSON(http.StatusConflict, gin.H{"error": "The object was modified during the move, try again"})
+		return
+	}
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to move object")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move object"})
+		return
+	}
+
+	if before.RoomID != after.RoomID {
+		recordMove(requestCtx(c), before, after, userID, input.Comment)
+		indexObjectForSearch(requestCtx(c), after)
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"objectID": objectID,
+		"from":     before.RoomID,
+		"to":       after.RoomID,
+		"userID":   userID,
+	}).Info("Object moved")
+
+	c.JSON(http.StatusOK, gin.H{"data": after})
+}
@@ -0,0 +1,130 @@
+package services_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"hexagone/object-service/src/models"
+	"hexagone/object-service/src/services"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listRoomObjects(t *testing.T, roomID string) []models.Object {
+	w := performRequest("GET", "/objects/room?room_id="+roomID, nil, "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string][]models.Object
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response["data"]
+}
+
+func TestMoveObject(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+	rooms := fakeRoomService(t)
+	defer rooms.Close()
+
+	// A cellar in another home
+	resp, err := http.Post(rooms.URL+"/rooms", "application/json", bytes.NewBufferString(`{"name": "Cave", "home_id": 2}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	object := createTestObject(t, map[string]interface{}{"name": "Armoire", "type": "furniture", "room_id": "1"})
+	performRequest("PATCH", "/objects/"+object.ID+"/reserve", nil, "heir1")
+	require.Len(t, listRoomObjects(t, "1"), 1)
+
+	w := performRequest("POST", "/objects/"+object.ID+"/move", map[string]interface{}{"roomId": "2", "comment": "Stored for the winter"}, "admin1")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response map[string]models.Object
+	json.Unmarshal(w.Body.Bytes(), &response)
+	moved := response["data"]
+	assert.Equal(t, "2", moved.RoomID)
+	assert.Equal(t, "2", moved.HomeID)
+	assert.True(t, moved.IsReserved, "the reservation survives the move")
+	assert.Equal(t, "heir1", moved.ReservedBy)
+
+	assert.Empty(t, listRoomObjects(t, "1"))
+	assert.Len(t, listRoomObjects(t, "2"), 1)
+
+	w = performRequest("GET", "/objects/"+object.ID+"/history", nil, "")
+	var history map[string][]models.HistoryEntry
+	json.Unmarshal(w.Body.Bytes(), &history)
+	last := history["data"][len(history["data"])-1]
+	assert.Equal(t, "move", last.Action)
+	assert.Equal(t, "admin1", last.UserID)
+	assert.Equal(t, "Stored for the winter", last.Comment)
+	assert.Equal(t, &models.Move{FromRoomID: "1", FromHomeID: "1", ToRoomID: "2", ToHomeID: "2"}, last.Move)
+	assert.Equal(t, models.StatusApproved, last.To)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, performRequest("POST", "/objects/"+object.ID+"/move", map[string]interface{}{"roomId": "42"}, "admin1").Code)
+	assert.Equal(t, http.StatusNotFound, performRequest("POST", "/objects/missing/move", map[string]interface{}{"roomId": "1"}, "admin1").Code)
+	performRequest("PUT", "/homes/1/settings", map[string]interface{}{"executorId": "executor"}, "")
+	assert.Equal(t, http.StatusNotFound, performRequest("POST", "/objects/home:1:settings/move", map[string]interface{}{"roomId": "1"}, "admin1").Code)
+	assert.NotContains(t, mustMembersOrNil(t, "room:1:objects"), "home:1:settings")
+	assert.Equal(t, http.StatusBadRequest, performRequest("POST", "/objects/"+object.ID+"/move", map[string]interface{}{}, "admin1").Code)
+}
+
+func TestBuildRoomIndex(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	// An object stored before the room index existed
+	mr.Set("legacy-object", `{"id": "legacy-object", "name": "Horloge", "type": "decor", "room_id": "attic"}`)
+	assert.Empty(t, listRoomObjects(t, "attic"))
+
+	require.NoError(t, services.BuildRoomIndex())
+	assert.Len(t, listRoomObjects(t, "attic"), 1)
+
+	// Later runs leave the index alone
+	mr.SRem("room:attic:objects", "legacy-object")
+	require.NoError(t, services.BuildRoomIndex())
+	assert.Empty(t, listRoomObjects(t, "attic"))
+}
+
+func TestMoveObjectLeavesItsBooking(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	slot := createTestSlot(t, "1", 2)
+	chair := reserveTestObject(t, "Chaise", "1", "heir1")
+	table := reserveTestObject(t, "Table", "1", "heir1")
+	w := performRequest("POST", "/pickup-slots/"+slot.ID+"/bookings", map[string]interface{}{
+		"objectIds": []string{chair.ID, table.ID},
+	}, "heir1")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response map[string]models.PickupBooking
+	json.Unmarshal(w.Body.Bytes(), &response)
+	bookingKey := "pickup:booking:" + response["data"].ID
+
+	rooms := fakeRoomService(t)
+	defer rooms.Close()
+	resp, err := http.Post(rooms.URL+"/rooms", "application/json", bytes.NewBufferString(`{"name": "Cave", "home_id": 2}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// Moving one object to another home keeps the booking for the other
+	w = performRequest("POST", "/objects/"+chair.ID+"/move", map[string]interface{}{"roomId": "2"}, "admin1")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, mustLoadObject(t, chair.ID).PickupBookingID)
+	var booking models.PickupBooking
+	stored, _ := mr.Get(bookingKey)
+	json.Unmarshal([]byte(stored), &booking)
+	assert.Equal(t, []string{table.ID}, booking.ObjectIDs)
+
+	// Moving the last one releases the booking and its place, once
+	w = performRequest("POST", "/objects/"+table.ID+"/move", map[string]interface{}{"roomId": "2"}, "admin1")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.False(t, mr.Exists(bookingKey))
+	booked, _ := mr.Get("pickup:slot:" + slot.ID + ":booked")
+	assert.Equal(t, "0", booked)
+}
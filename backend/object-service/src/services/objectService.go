@@ -1,112 +1,248 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"hexagone/object-service/src/database"
 	"hexagone/object-service/src/models"
-	"hexagone/object-service/src/utils"
+	"hexagone/shared/logging"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 )
 
-type ReserveObjectInput struct {
-	UserID string `json:"userId" binding:"required"`
+// requestCtx is the context of the DragonflyDB commands and service calls
+// made while handling a request. They join the request's trace, but unlike
+// the request they are not cancelled when the client goes away, so that a
+// write made of several commands is not left halfway.
+func requestCtx(c *gin.Context) context.Context {
+	return context.WithoutCancel(c.Request.Context())
 }
 
-// ReserveObject allows a user to reserve an object by its ID
-func ReserveObject(c *gin.Context) {
-	objectID := c.Param("id")
-	var input ReserveObjectInput
+// isObjectKey reports whether a DragonflyDB key holds an object rather than
+// one of the namespaced records (settings, history) stored next to them
+func isObjectKey(key string) bool {
+	return !strings.Contains(key, ":")
+}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.Log.WithFields(logrus.Fields{
-			"objectID": objectID,
-			"error":    err.Error(),
-		}).Error("Failed to bind input for reservation")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+// loadObjects reads the objects with the given IDs, skipping the ones that
+// no longer exist
+func loadObjects(ctx context.Context, ids []string) ([]models.Object, error) {
+	objects := []models.Object{}
+	if len(ids) == 0 {
+		return objects, nil
+	}
+
+	values, err := database.RDB.MGet(ctx, ids...).Result()
+	if err != nil {
+		return nil, err
 	}
+	for i, value := range values {
+		val, ok := value.(string)
+		if !ok {
+			logging.LogFrom(ctx).WithField("objectID", ids[i]).Warn("Indexed object no longer exists, skipping")
+			continue
+		}
 
-	utils.Log.WithField("objectID", objectID).Info("Attempting to reserve object")
+		var obj models.Object
+		if err := json.Unmarshal([]byte(val), &obj); err != nil {
+			logging.LogFrom(ctx).WithField("objectID", ids[i]).Warn("Failed to unmarshal object data, skipping")
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
 
-	// Fetch the object
-	val, err := database.RDB.Get(database.Ctx, objectID).Result()
+// fetchObjects returns every stored object matching keep
+func fetchObjects(ctx context.Context, keep func(models.Object) bool) ([]models.Object, error) {
+	keys, err := database.RDB.Keys(ctx, "*").Result()
 	if err != nil {
-		utils.Log.WithField("objectID", objectID).Warn("Object not found")
-		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
-		return
+		return nil, err
 	}
 
-	var object models.Object
-	if err := json.Unmarshal([]byte(val), &object); err != nil {
-		utils.Log.WithField("objectID", objectID).Error("Failed to unmarshal object data")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process object data"})
-		return
+	objects := []models.Object{}
+	for _, key := range keys {
+		if !isObjectKey(key) {
+			continue
+		}
+
+		val, err := database.RDB.Get(ctx, key).Result()
+		if err != nil {
+			logging.LogFrom(ctx).WithField("key", key).Warn("Failed to retrieve object from DragonflyDB, skipping")
+			continue
+		}
+
+		var obj models.Object
+		if err := json.Unmarshal([]byte(val), &obj); err != nil {
+			logging.LogFrom(ctx).WithField("key", key).Warn("Failed to unmarshal object data, skipping")
+			continue
+		}
+
+		if keep(obj) {
+			objects = append(objects, obj)
+		}
 	}
+	return objects, nil
+}
 
-	// Check if the object is already reserved
-	if object.IsReserved {
-		utils.Log.WithField("objectID", objectID).Info("Object is already reserved")
-		c.JSON(http.StatusConflict, gin.H{"error": "Object is already reserved"})
+// ReserveObject lets the calling user reserve an object by its ID
+func ReserveObject(c *gin.Context) {
+	objectID := c.Param("id")
+	userID := c.GetHeader("X-User-ID")
+	if userID == "" {
+		logging.RequestLog(c).WithField("objectID", objectID).Warn("No user ID found in header")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
 		return
 	}
 
-	// Reserve the object
-	object.IsReserved = true
-	object.ReservedBy = input.UserID
+	logging.RequestLog(c).WithField("objectID", objectID).Info("Attempting to reserve object")
 
-	// Save the updated object back to DragonflyDB
-	data, err := json.Marshal(object)
+	// Contested objects go to the lottery winner instead of the fastest click
+	open, err := hasOpenLottery(requestCtx(c), objectID)
 	if err != nil {
-		utils.Log.WithField("objectID", objectID).Error("Failed to marshal updated object")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save reservation"})
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to load lottery")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load lottery"})
+		return
+	}
+	if open {
+		logging.RequestLog(c).WithField("objectID", objectID).Info("Object has an open interest window")
+		c.JSON(http.StatusConflict, gin.H{"error": "Object is open for declarations of interest"})
 		return
 	}
 
-	err = database.RDB.Set(database.Ctx, object.ID, data, 0).Err()
+	// The checks run again when the object changes before the reservation is written
+	var previous, status models.ReservationStatus
+	_, object, err := updateObject(requestCtx(c), objectID, func(_ *redis.Tx, _ bookingChanges, object *models.Object) error {
+		if object.IsReserved {
+			return errRefused{http.StatusConflict, "Object is already reserved"}
+		}
+		if object.CurrentDisposition().LeavesHouse() {
+			return errRefused{http.StatusConflict, "Object is set aside to " + string(object.Disposition)}
+		}
+
+		// Homes in approval mode only get a pending claim until the executor signs off
+		settings, err := loadHomeSettings(requestCtx(c), object.HomeID)
+		if err != nil {
+			return err
+		}
+		status = models.StatusApproved
+		if settings.RequireApproval {
+			status = models.StatusPending
+		}
+		previous = object.CurrentStatus()
+		if !previous.CanTransition(status) {
+			return errRefused{http.StatusConflict, "Object cannot be reserved in its current state"}
+		}
+
+		object.IsReserved = true
+		object.ReservedBy = userID
+		object.ReservationStatus = status
+		return nil
+	})
 	if err != nil {
-		utils.Log.WithField("objectID", objectID).Error("Failed to update object in database")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update object in database"})
+		respondUpdateError(c, objectID, err, "Failed to save reservation")
 		return
 	}
 
-	utils.Log.WithFields(logrus.Fields{
+	indexReservation(requestCtx(c), userID, object.ID)
+	recordHistory(requestCtx(c), object.ID, models.HistoryEntry{
+		Action:     "reserve",
+		From:       previous,
+		To:         status,
+		UserID:     userID,
+		ClaimantID: userID,
+	})
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
 		"objectID": object.ID,
-		"userID":   input.UserID,
+		"userID":   userID,
+		"status":   status,
 	}).Info("Object reserved successfully")
+	reservationsMade.WithLabelValues("request").Inc()
 
 	c.JSON(http.StatusOK, gin.H{"data": object})
 }
 
+// prepareObjectDeletion watches and loads what goes along with an object
+// deleted in a transaction: its photos, whose blobs are deleted once the
+// transaction has committed, and its place in a pickup booking
+func prepareObjectDeletion(ctx context.Context, tx *redis.Tx, bookings bookingChanges, object *models.Object) ([]models.Photo, error) {
+	if err := tx.Watch(ctx, photosKey(object.ID)).Err(); err != nil {
+		return nil, err
+	}
+	photos, err := loadPhotos(ctx, object.ID)
+	if err != nil {
+		return nil, err
+	}
+	return photos, bookings.detach(ctx, tx, object)
+}
+
+// queueObjectDeletion deletes an object in a transaction along with its
+// photo list, history and lotteries, dropping it from the tag and condition
+// indexes and freeing its label code
+func queueObjectDeletion(ctx context.Context, pipe redis.Pipeliner, object models.Object) {
+	pipe.Del(ctx, object.ID, photosKey(object.ID), historyKey(object.ID), lotteryKey(object.ID), lotteryHistoryKey(object.ID), interestKey(object.ID))
+	pipe.ZRem(ctx, lotteryWindowsKey, object.ID)
+	unindexObjectMetadata(ctx, pipe, object)
+	unindexObjectLabel(ctx, pipe, object.ID)
+}
+
+// afterObjectDeletion cleans up the indexes and photo blobs kept outside the
+// deletion transaction once it has committed
+func afterObjectDeletion(ctx context.Context, object models.Object, photos []models.Photo) {
+	if object.IsReserved {
+		unindexReservation(ctx, object.ReservedBy, object.ID)
+	}
+	unindexObjectForSearch(ctx, object.ID)
+	for _, photo := range photos {
+		deletePhotoBlobs(ctx, photo)
+	}
+}
+
 func DeleteObject(c *gin.Context) {
 	objectID := c.Param("id")
 
-	utils.Log.WithField("objectID", objectID).Info("Attempting to delete object")
+	logging.RequestLog(c).WithField("objectID", objectID).Info("Attempting to delete object")
 
-	// Check if the object exists first
-	exists, err := database.RDB.Exists(database.Ctx, objectID).Result()
-	if err != nil {
-		utils.Log.WithFields(logrus.Fields{
-			"objectID": objectID,
-			"error":    err.Error(),
-		}).Error("Failed to check object existence")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check object existence"})
-		return
-	}
+	var object models.Object
+	var photos []models.Photo
+	err := database.RDB.Watch(requestCtx(c), func(tx *redis.Tx) error {
+		var err error
+		if object, err = loadObject(requestCtx(c), objectID); err != nil {
+			return err
+		}
+
+		bookings := bookingChanges{}
+		if photos, err = prepareObjectDeletion(requestCtx(c), tx, bookings, &object); err != nil {
+			return err
+		}
 
-	if exists == 0 {
-		utils.Log.WithField("objectID", objectID).Warn("Object not found")
+		_, err = tx.TxPipelined(requestCtx(c), func(pipe redis.Pipeliner) error {
+			queueObjectDeletion(requestCtx(c), pipe, object)
+			return bookings.queue(requestCtx(c), pipe)
+		})
+		return err
+	}, objectID)
+	if err == redis.Nil {
+		logging.RequestLog(c).WithField("objectID", objectID).Warn("Object not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
 		return
 	}
-
-	// Delete the object using Del command
-	err = database.RDB.Del(database.Ctx, objectID).Err()
+	if err == redis.TxFailedErr {
+		c.JSON(http.StatusConflict, gin.H{"error": "The object was modified while being deleted, try again"})
+		return
+	}
 	if err != nil {
-		utils.Log.WithFields(logrus.Fields{
+		logging.RequestLog(c).WithFields(logrus.Fields{
 			"objectID": objectID,
 			"error":    err.Error(),
 		}).Error("Failed to delete object")
@@ -114,90 +250,166 @@ func DeleteObject(c *gin.Context) {
 		return
 	}
 
-	utils.Log.WithField("objectID", objectID).Info("Object deleted successfully")
+	afterObjectDeletion(requestCtx(c), object, photos)
+
+	logging.RequestLog(c).WithField("objectID", objectID).Info("Object deleted successfully")
 	c.JSON(http.StatusOK, gin.H{"message": "Object deleted successfully"})
 }
 
 // ListReservedObjects retrieves all reserved objects
 func ListReservedObjects(c *gin.Context) {
-	utils.Log.Info("Fetching all reserved objects")
+	logging.RequestLog(c).Info("Fetching all reserved objects")
+
+	if hasMetadataFilter(c) {
+		listFilteredObjects(c, func(obj models.Object) bool { return obj.IsReserved })
+		return
+	}
 
-	keys, err := database.RDB.Keys(database.Ctx, "*").Result()
+	keys, err := database.RDB.Keys(requestCtx(c), "*").Result()
 	if err != nil {
-		utils.Log.WithField("error", err.Error()).Error("Failed to fetch keys from DragonflyDB")
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to fetch keys from DragonflyDB")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch keys"})
 		return
 	}
 
-	utils.Log.WithField("keysCount", len(keys)).Info("Fetched keys from database")
+	logging.RequestLog(c).WithField("keysCount", len(keys)).Info("Fetched keys from database")
 
 	reservedObjects := []models.Object{}
 	for _, key := range keys {
-		val, err := database.RDB.Get(database.Ctx, key).Result()
+		if !isObjectKey(key) {
+			continue
+		}
+
+		val, err := database.RDB.Get(requestCtx(c), key).Result()
 		if err != nil {
-			utils.Log.WithField("key", key).Warn("Failed to retrieve object from DragonflyDB, skipping")
+			logging.RequestLog(c).WithField("key", key).Warn("Failed to retrieve object from DragonflyDB, skipping")
 			continue
 		}
 
 		var obj models.Object
 		if err := json.Unmarshal([]byte(val), &obj); err != nil {
-			utils.Log.WithField("key", key).Warn("Failed to unmarshal object data, skipping")
+			logging.RequestLog(c).WithField("key", key).Warn("Failed to unmarshal object data, skipping")
 			continue
 		}
 
 		if obj.IsReserved {
-			utils.Log.WithField("objectID", obj.ID).Info("Reserved object found")
+			logging.RequestLog(c).WithField("objectID", obj.ID).Info("Reserved object found")
 			reservedObjects = append(reservedObjects, obj)
 		}
 	}
 
-	utils.Log.WithField("reservedCount", len(reservedObjects)).Info("Reserved objects fetched successfully")
+	logging.RequestLog(c).WithField("reservedCount", len(reservedObjects)).Info("Reserved objects fetched successfully")
 	c.JSON(http.StatusOK, gin.H{"data": reservedObjects})
 }
 
 type CreateObjectInput struct {
-	Name   string `json:"name" binding:"required"`
-	Type   string `json:"type" binding:"required"`
-	RoomID string `json:"room_id" binding:"required"`
+	Name        string             `json:"name" binding:"required"`
+	Type        string             `json:"type" binding:"required_without=CategoryID"`
+	CategoryID  string             `json:"categoryId"`
+	RoomID      string             `json:"room_id" binding:"required"`
+	Description string             `json:"description" binding:"max=2000"`
+	Condition   models.Condition   `json:"condition"`
+	Dimensions  *models.Dimensions `json:"dimensions"`
+	WeightKg    float64            `json:"weightKg" binding:"gte=0"`
+	Value       float64            `json:"value" binding:"gte=0"`
+	Material    string             `json:"material" binding:"max=100"`
+	Year        int                `json:"year" binding:"omitempty,gte=1000"`
+	Tags        []string           `json:"tags"`
+}
+
+// insertObject stores a new object along with its tag, condition and
+// category indexes, then adds it to the search index
+func insertObject(ctx context.Context, object models.Object) error {
+	pipe := database.RDB.TxPipeline()
+	if err := queueObjectInsert(ctx, pipe, object); err != nil {
+		return err
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	indexObjectForSearch(ctx, object)
+	return nil
+}
+
+// queueObjectInsert queues the writes that store a new object and its
+// metadata indexes
+func queueObjectInsert(ctx context.Context, pipe redis.Pipeliner, object models.Object) error {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+
+	pipe.Set(ctx, object.ID, data, 0)
+	indexObjectMetadata(ctx, pipe, object)
+	return nil
 }
 
 // CreateObject adds a new object to DragonflyDB
 func CreateObject(c *gin.Context) {
 	var input CreateObjectInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.Log.WithField("error", err.Error()).Error("Failed to bind JSON input for object creation")
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to bind JSON input for object creation")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateObjectMetadata(&input); err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Warn("Invalid object metadata")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	warning, err := resolveObjectCategory(requestCtx(c), &input)
+	if err != nil {
+		if err == errUnknownCategory {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Category not found"})
+			return
+		}
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to load categories")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load categories"})
+		return
+	}
 
-	utils.Log.WithFields(logrus.Fields{
+	// The home comes from the room service, so that it always matches the room
+	room, err := fetchRoom(requestCtx(c), input.RoomID)
+	if err == errRoomNotFound {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Room does not exist"})
+		return
+	}
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"roomID": input.RoomID,
+			"error":  err.Error(),
+		}).Error("Failed to check the room")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to check the room"})
+		return
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
 		"objectName": input.Name,
 		"objectType": input.Type,
 	}).Info("Creating new object")
 
 	// Create an object
 	object := models.Object{
-		ID:     uuid.New().String(),
-		Name:   input.Name,
-		Type:   input.Type,
-		RoomID: input.RoomID,
-	}
-
-	// Serialize object to JSON
-	data, err := json.Marshal(object)
-	if err != nil {
-		utils.Log.WithFields(logrus.Fields{
-			"objectID": object.ID,
-			"error":    err.Error(),
-		}).Error("Failed to marshal object to JSON")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process object"})
-		return
+		ID:          uuid.New().String(),
+		Name:        input.Name,
+		Type:        input.Type,
+		CategoryID:  input.CategoryID,
+		RoomID:      input.RoomID,
+		HomeID:      fmt.Sprint(room.HomeID),
+		Description: input.Description,
+		Condition:   input.Condition,
+		Dimensions:  input.Dimensions,
+		WeightKg:    input.WeightKg,
+		Value:       input.Value,
+		Material:    input.Material,
+		Year:        input.Year,
+		Tags:        input.Tags,
+		Disposition: models.DispositionUndecided,
 	}
 
-	// Store object in DragonflyDB
-	err = database.RDB.Set(database.Ctx, object.ID, data, 0).Err()
-	if err != nil {
-		utils.Log.WithFields(logrus.Fields{
+	if err := insertObject(requestCtx(c), object); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
 			"objectID": object.ID,
 			"error":    err.Error(),
 		}).Error("Failed to store object in DragonflyDB")
@@ -205,139 +417,207 @@ func CreateObject(c *gin.Context) {
 		return
 	}
 
-	utils.Log.WithField("objectID", object.ID).Info("Object created and stored successfully")
+	logging.RequestLog(c).WithField("objectID", object.ID).Info("Object created and stored successfully")
+	objectsCreated.WithLabelValues("api").Inc()
+	if warning != "" {
+		c.JSON(http.StatusOK, gin.H{"data": object, "warning": warning})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"data": object})
 }
 
-// ListObjects retrieves all objects from DragonflyDB
+// ListObjects retrieves all objects from DragonflyDB, or those listed in
+// ?ids=a,b
 func ListObjects(c *gin.Context) {
-	utils.Log.Info("Fetching all objects from DragonflyDB")
+	if ids := c.Query("ids"); ids != "" {
+		listObjectsByID(c, splitIDs(ids))
+		return
+	}
+
+	logging.RequestLog(c).Info("Fetching all objects from DragonflyDB")
 
-	keys, err := database.RDB.Keys(database.Ctx, "*").Result()
+	if hasMetadataFilter(c) {
+		listFilteredObjects(c, func(models.Object) bool { return true })
+		return
+	}
+
+	keys, err := database.RDB.Keys(requestCtx(c), "*").Result()
 	if err != nil {
-		utils.Log.WithField("error", err.Error()).Error("Failed to fetch keys from DragonflyDB")
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to fetch keys from DragonflyDB")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch keys"})
 		return
 	}
 
-	utils.Log.WithField("keysCount", len(keys)).Info("Fetched keys from database")
+	logging.RequestLog(c).WithField("keysCount", len(keys)).Info("Fetched keys from database")
 
 	objects := []models.Object{}
 	for _, key := range keys {
-		val, err := database.RDB.Get(database.Ctx, key).Result()
+		if !isObjectKey(key) {
+			continue
+		}
+
+		val, err := database.RDB.Get(requestCtx(c), key).Result()
 		if err != nil {
-			utils.Log.WithField("key", key).Warn("Failed to retrieve object from DragonflyDB, skipping")
+			logging.RequestLog(c).WithField("key", key).Warn("Failed to retrieve object from DragonflyDB, skipping")
 			continue
 		}
 
 		var obj models.Object
 		if err := json.Unmarshal([]byte(val), &obj); err != nil {
-			utils.Log.WithField("key", key).Warn("Failed to unmarshal object data, skipping")
+			logging.RequestLog(c).WithField("key", key).Warn("Failed to unmarshal object data, skipping")
 			continue
 		}
 
-		utils.Log.WithField("objectID", obj.ID).Info("Object fetched successfully")
+		logging.RequestLog(c).WithField("objectID", obj.ID).Info("Object fetched successfully")
 		objects = append(objects, obj)
 	}
 
-	utils.Log.WithField("objectsCount", len(objects)).Info("Objects fetched successfully")
+	logging.RequestLog(c).WithField("objectsCount", len(objects)).Info("Objects fetched successfully")
 	c.JSON(http.StatusOK, gin.H{"data": objects})
 }
 
-func ListObjectsByRoom(c *gin.Context) {
-	roomID := c.Query("room_id")
-	if roomID == "" {
-		utils.Log.Warn("room_id is missing in ListObjectsByRoom request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "room_id is required"})
+// listObjectsByID handles GET /objects?ids=: the objects among ids that
+// exist, in no particular order
+func listObjectsByID(c *gin.Context, ids []string) {
+	keys := []string{}
+	for _, id := range ids {
+		if isObjectKey(id) {
+			keys = append(keys, id)
+		}
+	}
+
+	objects, err := loadObjects(requestCtx(c), keys)
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to fetch objects by ID")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch objects"})
 		return
 	}
 
-	utils.Log.WithField("roomID", roomID).Info("Fetching objects for room")
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"requested": len(ids),
+		"found":     len(objects),
+	}).Info("Objects fetched by ID")
+	c.JSON(http.StatusOK, gin.H{"data": objects})
+}
 
-	keys, err := database.RDB.Keys(database.Ctx, "*").Result()
-	if err != nil {
-		utils.Log.WithField("error", err.Error()).Error("Failed to fetch keys from DragonflyDB")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch keys"})
+// ListObjectsByRoom lists the objects of one or more rooms (?room_id=1,2)
+func ListObjectsByRoom(c *gin.Context) {
+	roomIDs := splitIDs(c.Query("room_id"))
+	if len(roomIDs) == 0 {
+		logging.RequestLog(c).Warn("room_id is missing in ListObjectsByRoom request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "room_id is required"})
 		return
 	}
 
-	utils.Log.WithField("keysCount", len(keys)).Info("Fetched keys from database")
+	logging.RequestLog(c).WithField("roomIDs", roomIDs).Info("Fetching objects for rooms")
 
-	objects := []models.Object{}
-	for _, key := range keys {
-		val, err := database.RDB.Get(database.Ctx, key).Result()
-		if err != nil {
-			utils.Log.WithField("key", key).Warn("Failed to retrieve object from DragonflyDB, skipping")
-			continue
+	if hasMetadataFilter(c) {
+		inRooms := map[string]bool{}
+		for _, roomID := range roomIDs {
+			inRooms[roomID] = true
 		}
+		listFilteredObjects(c, func(obj models.Object) bool { return inRooms[obj.RoomID] })
+		return
+	}
 
-		var obj models.Object
-		if err := json.Unmarshal([]byte(val), &obj); err != nil {
-			utils.Log.WithField("key", key).Warn("Failed to unmarshal object data, skipping")
-			continue
-		}
+	// The room index spares scanning every key of the database
+	keys := make([]string, len(roomIDs))
+	for i, roomID := range roomIDs {
+		keys[i] = roomObjectsKey(roomID)
+	}
+	ids, err := database.RDB.SUnion(requestCtx(c), keys...).Result()
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to read the room index")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch objects"})
+		return
+	}
 
-		// Only include objects that belong to the specified room
-		if obj.RoomID == roomID {
-			utils.Log.WithField("objectID", obj.ID).Info("Object in room found")
-			objects = append(objects, obj)
-		}
+	objects, err := loadObjects(requestCtx(c), ids)
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to fetch indexed objects")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch objects"})
+		return
 	}
 
-	utils.Log.WithFields(logrus.Fields{
-		"roomID": roomID,
-		"count":  len(objects),
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"roomIDs": roomIDs,
+		"count":   len(objects),
 	}).Info("Room objects fetched successfully")
 
 	c.JSON(http.StatusOK, gin.H{"data": objects})
 }
 
+// splitIDs reads a comma-separated list of IDs, such as "1,2,3", which lets
+// other services fetch objects for several rooms or IDs in one call
+func splitIDs(value string) []string {
+	ids := []string{}
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// UnreserveObject releases a claim, on behalf of its holder or of the
+// executor of the object's home
 func UnreserveObject(c *gin.Context) {
 	objectID := c.Param("id")
-
-	utils.Log.WithField("objectID", objectID).Info("Attempting to unreserve object")
-
-	// Fetch the object
-	val, err := database.RDB.Get(database.Ctx, objectID).Result()
-	if err != nil {
-		utils.Log.WithField("objectID", objectID).Warn("Object not found")
-		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+	userID := c.GetHeader("X-User-ID")
+	if userID == "" {
+		logging.RequestLog(c).WithField("objectID", objectID).Warn("No user ID found in header")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
 		return
 	}
 
-	var object models.Object
-	if err := json.Unmarshal([]byte(val), &object); err != nil {
-		utils.Log.WithField("objectID", objectID).Error("Failed to unmarshal object data")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process object data"})
-		return
-	}
+	logging.RequestLog(c).WithField("objectID", objectID).Info("Attempting to unreserve object")
 
-	// Check if the object is not reserved
-	if !object.IsReserved {
-		utils.Log.WithField("objectID", objectID).Info("Object is not reserved")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Object is not reserved"})
-		return
-	}
+	var previous models.ReservationStatus
+	before, object, err := updateObject(requestCtx(c), objectID, func(tx *redis.Tx, bookings bookingChanges, object *models.Object) error {
+		if !object.IsReserved {
+			return errRefused{http.StatusBadRequest, "Object is not reserved"}
+		}
 
-	// Remove the reservation
-	object.IsReserved = false
-	object.ReservedBy = ""
+		if object.ReservedBy != userID {
+			settings, err := loadHomeSettings(requestCtx(c), object.HomeID)
+			if err != nil {
+				return err
+			}
+			if settings.ExecutorID == "" || settings.ExecutorID != userID {
+				return errRefused{http.StatusForbidden, "Only the member who claimed this object or the executor of its home can release it"}
+			}
+		}
 
-	// Save the updated object back to DragonflyDB
-	data, err := json.Marshal(object)
-	if err != nil {
-		utils.Log.WithField("objectID", objectID).Error("Failed to marshal updated object")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save unreservation"})
-		return
-	}
+		previous = object.CurrentStatus()
+		if !previous.CanTransition(models.StatusAvailable) {
+			return errRefused{http.StatusConflict, "Object can no longer be unreserved"}
+		}
 
-	err = database.RDB.Set(database.Ctx, object.ID, data, 0).Err()
+		// Remove the reservation, and the object from its pickup booking
+		if err := bookings.detach(requestCtx(c), tx, object); err != nil {
+			return err
+		}
+		object.IsReserved = false
+		object.ReservedBy = ""
+		object.ReservationStatus = models.StatusAvailable
+		return nil
+	})
 	if err != nil {
-		utils.Log.WithField("objectID", objectID).Error("Failed to update object in database")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update object in database"})
+		respondUpdateError(c, objectID, err, "Failed to save unreservation")
 		return
 	}
-
-	utils.Log.WithField("objectID", object.ID).Info("Object unreserved successfully")
+	reservedBy := before.ReservedBy
+
+	unindexReservation(requestCtx(c), reservedBy, object.ID)
+	recordHistory(requestCtx(c), object.ID, models.HistoryEntry{
+		Action:     "unreserve",
+		From:       previous,
+		To:         models.StatusAvailable,
+		UserID:     userID,
+		ClaimantID: reservedBy,
+	})
+
+	logging.RequestLog(c).WithField("objectID", object.ID).Info("Object unreserved successfully")
+	reservationsReleased.WithLabelValues("unreserved").Inc()
 	c.JSON(http.StatusOK, gin.H{"data": object})
 }
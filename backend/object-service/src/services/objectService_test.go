@@ -6,10 +6,16 @@ import (
 	"hexagone/object-service/src/database"
 	"hexagone/object-service/src/models"
 	"hexagone/object-service/src/services"
-	"hexagone/object-service/src/utils"
+	"hexagone/object-service/src/storage"
+	"hexagone/shared/auth"
+	"hexagone/shared/logging"
+	"hexagone/shared/metrics"
+	"io/fs"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -19,9 +25,42 @@ import (
 
 var router *gin.Engine
 var mr *miniredis.Miniredis
+var mediaDir string
+var roomService *httptest.Server
+
+// testServiceToken is the internal service token of the test router
+const testServiceToken = "0123456789abcdef0123456789abcdef"
+
+// testRooms are the rooms the stand-in room service knows, with their home
+var testRooms = map[string]int{
+	"1": 1, "2": 1, "3": 2, "4": 1,
+	"room1": 1, "room2": 1, "room3": 1, "room123": 1, "attic": 1,
+	"room9": 2, "garage": 3,
+}
+
+// roomInHome is a room of the stand-in room service in the given home
+func roomInHome(homeID string) string {
+	if homeID == "2" {
+		return "room9"
+	}
+	return "room1"
+}
+
+// startRoomService stands in for the room service objects are created in
+func startRoomService() {
+	roomService = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		homeID, ok := testRooms[strings.TrimPrefix(r.URL.Path, "/rooms/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"home_id": homeID}})
+	}))
+	services.RoomServiceURL = roomService.URL
+}
 
 func setupTestServer() error {
-	utils.InitLogger()
+	logging.InitLogger()
 	gin.SetMode(gin.TestMode)
 	
 	var err error
@@ -30,10 +69,17 @@ func setupTestServer() error {
 		return err
 	}
 	
-	os.Setenv("DRAGONFLY_HOST", mr.Host())
-	os.Setenv("DRAGONFLY_PORT", mr.Port())
-	
-	if err := database.ConnectDatabase(); err != nil {
+	if err := database.ConnectDatabase(mr.Addr()); err != nil {
+		return err
+	}
+
+	startRoomService()
+
+	mediaDir, err = os.MkdirTemp("", "object-media")
+	if err != nil {
+		return err
+	}
+	if storage.Media, err = storage.NewLocalStore(mediaDir); err != nil {
 		return err
 	}
 	
@@ -44,12 +90,61 @@ func setupTestServer() error {
 	router.PATCH("/objects/:id/reserve", services.ReserveObject)
 	router.PATCH("/objects/:id/unreserve", services.UnreserveObject)
 	router.GET("/objects/reserved", services.ListReservedObjects)
+	router.PATCH("/objects/:id/approve", services.ApproveReservation)
+	router.PATCH("/objects/:id/reject", services.RejectReservation)
+	router.PATCH("/objects/:id/pickup", services.ConfirmPickup)
+	router.GET("/objects/:id/history", services.GetObjectHistory)
+	router.GET("/homes/:id/settings", services.GetHomeSettings)
+	router.PUT("/homes/:id/settings", services.UpdateHomeSettings)
+	router.POST("/objects/:id/interest", services.DeclareInterest)
+	router.DELETE("/objects/:id/interest", services.WithdrawInterest)
+	router.GET("/objects/:id/lottery", services.GetLottery)
+	router.POST("/objects/:id/lottery", services.OpenInterestWindow)
+	router.POST("/objects/:id/lottery/draw", services.DrawLottery)
+	router.POST("/homes/:id/pickup-slots", services.CreatePickupSlot)
+	router.GET("/homes/:id/pickup-slots", services.ListPickupSlots)
+	router.GET("/homes/:id/pickup-schedule", services.ExportPickupSchedule)
+	router.POST("/pickup-slots/:id/bookings", services.BookPickupSlot)
+	router.DELETE("/pickup-bookings/:id", services.CancelPickupBooking)
+	router.POST("/pickup-bookings/:id/handover", services.ConfirmHandover)
+	router.PATCH("/objects/:id/disposition", services.SetDisposition)
+	router.POST("/homes/:id/dispositions", services.BulkAssignDisposition)
+	router.GET("/homes/:id/dispositions", services.DispositionReport)
+	router.GET("/users/:id/reservations", services.ListUserReservations)
+	router.GET("/users/:id/history", services.GetUserHistory)
+	router.GET("/me/reservations", services.ListMyReservations)
+	router.DELETE("/objects/:id", services.DeleteObject)
+	router.POST("/objects/bulk", services.BulkUpdateObjects)
+	router.POST("/objects/:id/move", services.MoveObject)
+	router.POST("/objects/:id/photos", services.UploadObjectPhotos)
+	router.GET("/objects/:id/photos", services.ListObjectPhotos)
+	router.GET("/objects/:id/photos/:photoId/:rendition", services.GetPhotoRendition)
+	router.PUT("/objects/:id/photos/order", services.ReorderObjectPhotos)
+	router.PATCH("/objects/:id/photos/:photoId/primary", services.SetPrimaryPhoto)
+	router.DELETE("/objects/:id/photos/:photoId", services.DeleteObjectPhoto)
+	router.GET("/categories", services.ListCategories)
+	router.GET("/categories/:id", services.GetCategory)
+	router.POST("/categories", services.CreateCategory)
+	router.PATCH("/categories/:id", services.UpdateCategory)
+	router.DELETE("/categories/:id", services.DeleteCategory)
+	router.POST("/categories/migrate", services.MigrateObjectTypes)
+	router.POST("/homes/:id/import", services.ImportObjects)
+	router.GET("/search", services.Search)
+	internalRoutes := router.Group("/", auth.RequireServiceToken(testServiceToken))
+	internalRoutes.PUT("/search/documents/:kind/:id", services.PutSearchDocument)
+	internalRoutes.DELETE("/search/documents/:kind/:id", services.DeleteSearchDocument)
+	router.POST("/search/reindex", services.ReindexSearch)
+	router.GET("/labels", services.PrintLabels)
+	router.GET("/labels/:code", services.LookupLabel)
+	router.GET("/metrics", metrics.Handler())
 	
 	return nil
 }
 
 func cleanupTest() {
 	mr.Close()
+	roomService.Close()
+	os.RemoveAll(mediaDir)
 }
 
 func TestCreateObject(t *testing.T) {
@@ -96,6 +191,15 @@ func TestCreateObject(t *testing.T) {
 			},
 			expectedCode: http.StatusBadRequest,
 		},
+		{
+			name: "Unknown Room",
+			input: map[string]interface{}{
+				"name": "Test Object",
+				"type": "furniture",
+				"room_id": "cellar",
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
 	}
 
 	for _, tt := range tests {
@@ -123,6 +227,25 @@ func TestCreateObject(t *testing.T) {
 	}
 }
 
+func TestCreateObjectTakesHomeFromRoom(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	object := createTestObject(t, map[string]interface{}{
+		"name": "Buffet", "type": "furniture", "room_id": "room9", "home_id": "1",
+	})
+	assert.Equal(t, "2", object.HomeID, "the home sent by the client is ignored")
+
+	// Keys that are not objects cannot be reviewed as objects
+	performRequest("PUT", "/homes/1/settings", map[string]interface{}{
+		"requireApproval": true, "executorId": "executor",
+	}, "")
+	w := performRequest("PATCH", "/objects/home:1:settings/approve", nil, "executor")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
 func TestListObjectsByRoom(t *testing.T) {
 	if err := setupTestServer(); err != nil {
 		t.Fatalf("Failed to setup test server: %v", err)
@@ -200,6 +323,58 @@ func TestListObjectsByRoom(t *testing.T) {
 	}
 }
 
+func TestListObjectsInBatch(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	var ids []string
+	for _, obj := range []map[string]interface{}{
+		{"name": "Object 1", "type": "furniture", "room_id": "room1"},
+		{"name": "Object 2", "type": "electronics", "room_id": "room2"},
+		{"name": "Object 3", "type": "furniture", "room_id": "room3"},
+	} {
+		jsonInput, _ := json.Marshal(obj)
+		req := httptest.NewRequest("POST", "/objects", bytes.NewBuffer(jsonInput))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]models.Object
+		json.Unmarshal(w.Body.Bytes(), &response)
+		ids = append(ids, response["data"].ID)
+	}
+
+	tests := []struct {
+		name          string
+		url           string
+		expectedNames []string
+	}{
+		{"Several rooms", "/objects/room?room_id=room1,room3", []string{"Object 1", "Object 3"}},
+		{"Object IDs", "/objects?ids=" + ids[1] + "," + ids[2] + ",missing", []string{"Object 2", "Object 3"}},
+		{"Only object keys", "/objects?ids=" + ids[0] + ",room-index:built", []string{"Object 1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string][]models.Object
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			names := []string{}
+			for _, obj := range response["data"] {
+				names = append(names, obj.Name)
+			}
+			assert.ElementsMatch(t, tt.expectedNames, names)
+		})
+	}
+}
+
 func TestUnreserveObject(t *testing.T) {
 	if err := setupTestServer(); err != nil {
 		t.Fatalf("Failed to setup test server: %v", err)
@@ -223,12 +398,8 @@ func TestUnreserveObject(t *testing.T) {
 	objectID := createResponse["data"].ID
 
 	// Reserve the object
-	reserveInput := map[string]interface{}{
-		"userId": "user123",
-	}
-	jsonInput, _ = json.Marshal(reserveInput)
-	req = httptest.NewRequest("PATCH", "/objects/"+objectID+"/reserve", bytes.NewBuffer(jsonInput))
-	req.Header.Set("Content-Type", "application/json")
+	req = httptest.NewRequest("PATCH", "/objects/"+objectID+"/reserve", nil)
+	req.Header.Set("X-User-ID", "user123")
 	w = httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusOK, w.Code)
@@ -236,21 +407,42 @@ func TestUnreserveObject(t *testing.T) {
 	tests := []struct {
 		name         string
 		objectID     string
+		userID       string
 		expectedCode int
 	}{
+		{
+			name:         "Without User",
+			objectID:     objectID,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "Another Member",
+			objectID:     objectID,
+			userID:       "user456",
+			expectedCode: http.StatusForbidden,
+		},
 		{
 			name:         "Valid Unreservation",
 			objectID:     objectID,
+			userID:       "user123",
 			expectedCode: http.StatusOK,
 		},
 		{
 			name:         "Already Unreserved",
 			objectID:     objectID,
+			userID:       "user123",
 			expectedCode: http.StatusBadRequest,
 		},
 		{
 			name:         "Non-existent Object",
 			objectID:     "nonexistent",
+			userID:       "user123",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Not An Object",
+			objectID:     "home:1:settings",
+			userID:       "user123",
 			expectedCode: http.StatusNotFound,
 		},
 	}
@@ -258,6 +450,7 @@ func TestUnreserveObject(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			req := httptest.NewRequest("PATCH", "/objects/"+tt.objectID+"/unreserve", nil)
+			req.Header.Set("X-User-ID", tt.userID)
 			w := httptest.NewRecorder()
 			
 			router.ServeHTTP(w, req)
@@ -273,4 +466,53 @@ func TestUnreserveObject(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestDeleteObjectCleansUp(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	slot := createTestSlot(t, "1", 1)
+	chair := reserveTestObject(t, "Chaise", "1", "heir1")
+	table := reserveTestObject(t, "Table", "1", "heir1")
+	w := performRequest("POST", "/pickup-slots/"+slot.ID+"/bookings", map[string]interface{}{
+		"objectIds": []string{chair.ID, table.ID},
+	}, "heir1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var booking map[string]models.PickupBooking
+	json.Unmarshal(w.Body.Bytes(), &booking)
+
+	assert.Equal(t, http.StatusOK, uploadPhotos(chair.ID, map[string][]byte{"front.png": testPNG(60, 30)}).Code)
+	// Left over from a lottery opened before the object was reserved
+	mr.Set("object:"+chair.ID+":lottery", `{"status": "open"}`)
+	mr.ZAdd("object:"+chair.ID+":interest", 1, "heir2")
+	mr.ZAdd("lottery:windows", 1, chair.ID)
+
+	w = performRequest("DELETE", "/objects/"+chair.ID, nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	for _, key := range []string{"photos", "history", "lottery", "interest"} {
+		assert.False(t, mr.Exists("object:"+chair.ID+":"+key), key)
+	}
+	windows, _ := mr.ZMembers("lottery:windows")
+	assert.NotContains(t, windows, chair.ID)
+
+	blobs := 0
+	filepath.WalkDir(mediaDir, func(_ string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			blobs++
+		}
+		return nil
+	})
+	assert.Zero(t, blobs, "the photo renditions are deleted from the media store")
+
+	// The booking keeps the objects still to collect, and goes with the last one
+	stored, _ := mr.Get("pickup:booking:" + booking["data"].ID)
+	assert.NotContains(t, stored, chair.ID)
+	assert.Contains(t, stored, table.ID)
+
+	performRequest("DELETE", "/objects/"+table.ID, nil, "")
+	assert.False(t, mr.Exists("pickup:booking:"+booking["data"].ID))
+	booked, _ := mr.Get("pickup:slot:" + slot.ID + ":booked")
+	assert.Equal(t, "0", booked)
+}
@@ -0,0 +1,478 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hexagone/object-service/src/database"
+	"hexagone/object-service/src/media"
+	"hexagone/object-service/src/models"
+	"hexagone/object-service/src/storage"
+	"hexagone/shared/logging"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxPhotoSize       = 10 << 20 // Per uploaded file
+	maxPhotosPerUpload = 10
+	maxPhotosPerObject = 30
+	signedURLTTL       = 15 * time.Minute
+)
+
+var errPhotoTooLarge = errors.New("photo exceeds the maximum size")
+
+func photosKey(objectID string) string {
+	return "object:" + objectID + ":photos"
+}
+
+// photoBlobKey is where a rendition is kept in the media store
+func photoBlobKey(objectID, photoID string, rendition models.PhotoRendition) string {
+	ext := "png"
+	if rendition.ContentType == "image/jpeg" {
+		ext = "jpg"
+	}
+	return fmt.Sprintf("objects/%s/photos/%s/%s.%s", objectID, photoID, rendition.Name, ext)
+}
+
+// withSignedURLs returns a copy of photos whose renditions carry a direct,
+// time-limited link to the media store. Clients fall back to URL, which is
+// served by this service, when signing fails.
+func withSignedURLs(c *gin.Context, photos []models.Photo) []models.Photo {
+	signed := make([]models.Photo, len(photos))
+	for i, photo := range photos {
+		photo.Renditions = append([]models.PhotoRendition{}, photo.Renditions...)
+		for j, rendition := range photo.Renditions {
+			url, err := storage.Media.SignedURL(requestCtx(c), photoBlobKey(photo.ObjectID, photo.ID, rendition), signedURLTTL)
+			if err != nil {
+				logging.RequestLog(c).WithFields(logrus.Fields{
+					"photoID": photo.ID,
+					"error":   err.Error(),
+				}).Warn("Failed to sign photo URL")
+				continue
+			}
+			photo.Renditions[j].SignedURL = url
+		}
+		signed[i] = photo
+	}
+	return signed
+}
+
+// loadPhotos returns the photos of an object in display order
+func loadPhotos(ctx context.Context, objectID string) ([]models.Photo, error) {
+	photos := []models.Photo{}
+
+	val, err := database.RDB.Get(ctx, photosKey(objectID)).Result()
+	if err == redis.Nil {
+		return photos, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal([]byte(val), &photos)
+	for i := range photos {
+		photos[i].Position = i
+	}
+	return photos, err
+}
+
+// photoChange returns the new photo list of an object from the current one
+type photoChange func(photos []models.Photo) ([]models.Photo, error)
+
+// updatePhotos reads the photos of an object in a transaction watching them
+// and the object, lets change rearrange them and stores the result, making
+// sure exactly one is primary. It starts over when either is written in the
+// meantime, and fails like updateObject.
+func updatePhotos(ctx context.Context, objectID string, change photoChange) ([]models.Photo, error) {
+	if !isObjectKey(objectID) {
+		return nil, redis.Nil
+	}
+
+	var photos []models.Photo
+	transaction := func(tx *redis.Tx) error {
+		if err := tx.Get(ctx, objectID).Err(); err != nil {
+			return err
+		}
+
+		photos = []models.Photo{}
+		val, err := tx.Get(ctx, photosKey(objectID)).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if err == nil {
+			if err := json.Unmarshal([]byte(val), &photos); err != nil {
+				return err
+			}
+		}
+
+		if photos, err = change(photos); err != nil {
+			return err
+		}
+		primary := -1
+		for i := range photos {
+			photos[i].Position = i
+			if photos[i].IsPrimary && primary == -1 {
+				primary = i
+			} else {
+				photos[i].IsPrimary = false
+			}
+		}
+		if primary == -1 && len(photos) > 0 {
+			photos[0].IsPrimary = true
+		}
+
+		data, err := json.Marshal(photos)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, photosKey(objectID), data, 0)
+			return nil
+		})
+		return err
+	}
+
+	var err error
+	for attempt := 0; attempt < maxObjectAttempts; attempt++ {
+		if err = database.RDB.Watch(ctx, transaction, objectID, photosKey(objectID)); err != redis.TxFailedErr {
+			break
+		}
+	}
+	return photos, err
+}
+
+// storePhoto cleans an uploaded image and writes every rendition to the media store
+func storePhoto(c *gin.Context, objectID string, file *multipart.FileHeader) (models.Photo, error) {
+	photo := models.Photo{
+		ID:         uuid.New().String(),
+		ObjectID:   objectID,
+		UploadedBy: c.GetHeader("X-User-ID"),
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	if file.Size > maxPhotoSize {
+		return photo, errPhotoTooLarge
+	}
+	f, err := file.Open()
+	if err != nil {
+		return photo, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxPhotoSize+1))
+	if err != nil {
+		return photo, err
+	}
+	if len(data) > maxPhotoSize {
+		return photo, errPhotoTooLarge
+	}
+
+	renditions, err := media.Process(data)
+	if err != nil {
+		return photo, err
+	}
+
+	for _, encoded := range renditions {
+		rendition := models.PhotoRendition{
+			Name:        encoded.Name,
+			URL:         fmt.Sprintf("/objects/%s/photos/%s/%s", objectID, photo.ID, encoded.Name),
+			ContentType: encoded.ContentType,
+			Width:       encoded.Width,
+			Height:      encoded.Height,
+			Size:        len(encoded.Data),
+		}
+		key := photoBlobKey(objectID, photo.ID, rendition)
+		if err := storage.Media.Put(requestCtx(c), key, bytes.NewReader(encoded.Data), encoded.ContentType); err != nil {
+			deletePhotoBlobs(requestCtx(c), photo)
+			return photo, err
+		}
+		photo.Renditions = append(photo.Renditions, rendition)
+	}
+	return photo, nil
+}
+
+func deletePhotoBlobs(ctx context.Context, photo models.Photo) {
+	for _, rendition := range photo.Renditions {
+		key := photoBlobKey(photo.ObjectID, photo.ID, rendition)
+		if err := storage.Media.Delete(ctx, key); err != nil {
+			logging.LogFrom(ctx).WithFields(logrus.Fields{
+				"photoID": photo.ID,
+				"key":     key,
+				"error":   err.Error(),
+			}).Warn("Failed to delete photo blob")
+		}
+	}
+}
+
+// uploadErrorStatus maps a failed upload to the HTTP status reported to the client
+func uploadErrorStatus(err error) int {
+	var maxBytes *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytes), errors.Is(err, errPhotoTooLarge), errors.Is(err, media.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, media.ErrUnsupportedType):
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusBadRequest
+}
+
+// UploadObjectPhotos accepts one or more images as multipart "photos" fields,
+// strips their metadata and stores them with their thumbnails
+func UploadObjectPhotos(c *gin.Context) {
+	objectID := c.Param("id")
+
+	if _, err := loadObject(requestCtx(c), objectID); err != nil {
+		logging.RequestLog(c).WithField("objectID", objectID).Warn("Object not found")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPhotoSize*maxPhotosPerUpload+(1<<20))
+	form, err := c.MultipartForm()
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Warn("Failed to parse photo upload")
+		c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer form.RemoveAll()
+
+	files := append(form.File["photos"], form.File["photo"]...)
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No photo in the \"photos\" form field"})
+		return
+	}
+	if len(files) > maxPhotosPerUpload {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d photos per upload", maxPhotosPerUpload)})
+		return
+	}
+
+	photos, err := loadPhotos(requestCtx(c), objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load photos"})
+		return
+	}
+	if len(photos)+len(files) > maxPhotosPerObject {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("An object can have at most %d photos", maxPhotosPerObject)})
+		return
+	}
+
+	uploaded := []models.Photo{}
+	for _, file := range files {
+		photo, err := storePhoto(c, objectID, file)
+		if err != nil {
+			logging.RequestLog(c).WithFields(logrus.Fields{
+				"objectID": objectID,
+				"filename": file.Filename,
+				"error":    err.Error(),
+			}).Warn("Rejected photo upload")
+			for _, done := range uploaded {
+				deletePhotoBlobs(requestCtx(c), done)
+			}
+			c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error(), "filename": file.Filename})
+			return
+		}
+		uploaded = append(uploaded, photo)
+	}
+
+	// Photos may have been added while these were processed, so the limit is
+	// checked again against the stored list
+	photos, err = updatePhotos(requestCtx(c), objectID, func(photos []models.Photo) ([]models.Photo, error) {
+		if len(photos)+len(uploaded) > maxPhotosPerObject {
+			return nil, errRefused{http.StatusConflict, fmt.Sprintf("An object can have at most %d photos", maxPhotosPerObject)}
+		}
+		return append(photos, uploaded...), nil
+	})
+	if err != nil {
+		for _, done := range uploaded {
+			deletePhotoBlobs(requestCtx(c), done)
+		}
+		respondUpdateError(c, objectID, err, "Failed to save photos")
+		return
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"objectID": objectID,
+		"count":    len(uploaded),
+	}).Info("Photos uploaded")
+
+	c.JSON(http.StatusOK, gin.H{"data": withSignedURLs(c, photos)})
+}
+
+// ListObjectPhotos returns the photos of an object in display order
+func ListObjectPhotos(c *gin.Context) {
+	objectID := c.Param("id")
+
+	photos, err := loadPhotos(requestCtx(c), objectID)
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to load photos")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load photos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": withSignedURLs(c, photos)})
+}
+
+// GetPhotoRendition streams one size of a photo
+func GetPhotoRendition(c *gin.Context) {
+	objectID := c.Param("id")
+	photoID := c.Param("photoId")
+	name := c.Param("rendition")
+
+	photos, err := loadPhotos(requestCtx(c), objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load photos"})
+		return
+	}
+
+	for _, photo := range photos {
+		if photo.ID != photoID {
+			continue
+		}
+		for _, rendition := range photo.Renditions {
+			if rendition.Name != name {
+				continue
+			}
+
+			blob, err := storage.Media.Get(requestCtx(c), photoBlobKey(objectID, photoID, rendition))
+			if err != nil {
+				logging.RequestLog(c).WithFields(logrus.Fields{
+					"photoID":   photoID,
+					"rendition": name,
+					"error":     err.Error(),
+				}).Error("Failed to read photo blob")
+				c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
+				return
+			}
+			defer blob.Close()
+
+			c.DataFromReader(http.StatusOK, int64(rendition.Size), rendition.ContentType, blob, map[string]string{
+				"Cache-Control":          "public, max-age=86400, immutable",
+				"X-Content-Type-Options": "nosniff",
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
+}
+
+type ReorderPhotosInput struct {
+	PhotoIDs []string `json:"photoIds" binding:"required"`
+}
+
+// ReorderObjectPhotos sets the display order of an object's photos
+func ReorderObjectPhotos(c *gin.Context) {
+	objectID := c.Param("id")
+	var input ReorderPhotosInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ordered, err := updatePhotos(requestCtx(c), objectID, func(photos []models.Photo) ([]models.Photo, error) {
+		byID := map[string]models.Photo{}
+		for _, photo := range photos {
+			byID[photo.ID] = photo
+		}
+
+		if len(input.PhotoIDs) != len(photos) {
+			return nil, errRefused{http.StatusBadRequest, "photoIds must list every photo of the object exactly once"}
+		}
+		ordered := []models.Photo{}
+		for _, photoID := range input.PhotoIDs {
+			photo, ok := byID[photoID]
+			if !ok {
+				return nil, errRefused{http.StatusBadRequest, "photoIds must list every photo of the object exactly once"}
+			}
+			delete(byID, photoID)
+			ordered = append(ordered, photo)
+		}
+		return ordered, nil
+	})
+	if err != nil {
+		respondUpdateError(c, objectID, err, "Failed to save photos")
+		return
+	}
+
+	logging.RequestLog(c).WithField("objectID", objectID).Info("Photos reordered")
+	c.JSON(http.StatusOK, gin.H{"data": ordered})
+}
+
+// SetPrimaryPhoto chooses the photo shown for an object in listings
+func SetPrimaryPhoto(c *gin.Context) {
+	objectID := c.Param("id")
+	photoID := c.Param("photoId")
+
+	photos, err := updatePhotos(requestCtx(c), objectID, func(photos []models.Photo) ([]models.Photo, error) {
+		found := false
+		for i := range photos {
+			photos[i].IsPrimary = photos[i].ID == photoID
+			found = found || photos[i].IsPrimary
+		}
+		if !found {
+			return nil, errRefused{http.StatusNotFound, "Photo not found"}
+		}
+		return photos, nil
+	})
+	if err != nil {
+		respondUpdateError(c, objectID, err, "Failed to save photos")
+		return
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"objectID": objectID,
+		"photoID":  photoID,
+	}).Info("Primary photo updated")
+	c.JSON(http.StatusOK, gin.H{"data": photos})
+}
+
+// DeleteObjectPhoto removes a photo and all of its renditions
+func DeleteObjectPhoto(c *gin.Context) {
+	objectID := c.Param("id")
+	photoID := c.Param("photoId")
+
+	var deleted *models.Photo
+	_, err := updatePhotos(requestCtx(c), objectID, func(photos []models.Photo) ([]models.Photo, error) {
+		deleted = nil
+		remaining := []models.Photo{}
+		for i := range photos {
+			if photos[i].ID == photoID {
+				deleted = &photos[i]
+				continue
+			}
+			remaining = append(remaining, photos[i])
+		}
+		if deleted == nil {
+			return nil, errRefused{http.StatusNotFound, "Photo not found"}
+		}
+		return remaining, nil
+	})
+	if err != nil {
+		respondUpdateError(c, objectID, err, "Failed to save photos")
+		return
+	}
+	deletePhotoBlobs(requestCtx(c), *deleted)
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"objectID": objectID,
+		"photoID":  photoID,
+	}).Info("Photo deleted")
+	c.JSON(http.StatusOK, gin.H{"message": "Photo deleted successfully"})
+}
@@ -0,0 +1,136 @@
+package services_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"hexagone/object-service/src/models"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPNG(w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, 0, color.RGBA{255, 0, 0, 255})
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func uploadPhotos(objectID string, files map[string][]byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, data := range files {
+		part, _ := writer.CreateFormFile("photos", name)
+		part.Write(data)
+	}
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/objects/"+objectID+"/photos", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestObjectPhotos(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	object := createTestObject(t, map[string]interface{}{"name": "Commode", "type": "furniture", "room_id": "room1"})
+
+	tests := []struct {
+		name         string
+		objectID     string
+		files        map[string][]byte
+		expectedCode int
+	}{
+		{"Unknown Object", "nonexistent", map[string][]byte{"a.png": testPNG(10, 10)}, http.StatusNotFound},
+		{"Not An Image", object.ID, map[string][]byte{"a.png": []byte("<html>not an image</html>")}, http.StatusUnsupportedMediaType},
+		{"No File", object.ID, map[string][]byte{}, http.StatusBadRequest},
+		{"Valid Upload", object.ID, map[string][]byte{"front.png": testPNG(600, 300)}, http.StatusOK},
+		{"Second Upload", object.ID, map[string][]byte{"back.png": testPNG(300, 600)}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := uploadPhotos(tt.objectID, tt.files)
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+
+	w := performRequest("GET", "/objects/"+object.ID+"/photos", nil, "")
+	var response map[string][]models.Photo
+	json.Unmarshal(w.Body.Bytes(), &response)
+	photos := response["data"]
+	if !assert.Len(t, photos, 2) {
+		return
+	}
+	assert.True(t, photos[0].IsPrimary, "the first photo becomes primary")
+	assert.False(t, photos[1].IsPrimary)
+	assert.Len(t, photos[0].Renditions, 4)
+
+	// Thumbnails are served from the media store
+	for _, rendition := range photos[0].Renditions {
+		assert.Contains(t, rendition.SignedURL, "/media/objects/"+object.ID+"/photos/")
+		w = performRequest("GET", rendition.URL, nil, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		decoded, _, err := image.Decode(bytes.NewReader(w.Body.Bytes()))
+		if assert.NoError(t, err) {
+			assert.Equal(t, rendition.Width, decoded.Bounds().Dx())
+		}
+	}
+	w = performRequest("GET", "/objects/"+object.ID+"/photos/"+photos[0].ID+"/huge", nil, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performRequest("PUT", "/objects/"+object.ID+"/photos/order", map[string]interface{}{
+		"photoIds": []string{photos[1].ID},
+	}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code, "every photo must be listed")
+
+	w = performRequest("PUT", "/objects/"+object.ID+"/photos/order", map[string]interface{}{
+		"photoIds": []string{photos[1].ID, photos[0].ID},
+	}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest("PATCH", "/objects/"+object.ID+"/photos/"+photos[1].ID+"/primary", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest("PATCH", "/objects/"+object.ID+"/photos/unknown/primary", nil, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest("PATCH", "/objects/nonexistent/photos/"+photos[1].ID+"/primary", nil, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.False(t, mr.Exists("object:nonexistent:photos"), "no photo list is written for a missing object")
+
+	w = performRequest("DELETE", "/objects/"+object.ID+"/photos/"+photos[1].ID, nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest("DELETE", "/objects/"+object.ID+"/photos/"+photos[1].ID, nil, "")
+	assert.Equal(t, http.StatusNotFound, w.Code, "a photo is deleted once")
+
+	w = performRequest("GET", "/objects/"+object.ID+"/photos", nil, "")
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if assert.Len(t, response["data"], 1) {
+		assert.Equal(t, photos[0].ID, response["data"][0].ID)
+		assert.True(t, response["data"][0].IsPrimary, "the remaining photo takes over as primary")
+	}
+
+	w = performRequest("GET", photos[1].Renditions[0].URL, nil, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Photo records must not leak into object listings
+	w = performRequest("GET", "/objects", nil, "")
+	var objects map[string][]models.Object
+	json.Unmarshal(w.Body.Bytes(), &objects)
+	assert.Len(t, objects["data"], 1)
+}
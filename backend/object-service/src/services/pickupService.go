@@ -0,0 +1,697 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hexagone/object-service/src/database"
+	"hexagone/object-service/src/models"
+	"hexagone/shared/logging"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+func pickupSlotKey(slotID string) string {
+	return "pickup:slot:" + slotID
+}
+
+func slotBookedKey(slotID string) string {
+	return "pickup:slot:" + slotID + ":booked"
+}
+
+func slotBookingsKey(slotID string) string {
+	return "pickup:slot:" + slotID + ":bookings"
+}
+
+func pickupBookingKey(bookingID string) string {
+	return "pickup:booking:" + bookingID
+}
+
+// homeSlotsKey is a sorted set of a home's slot IDs, scored by start time
+func homeSlotsKey(homeID string) string {
+	return "home:" + homeID + ":pickup-slots"
+}
+
+// loadPickupSlot fetches a slot along with its current number of bookings
+func loadPickupSlot(ctx context.Context, slotID string) (models.PickupSlot, error) {
+	var slot models.PickupSlot
+
+	val, err := database.RDB.Get(ctx, pickupSlotKey(slotID)).Result()
+	if err != nil {
+		return slot, err
+	}
+	if err := json.Unmarshal([]byte(val), &slot); err != nil {
+		return slot, err
+	}
+
+	booked, err := database.RDB.Get(ctx, slotBookedKey(slotID)).Int()
+	if err != nil && err != redis.Nil {
+		return slot, err
+	}
+	slot.Booked = booked
+	return slot, nil
+}
+
+func loadPickupBooking(ctx context.Context, bookingID string) (models.PickupBooking, error) {
+	var booking models.PickupBooking
+
+	val, err := database.RDB.Get(ctx, pickupBookingKey(bookingID)).Result()
+	if err != nil {
+		return booking, err
+	}
+
+	err = json.Unmarshal([]byte(val), &booking)
+	return booking, err
+}
+
+// loadHomePickupSlots returns the slots of a home starting in [from, to), ordered by start time
+func loadHomePickupSlots(ctx context.Context, homeID string, from, to time.Time) ([]models.PickupSlot, error) {
+	slotIDs, err := database.RDB.ZRangeByScore(ctx, homeSlotsKey(homeID), &redis.ZRangeBy{
+		Min: strconv.FormatInt(from.Unix(), 10),
+		Max: "(" + strconv.FormatInt(to.Unix(), 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	slots := []models.PickupSlot{}
+	for _, slotID := range slotIDs {
+		slot, err := loadPickupSlot(ctx, slotID)
+		if err != nil {
+			logging.LogFrom(ctx).WithField("slotID", slotID).Warn("Failed to load pickup slot, skipping")
+			continue
+		}
+		slots = append(slots, slot)
+	}
+	return slots, nil
+}
+
+// releaseBookingCapacity frees the place a booking held in its slot and deletes it
+func releaseBookingCapacity(ctx context.Context, booking models.PickupBooking) error {
+	pipe := database.RDB.TxPipeline()
+	queueBookingRelease(ctx, pipe, booking)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// queueBookingRelease frees the place of a booking and deletes it in a transaction
+func queueBookingRelease(ctx context.Context, pipe redis.Pipeliner, booking models.PickupBooking) {
+	pipe.Decr(ctx, slotBookedKey(booking.SlotID))
+	pipe.SRem(ctx, slotBookingsKey(booking.SlotID), booking.ID)
+	pipe.Del(ctx, pickupBookingKey(booking.ID))
+}
+
+// bookingChanges are the pickup bookings objects are detached from during a
+// transaction, by ID. They are only written by queue, in the transaction
+// itself, so that a retried transaction starts again from the stored bookings.
+type bookingChanges map[string]*models.PickupBooking
+
+// detach removes an object from its pickup booking. The booking and its slot
+// are watched, so that the transaction fails if they change in the meantime.
+// The caller saves the object.
+func (changes bookingChanges) detach(ctx context.Context, tx *redis.Tx, object *models.Object) error {
+	bookingID := object.PickupBookingID
+	if bookingID == "" {
+		return nil
+	}
+	object.PickupBookingID = ""
+
+	booking, ok := changes[bookingID]
+	if !ok {
+		if err := tx.Watch(ctx, pickupBookingKey(bookingID)).Err(); err != nil {
+			return err
+		}
+		loaded, err := loadPickupBooking(ctx, bookingID)
+		if err == redis.Nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Watch(ctx, slotBookedKey(loaded.SlotID), slotBookingsKey(loaded.SlotID)).Err(); err != nil {
+			return err
+		}
+		booking = &loaded
+		changes[bookingID] = booking
+	}
+
+	remaining := []string{}
+	for _, id := range booking.ObjectIDs {
+		if id != object.ID {
+			remaining = append(remaining, id)
+		}
+	}
+	booking.ObjectIDs = remaining
+	return nil
+}
+
+// queue writes the changed bookings in a transaction, cancelling those left
+// with nothing to collect
+func (changes bookingChanges) queue(ctx context.Context, pipe redis.Pipeliner) error {
+	for _, booking := range changes {
+		if len(booking.ObjectIDs) == 0 {
+			queueBookingRelease(ctx, pipe, *booking)
+			continue
+		}
+		data, err := json.Marshal(booking)
+		if err != nil {
+			return err
+		}
+		pipe.Set(ctx, pickupBookingKey(booking.ID), data, 0)
+	}
+	return nil
+}
+
+// markCollected moves an approved object to picked up and records the handover
+func markCollected(object *models.Object, collectedBy, handedOverBy string) error {
+	from := object.CurrentStatus()
+	if !from.CanTransition(models.StatusPickedUp) {
+		return fmt.Errorf("object %s cannot be collected while %s", object.ID, describeStatus(from))
+	}
+
+	now := time.Now().UTC()
+	object.ReservationStatus = models.StatusPickedUp
+	object.CollectedBy = collectedBy
+	object.HandedOverBy = handedOverBy
+	object.CollectedAt = &now
+	return nil
+}
+
+type CreatePickupSlotInput struct {
+	Start    time.Time `json:"start" binding:"required"`
+	End      time.Time `json:"end" binding:"required"`
+	Capacity int       `json:"capacity" binding:"required,min=1"`
+}
+
+// CreatePickupSlot opens a pickup time range for a home
+func CreatePickupSlot(c *gin.Context) {
+	homeID := c.Param("id")
+	var input CreatePickupSlotInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"homeID": homeID,
+			"error":  err.Error(),
+		}).Error("Failed to bind input for pickup slot")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !input.End.After(input.Start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end must be after start"})
+		return
+	}
+
+	slot := models.PickupSlot{
+		ID:       uuid.New().String(),
+		HomeID:   homeID,
+		Start:    input.Start.UTC(),
+		End:      input.End.UTC(),
+		Capacity: input.Capacity,
+	}
+
+	data, err := json.Marshal(slot)
+	if err != nil {
+		logging.RequestLog(c).WithField("homeID", homeID).Error("Failed to marshal pickup slot")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pickup slot"})
+		return
+	}
+
+	if err := database.RDB.Set(requestCtx(c), pickupSlotKey(slot.ID), data, 0).Err(); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"homeID": homeID,
+			"error":  err.Error(),
+		}).Error("Failed to store pickup slot")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pickup slot"})
+		return
+	}
+	database.RDB.ZAdd(requestCtx(c), homeSlotsKey(homeID), redis.Z{Score: float64(slot.Start.Unix()), Member: slot.ID})
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"homeID":   homeID,
+		"slotID":   slot.ID,
+		"start":    slot.Start,
+		"capacity": slot.Capacity,
+	}).Info("Pickup slot created")
+
+	c.JSON(http.StatusOK, gin.H{"data": slot})
+}
+
+// ListPickupSlots lists the pickup slots of a home with their remaining capacity
+func ListPickupSlots(c *gin.Context) {
+	homeID := c.Param("id")
+
+	slots, err := loadHomePickupSlots(requestCtx(c), homeID, time.Unix(0, 0), time.Unix(1<<40, 0))
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"homeID": homeID,
+			"error":  err.Error(),
+		}).Error("Failed to fetch pickup slots")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pickup slots"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": slots})
+}
+
+type BookPickupSlotInput struct {
+	ObjectIDs []string `json:"objectIds" binding:"required,min=1"`
+}
+
+// errBookingRefused is an object that cannot be booked for pickup, with the
+// status code to answer
+type errBookingRefused struct {
+	code     int
+	message  string
+	objectID string
+}
+
+func (e errBookingRefused) Error() string { return e.message }
+
+// uniqueIDs returns ids without repetitions, keeping their first order
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// bookObjects stores a booking, takes a place in its slot and links its
+// objects to it in one transaction watching the objects and the slot's
+// count, so that no object is linked after it changed hands and no slot is
+// overfilled
+func bookObjects(ctx context.Context, slot models.PickupSlot, booking models.PickupBooking) error {
+	transaction := func(tx *redis.Tx) error {
+		booked, err := tx.Get(ctx, slotBookedKey(slot.ID)).Int()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if booked >= slot.Capacity {
+			return errBookingRefused{http.StatusConflict, "Pickup slot is full", ""}
+		}
+
+		objects := make([]models.Object, 0, len(booking.ObjectIDs))
+		for _, objectID := range booking.ObjectIDs {
+			if !isObjectKey(objectID) {
+				return errBookingRefused{http.StatusNotFound, "Object not found", objectID}
+			}
+			val, err := tx.Get(ctx, objectID).Result()
+			if err == redis.Nil {
+				return errBookingRefused{http.StatusNotFound, "Object not found", objectID}
+			}
+			if err != nil {
+				return err
+			}
+			var object models.Object
+			if err := json.Unmarshal([]byte(val), &object); err != nil {
+				return err
+			}
+
+			if object.ReservedBy != booking.UserID || object.CurrentStatus() != models.StatusApproved {
+				return errBookingRefused{http.StatusConflict, "Object is not an approved reservation of this user", objectID}
+			}
+			if object.HomeID != slot.HomeID {
+				return errBookingRefused{http.StatusBadRequest, "Object does not belong to the slot's home", objectID}
+			}
+			if object.PickupBookingID != "" {
+				return errBookingRefused{http.StatusConflict, "Object is already scheduled for pickup", objectID}
+			}
+			object.PickupBookingID = booking.ID
+			objects = append(objects, object)
+		}
+
+		data, err := json.Marshal(booking)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Incr(ctx, slotBookedKey(slot.ID))
+			pipe.Set(ctx, pickupBookingKey(booking.ID), data, 0)
+			pipe.SAdd(ctx, slotBookingsKey(slot.ID), booking.ID)
+			for _, object := range objects {
+				data, err := json.Marshal(object)
+				if err != nil {
+					return err
+				}
+				pipe.Set(ctx, object.ID, data, 0)
+			}
+			return nil
+		})
+		return err
+	}
+
+	keys := append([]string{slotBookedKey(slot.ID)}, booking.ObjectIDs...)
+	var err error
+	for attempt := 0; attempt < maxObjectAttempts; attempt++ {
+		if err = database.RDB.Watch(ctx, transaction, keys...); err != redis.TxFailedErr {
+			break
+		}
+	}
+	return err
+}
+
+// BookPickupSlot schedules the collection of some of the caller's reserved objects
+func BookPickupSlot(c *gin.Context) {
+	slotID := c.Param("id")
+	userID := c.GetHeader("X-User-ID")
+	var input BookPickupSlotInput
+
+	if userID == "" {
+		logging.RequestLog(c).WithField("slotID", slotID).Warn("No user ID found in header")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"slotID": slotID,
+			"error":  err.Error(),
+		}).Error("Failed to bind input for pickup booking")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slot, err := loadPickupSlot(requestCtx(c), slotID)
+	if err == redis.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pickup slot not found"})
+		return
+	}
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"slotID": slotID,
+			"error":  err.Error(),
+		}).Error("Failed to load pickup slot")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load pickup slot"})
+		return
+	}
+
+	booking := models.PickupBooking{
+		ID:        uuid.New().String(),
+		SlotID:    slotID,
+		HomeID:    slot.HomeID,
+		UserID:    userID,
+		ObjectIDs: uniqueIDs(input.ObjectIDs),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	err = bookObjects(requestCtx(c), slot, booking)
+	var refused errBookingRefused
+	if errors.As(err, &refused) {
+		response := gin.H{"error": refused.message}
+		if refused.objectID != "" {
+			response["objectId"] = refused.objectID
+		}
+		c.JSON(refused.code, response)
+		return
+	}
+	if err == redis.TxFailedErr {
+		c.JSON(http.StatusConflict, gin.H{"error": "The objects or the slot kept being modified, try again"})
+		return
+	}
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"slotID": slotID,
+			"error":  err.Error(),
+		}).Error("Failed to store pickup booking")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to book pickup slot"})
+		return
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"slotID":    slotID,
+		"bookingID": booking.ID,
+		"userID":    booking.UserID,
+		"objects":   len(booking.ObjectIDs),
+	}).Info("Pickup slot booked")
+
+	c.JSON(http.StatusOK, gin.H{"data": booking})
+}
+
+// CancelPickupBooking frees a booking's place in its slot
+func CancelPickupBooking(c *gin.Context) {
+	bookingID := c.Param("id")
+	userID := c.GetHeader("X-User-ID")
+
+	booking, err := loadPickupBooking(requestCtx(c), bookingID)
+	if err == redis.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pickup booking not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load pickup booking"})
+		return
+	}
+
+	if userID == "" || userID != booking.UserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the user who booked can cancel this booking"})
+		return
+	}
+
+	for _, objectID := range booking.ObjectIDs {
+		object, err := loadObject(requestCtx(c), objectID)
+		if err != nil || object.PickupBookingID != bookingID {
+			continue
+		}
+		object.PickupBookingID = ""
+		if err := saveObject(requestCtx(c), object); err != nil {
+			logging.RequestLog(c).WithField("objectID", objectID).Error("Failed to unlink object from pickup booking")
+		}
+	}
+
+	if err := releaseBookingCapacity(requestCtx(c), booking); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"bookingID": bookingID,
+			"error":     err.Error(),
+		}).Error("Failed to cancel pickup booking")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel pickup booking"})
+		return
+	}
+
+	logging.RequestLog(c).WithField("bookingID", bookingID).Info("Pickup booking cancelled")
+	c.JSON(http.StatusOK, gin.H{"message": "Pickup booking cancelled"})
+}
+
+type HandoverInput struct {
+	ObjectIDs   []string `json:"objectIds"`   // Defaults to every object in the booking
+	CollectedBy string   `json:"collectedBy"` // Defaults to the user who booked
+}
+
+// ConfirmHandover marks the objects of a booking as collected, recording who
+// took them, who handed them over and when
+func ConfirmHandover(c *gin.Context) {
+	bookingID := c.Param("id")
+	handedOverBy := c.GetHeader("X-User-ID")
+
+	var input HandoverInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if handedOverBy == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	booking, err := loadPickupBooking(requestCtx(c), bookingID)
+	if err == redis.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pickup booking not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load pickup booking"})
+		return
+	}
+
+	settings, err := loadHomeSettings(requestCtx(c), booking.HomeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load home settings"})
+		return
+	}
+	if settings.ExecutorID == "" || settings.ExecutorID != handedOverBy {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the executor of this home can confirm handovers"})
+		return
+	}
+
+	objectIDs := input.ObjectIDs
+	if len(objectIDs) == 0 {
+		objectIDs = booking.ObjectIDs
+	}
+	collectedBy := input.CollectedBy
+	if collectedBy == "" {
+		collectedBy = booking.UserID
+	}
+
+	// Validate every object before touching any of them
+	objects := []models.Object{}
+	for _, objectID := range objectIDs {
+		object, err := loadObject(requestCtx(c), objectID)
+		if err != nil || object.PickupBookingID != bookingID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Object is not part of this booking", "objectId": objectID})
+			return
+		}
+		if err := markCollected(&object, collectedBy, handedOverBy); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "objectId": objectID})
+			return
+		}
+		objects = append(objects, object)
+	}
+
+	for _, object := range objects {
+		if err := saveObject(requestCtx(c), object); err != nil {
+			logging.RequestLog(c).WithFields(logrus.Fields{
+				"objectID": object.ID,
+				"error":    err.Error(),
+			}).Error("Failed to record handover")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record handover", "objectId": object.ID})
+			return
+		}
+		recordHistory(requestCtx(c), object.ID, models.HistoryEntry{
+			Action:     "handover",
+			From:       models.StatusApproved,
+			To:         models.StatusPickedUp,
+			UserID:     handedOverBy,
+			ClaimantID: object.ReservedBy,
+			Comment:    "Collected by " + collectedBy,
+		})
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"bookingID":    bookingID,
+		"collectedBy":  collectedBy,
+		"handedOverBy": handedOverBy,
+		"objects":      len(objects),
+	}).Info("Handover confirmed")
+
+	c.JSON(http.StatusOK, gin.H{"data": objects})
+}
+
+// ScheduleEntry is one booked object in a day's pickup schedule
+type ScheduleEntry struct {
+	SlotID      string     `json:"slotId"`
+	SlotStart   time.Time  `json:"slotStart"`
+	SlotEnd     time.Time  `json:"slotEnd"`
+	BookingID   string     `json:"bookingId"`
+	UserID      string     `json:"userId"`
+	ObjectID    string     `json:"objectId"`
+	ObjectName  string     `json:"objectName"`
+	RoomID      string     `json:"room_id"`
+	Status      string     `json:"status"`
+	CollectedBy string     `json:"collectedBy,omitempty"`
+	CollectedAt *time.Time `json:"collectedAt,omitempty"`
+}
+
+// ExportPickupSchedule lists everything booked for collection from a home on
+// a given day (?date=YYYY-MM-DD, today by default) as JSON or CSV (?format=csv)
+func ExportPickupSchedule(c *gin.Context) {
+	homeID := c.Param("id")
+	date := c.DefaultQuery("date", time.Now().UTC().Format("2006-01-02"))
+
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date must be formatted as YYYY-MM-DD"})
+		return
+	}
+
+	slots, err := loadHomePickupSlots(requestCtx(c), homeID, day, day.Add(24*time.Hour))
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"homeID": homeID,
+			"error":  err.Error(),
+		}).Error("Failed to fetch pickup slots")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pickup schedule"})
+		return
+	}
+
+	entries := []ScheduleEntry{}
+	for _, slot := range slots {
+		bookingIDs, err := database.RDB.SMembers(requestCtx(c), slotBookingsKey(slot.ID)).Result()
+		if err != nil {
+			logging.RequestLog(c).WithField("slotID", slot.ID).Warn("Failed to fetch slot bookings, skipping")
+			continue
+		}
+
+		bookings := []models.PickupBooking{}
+		for _, bookingID := range bookingIDs {
+			booking, err := loadPickupBooking(requestCtx(c), bookingID)
+			if err == nil {
+				bookings = append(bookings, booking)
+			}
+		}
+		sort.Slice(bookings, func(i, j int) bool { return bookings[i].CreatedAt.Before(bookings[j].CreatedAt) })
+
+		for _, booking := range bookings {
+			for _, objectID := range booking.ObjectIDs {
+				entry := ScheduleEntry{
+					SlotID:    slot.ID,
+					SlotStart: slot.Start,
+					SlotEnd:   slot.End,
+					BookingID: booking.ID,
+					UserID:    booking.UserID,
+					ObjectID:  objectID,
+				}
+				if object, err := loadObject(requestCtx(c), objectID); err == nil {
+					entry.ObjectName = object.Name
+					entry.RoomID = object.RoomID
+					entry.Status = string(object.CurrentStatus())
+					entry.CollectedBy = object.CollectedBy
+					entry.CollectedAt = object.CollectedAt
+				}
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"homeID":  homeID,
+		"date":    date,
+		"entries": len(entries),
+	}).Info("Pickup schedule exported")
+
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, gin.H{"data": entries})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=pickup-schedule-%s-%s.csv", homeID, date))
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"slot_start", "slot_end", "booking_id", "user_id", "object_id", "object_name", "room_id", "status", "collected_by", "collected_at"})
+	for _, entry := range entries {
+		collectedAt := ""
+		if entry.CollectedAt != nil {
+			collectedAt = entry.CollectedAt.Format(time.RFC3339)
+		}
+		writer.Write([]string{
+			entry.SlotStart.Format(time.RFC3339),
+			entry.SlotEnd.Format(time.RFC3339),
+			entry.BookingID,
+			entry.UserID,
+			entry.ObjectID,
+			entry.ObjectName,
+			entry.RoomID,
+			entry.Status,
+			entry.CollectedBy,
+			collectedAt,
+		})
+	}
+	writer.Flush()
+}
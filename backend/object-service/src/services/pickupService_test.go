@@ -0,0 +1,189 @@
+package services_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"hexagone/object-service/src/models"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func reserveTestObject(t *testing.T, name, homeID, userID string) models.Object {
+	object := createTestObject(t, map[string]interface{}{
+		"name": name, "type": "furniture", "room_id": roomInHome(homeID),
+	})
+	w := performRequest("PATCH", "/objects/"+object.ID+"/reserve", nil, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+	return object
+}
+
+func createTestSlot(t *testing.T, homeID string, capacity int) models.PickupSlot {
+	w := performRequest("POST", "/homes/"+homeID+"/pickup-slots", map[string]interface{}{
+		"start":    "2026-10-19T09:00:00Z",
+		"end":      "2026-10-19T12:00:00Z",
+		"capacity": capacity,
+	}, "admin")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]models.PickupSlot
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response["data"]
+}
+
+func TestPickupBookingAndHandover(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	slot := createTestSlot(t, "1", 1)
+	chair := reserveTestObject(t, "Chaise", "1", "heir1")
+	table := reserveTestObject(t, "Table", "1", "heir1")
+	lamp := reserveTestObject(t, "Lampe", "1", "heir2")
+	elsewhere := reserveTestObject(t, "Buffet", "2", "heir1")
+
+	tests := []struct {
+		name         string
+		userID       string
+		objectIDs    []string
+		expectedCode int
+	}{
+		{"Without User", "", []string{chair.ID}, http.StatusUnauthorized},
+		{"Object Of Another User", "heir1", []string{lamp.ID}, http.StatusConflict},
+		{"Object Of Another Home", "heir1", []string{elsewhere.ID}, http.StatusBadRequest},
+		{"Unknown Object", "heir1", []string{"nonexistent"}, http.StatusNotFound},
+		{"Valid Booking", "heir1", []string{chair.ID, table.ID, chair.ID}, http.StatusOK},
+		{"Slot Full", "heir2", []string{lamp.ID}, http.StatusConflict},
+	}
+
+	var booking models.PickupBooking
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := performRequest("POST", "/pickup-slots/"+slot.ID+"/bookings", map[string]interface{}{
+				"objectIds": tt.objectIDs,
+			}, tt.userID)
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedCode == http.StatusOK {
+				var response map[string]models.PickupBooking
+				json.Unmarshal(w.Body.Bytes(), &response)
+				booking = response["data"]
+				assert.Equal(t, []string{chair.ID, table.ID}, booking.ObjectIDs, "repeated objects are booked once")
+			}
+		})
+	}
+
+	assert.Equal(t, booking.ID, mustLoadObject(t, chair.ID).PickupBookingID)
+	assert.Equal(t, booking.ID, mustLoadObject(t, table.ID).PickupBookingID)
+
+	w := performRequest("GET", "/homes/1/pickup-slots", nil, "")
+	var slots map[string][]models.PickupSlot
+	json.Unmarshal(w.Body.Bytes(), &slots)
+	if assert.Len(t, slots["data"], 1) {
+		assert.Equal(t, 1, slots["data"][0].Booked)
+	}
+
+	w = performRequest("POST", "/pickup-bookings/"+booking.ID+"/handover", nil, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequest("POST", "/pickup-bookings/"+booking.ID+"/handover", map[string]interface{}{
+		"objectIds": []string{chair.ID},
+	}, "executor")
+	assert.Equal(t, http.StatusForbidden, w.Code, "a home without an executor accepts no handover")
+
+	performRequest("PUT", "/homes/1/settings", map[string]interface{}{"executorId": "executor"}, "")
+	w = performRequest("POST", "/pickup-bookings/"+booking.ID+"/handover", map[string]interface{}{
+		"objectIds": []string{chair.ID},
+	}, "heir1")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest("POST", "/pickup-bookings/"+booking.ID+"/handover", map[string]interface{}{
+		"objectIds": []string{chair.ID},
+	}, "executor")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var handedOver map[string][]models.Object
+	json.Unmarshal(w.Body.Bytes(), &handedOver)
+	if assert.Len(t, handedOver["data"], 1) {
+		collected := handedOver["data"][0]
+		assert.Equal(t, models.StatusPickedUp, collected.ReservationStatus)
+		assert.Equal(t, "heir1", collected.CollectedBy)
+		assert.Equal(t, "executor", collected.HandedOverBy)
+		assert.NotNil(t, collected.CollectedAt)
+	}
+
+	w = performRequest("POST", "/pickup-bookings/"+booking.ID+"/handover", map[string]interface{}{
+		"objectIds": []string{chair.ID},
+	}, "executor")
+	assert.Equal(t, http.StatusConflict, w.Code, "an object cannot be handed over twice")
+
+	w = performRequest("GET", "/homes/1/pickup-schedule?date=2026-10-19&format=csv", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+	rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3, "header plus one row per booked object")
+
+	w = performRequest("GET", "/homes/1/pickup-schedule?date=2026-10-20", nil, "")
+	var empty map[string][]interface{}
+	json.Unmarshal(w.Body.Bytes(), &empty)
+	assert.Empty(t, empty["data"])
+}
+
+func TestCancelPickupBooking(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	slot := createTestSlot(t, "1", 1)
+	chair := reserveTestObject(t, "Chaise", "1", "heir1")
+
+	w := performRequest("POST", "/pickup-slots/"+slot.ID+"/bookings", map[string]interface{}{
+		"objectIds": []string{chair.ID},
+	}, "heir1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]models.PickupBooking
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	w = performRequest("DELETE", "/pickup-bookings/"+response["data"].ID, nil, "heir2")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest("DELETE", "/pickup-bookings/"+response["data"].ID, nil, "heir1")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The place is free again and the object can be rebooked
+	w = performRequest("POST", "/pickup-slots/"+slot.ID+"/bookings", map[string]interface{}{
+		"objectIds": []string{chair.ID},
+	}, "heir1")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Unreserving the only booked object releases the booking too
+	w = performRequest("PATCH", "/objects/"+chair.ID+"/unreserve", nil, "heir1")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest("GET", "/homes/1/pickup-slots", nil, "")
+	var slots map[string][]models.PickupSlot
+	json.Unmarshal(w.Body.Bytes(), &slots)
+	assert.Equal(t, 0, slots["data"][0].Booked)
+}
+
+func TestCreatePickupSlotValidation(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	w := performRequest("POST", "/homes/1/pickup-slots", map[string]interface{}{
+		"start": "2026-10-19T12:00:00Z", "end": "2026-10-19T09:00:00Z", "capacity": 2,
+	}, "admin")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest("POST", "/homes/1/pickup-slots", map[string]interface{}{
+		"start": "2026-10-19T09:00:00Z", "end": "2026-10-19T12:00:00Z", "capacity": 0,
+	}, "admin")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,422 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hexagone/object-service/src/database"
+	"hexagone/object-service/src/models"
+	"hexagone/shared/logging"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+func homeSettingsKey(homeID string) string {
+	return "home:" + homeID + ":settings"
+}
+
+func historyKey(objectID string) string {
+	return "object:" + objectID + ":history"
+}
+
+// loadHomeSettings returns the reservation settings of a home, falling back
+// to direct reservations when the home has never been configured
+func loadHomeSettings(ctx context.Context, homeID string) (models.HomeSettings, error) {
+	settings := models.HomeSettings{HomeID: homeID}
+	if homeID == "" {
+		return settings, nil
+	}
+
+	val, err := database.RDB.Get(ctx, homeSettingsKey(homeID)).Result()
+	if err == redis.Nil {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, err
+	}
+
+	err = json.Unmarshal([]byte(val), &settings)
+	return settings, err
+}
+
+// loadObject fetches a single object from DragonflyDB. The keys that are not
+// objects, such as home settings, are not found.
+func loadObject(ctx context.Context, objectID string) (models.Object, error) {
+	var object models.Object
+	if !isObjectKey(objectID) {
+		return object, redis.Nil
+	}
+
+	val, err := database.RDB.Get(ctx, objectID).Result()
+	if err != nil {
+		return object, err
+	}
+
+	err = json.Unmarshal([]byte(val), &object)
+	return object, err
+}
+
+// saveObject writes an object back to DragonflyDB
+func saveObject(ctx context.Context, object models.Object) error {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+
+	return database.RDB.Set(ctx, object.ID, data, 0).Err()
+}
+
+// maxObjectAttempts bounds the retries when an object changes while it is
+// being updated
+const maxObjectAttempts = 3
+
+// errRefused is a change to an object refused for a reason given to the
+// client, with the status code to answer
+type errRefused struct {
+	code    int
+	message string
+}
+
+func (e errRefused) Error() string { return e.message }
+
+// objectChange changes an object read in a transaction. Pickup bookings it
+// detaches the object from are changed in bookings, queued with the object.
+type objectChange func(tx *redis.Tx, bookings bookingChanges, object *models.Object) error
+
+// updateObject reads an object in a transaction watching it, lets change
+// modify it and writes it back, starting over when the object is written in
+// the meantime. An error from change leaves the object as it was and is
+// returned as is. A key that is not an object's is not found (redis.Nil), and
+// redis.TxFailedErr is returned once the attempts run out.
+func updateObject(ctx context.Context, objectID string, change objectChange) (before, after models.Object, err error) {
+	if !isObjectKey(objectID) {
+		return before, after, redis.Nil
+	}
+
+	transaction := func(tx *redis.Tx) error {
+		val, err := tx.Get(ctx, objectID).Result()
+		if err != nil {
+			return err
+		}
+		before = models.Object{}
+		if err := json.Unmarshal([]byte(val), &before); err != nil {
+			return err
+		}
+
+		after = before
+		bookings := bookingChanges{}
+		if err := change(tx, bookings, &after); err != nil {
+			return err
+		}
+		data, err := json.Marshal(after)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, objectID, data, 0)
+			unindexObjectMetadata(ctx, pipe, before)
+			indexObjectMetadata(ctx, pipe, after)
+			return bookings.queue(ctx, pipe)
+		})
+		return err
+	}
+
+	for attempt := 0; attempt < maxObjectAttempts; attempt++ {
+		if err = database.RDB.Watch(ctx, transaction, objectID); err != redis.TxFailedErr {
+			break
+		}
+	}
+	return before, after, err
+}
+
+// respondUpdateError answers a failed updateObject: the object is not found,
+// kept changing, or the change was refused or failed
+func respondUpdateError(c *gin.Context, objectID string, err error, failure string) {
+	var refused errRefused
+	switch {
+	case err == redis.Nil:
+		logging.RequestLog(c).WithField("objectID", objectID).Warn("Object not found")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+	case err == redis.TxFailedErr:
+		c.JSON(http.StatusConflict, gin.H{"error": "The object kept being modified, try again"})
+	case errors.As(err, &refused):
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"reason":   refused.message,
+		}).Info("Object change refused")
+		c.JSON(refused.code, gin.H{"error": refused.message})
+	default:
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error(failure)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
+	}
+}
+
+// recordHistory appends an entry to an object's reservation history and to
+// the history of the user whose claim changed. A failure is logged but does
+// not undo the state change it describes.
+func recordHistory(ctx context.Context, objectID string, entry models.HistoryEntry) {
+	entry.ObjectID = objectID
+	entry.At = time.Now().UTC()
+
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = database.RDB.RPush(ctx, historyKey(objectID), data).Err()
+	}
+	if err == nil && entry.ClaimantID != "" {
+		err = database.RDB.RPush(ctx, userHistoryKey(entry.ClaimantID), data).Err()
+	}
+	if err != nil {
+		logging.LogFrom(ctx).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"action":   entry.Action,
+			"error":    err.Error(),
+		}).Error("Failed to record object history")
+	}
+}
+
+type UpdateHomeSettingsInput struct {
+	RequireApproval bool       `json:"requireApproval"`
+	ExecutorID      string     `json:"executorId"`
+	ClaimDeadline   *time.Time `json:"claimDeadline"`
+}
+
+// GetHomeSettings returns the reservation settings of a home
+func GetHomeSettings(c *gin.Context) {
+	homeID := c.Param("id")
+
+	settings, err := loadHomeSettings(requestCtx(c), homeID)
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"homeID": homeID,
+			"error":  err.Error(),
+		}).Error("Failed to load home settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load home settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": settings})
+}
+
+// UpdateHomeSettings switches a home between direct reservations and the
+// executor approval workflow, and sets the deadline for claiming its objects
+func UpdateHomeSettings(c *gin.Context) {
+	homeID := c.Param("id")
+	var input UpdateHomeSettingsInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"homeID": homeID,
+			"error":  err.Error(),
+		}).Error("Failed to bind input for home settings")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.RequireApproval && input.ExecutorID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "executorId is required when approval is enabled"})
+		return
+	}
+
+	settings := models.HomeSettings{
+		HomeID:          homeID,
+		RequireApproval: input.RequireApproval,
+		ExecutorID:      input.ExecutorID,
+		ClaimDeadline:   input.ClaimDeadline,
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		logging.RequestLog(c).WithField("homeID", homeID).Error("Failed to marshal home settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save home settings"})
+		return
+	}
+
+	if err := database.RDB.Set(requestCtx(c), homeSettingsKey(homeID), data, 0).Err(); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"homeID": homeID,
+			"error":  err.Error(),
+		}).Error("Failed to store home settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save home settings"})
+		return
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"homeID":          homeID,
+		"requireApproval": settings.RequireApproval,
+		"executorID":      settings.ExecutorID,
+		"claimDeadline":   settings.ClaimDeadline,
+	}).Info("Home settings updated")
+
+	c.JSON(http.StatusOK, gin.H{"data": settings})
+}
+
+type ReviewReservationInput struct {
+	Comment string `json:"comment"`
+}
+
+// ApproveReservation accepts a pending claim
+func ApproveReservation(c *gin.Context) {
+	transitionReservation(c, "approve", models.StatusApproved)
+}
+
+// RejectReservation refuses a pending claim and makes the object available again
+func RejectReservation(c *gin.Context) {
+	transitionReservation(c, "reject", models.StatusRejected)
+}
+
+// ConfirmPickup records that an approved object has been collected
+func ConfirmPickup(c *gin.Context) {
+	transitionReservation(c, "pickup", models.StatusPickedUp)
+}
+
+// transitionReservation moves an object's claim to a new state on behalf of
+// the executor of the object's home
+func transitionReservation(c *gin.Context, action string, to models.ReservationStatus) {
+	objectID := c.Param("id")
+	userID := c.GetHeader("X-User-ID")
+
+	var input ReviewReservationInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if userID == "" {
+		logging.RequestLog(c).WithField("objectID", objectID).Warn("No user ID found in header")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	object, err := loadObject(requestCtx(c), objectID)
+	if err == redis.Nil {
+		logging.RequestLog(c).WithField("objectID", objectID).Warn("Object not found")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+		return
+	}
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to load object")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process object data"})
+		return
+	}
+
+	settings, err := loadHomeSettings(requestCtx(c), object.HomeID)
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"homeID":   object.HomeID,
+			"error":    err.Error(),
+		}).Error("Failed to load home settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load home settings"})
+		return
+	}
+
+	if settings.ExecutorID == "" || settings.ExecutorID != userID {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"userID":   userID,
+		}).Warn("Non-executor attempted to review a reservation")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the executor of this home can review reservations"})
+		return
+	}
+
+	from := object.CurrentStatus()
+	if !from.CanTransition(to) {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"from":     from,
+			"to":       to,
+		}).Info("Invalid reservation transition")
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot " + action + " a reservation that is " + describeStatus(from)})
+		return
+	}
+
+	claimantID := object.ReservedBy
+	switch to {
+	case models.StatusPickedUp:
+		markCollected(&object, object.ReservedBy, userID)
+	case models.StatusRejected:
+		object.ReservationStatus = to
+		object.IsReserved = false
+		object.ReservedBy = ""
+	default:
+		object.ReservationStatus = to
+	}
+
+	if err := saveObject(requestCtx(c), object); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to update object in database")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update object in database"})
+		return
+	}
+
+	if to == models.StatusRejected {
+		unindexReservation(requestCtx(c), claimantID, object.ID)
+		reservationsReleased.WithLabelValues("rejected").Inc()
+	}
+	recordHistory(requestCtx(c), object.ID, models.HistoryEntry{
+		Action:     action,
+		From:       from,
+		To:         to,
+		UserID:     userID,
+		ClaimantID: claimantID,
+		Comment:    input.Comment,
+	})
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"objectID": object.ID,
+		"from":     from,
+		"to":       to,
+		"userID":   userID,
+	}).Info("Reservation state updated")
+
+	c.JSON(http.StatusOK, gin.H{"data": object})
+}
+
+func describeStatus(status models.ReservationStatus) string {
+	if status == models.StatusAvailable {
+		return "not reserved"
+	}
+	return string(status)
+}
+
+// GetObjectHistory returns every recorded reservation state change of an object
+func GetObjectHistory(c *gin.Context) {
+	objectID := c.Param("id")
+
+	vals, err := database.RDB.LRange(requestCtx(c), historyKey(objectID), 0, -1).Result()
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to fetch object history")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch object history"})
+		return
+	}
+
+	history := []models.HistoryEntry{}
+	for _, val := range vals {
+		var entry models.HistoryEntry
+		if err := json.Unmarshal([]byte(val), &entry); err != nil {
+			logging.RequestLog(c).WithField("objectID", objectID).Warn("Failed to unmarshal history entry, skipping")
+			continue
+		}
+		history = append(history, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": history})
+}
@@ -0,0 +1,222 @@
+package services_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"hexagone/object-service/src/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func performRequest(method, url string, body interface{}, userID string) *httptest.ResponseRecorder {
+	var buf *bytes.Buffer
+	if body != nil {
+		jsonInput, _ := json.Marshal(body)
+		buf = bytes.NewBuffer(jsonInput)
+	} else {
+		buf = bytes.NewBuffer(nil)
+	}
+
+	req := httptest.NewRequest(method, url, buf)
+	req.Header.Set("Content-Type", "application/json")
+	if userID != "" {
+		req.Header.Set("X-User-ID", userID)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func createTestObject(t *testing.T, input map[string]interface{}) models.Object {
+	w := performRequest("POST", "/objects", input, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]models.Object
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response["data"]
+}
+
+func TestReservationApprovalWorkflow(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	w := performRequest("PUT", "/homes/1/settings", map[string]interface{}{
+		"requireApproval": true,
+		"executorId":      "executor",
+	}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	object := createTestObject(t, map[string]interface{}{
+		"name": "Armoire", "type": "furniture", "room_id": "room1",
+	})
+
+	var response map[string]models.Object
+
+	w = performRequest("PATCH", "/objects/"+object.ID+"/reserve", map[string]interface{}{"userId": "heir1"}, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "the holder is the caller, not a user named in the body")
+
+	w = performRequest("PATCH", "/objects/"+object.ID+"/reserve", map[string]interface{}{"userId": "heir2"}, "heir1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "heir1", response["data"].ReservedBy)
+	assert.Equal(t, models.StatusPending, response["data"].ReservationStatus)
+	assert.True(t, response["data"].IsReserved)
+
+	tests := []struct {
+		name           string
+		action         string
+		userID         string
+		expectedCode   int
+		expectedStatus models.ReservationStatus
+	}{
+		{"Pickup Before Approval", "pickup", "executor", http.StatusConflict, ""},
+		{"Approval By Non-Executor", "approve", "heir1", http.StatusForbidden, ""},
+		{"Approval Without User", "approve", "", http.StatusUnauthorized, ""},
+		{"Approval By Executor", "approve", "executor", http.StatusOK, models.StatusApproved},
+		{"Reject After Approval", "reject", "executor", http.StatusConflict, ""},
+		{"Pickup After Approval", "pickup", "executor", http.StatusOK, models.StatusPickedUp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := performRequest("PATCH", "/objects/"+object.ID+"/"+tt.action, map[string]interface{}{"comment": "ok"}, tt.userID)
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedCode == http.StatusOK {
+				var response map[string]models.Object
+				json.Unmarshal(w.Body.Bytes(), &response)
+				assert.Equal(t, tt.expectedStatus, response["data"].ReservationStatus)
+			}
+		})
+	}
+
+	// A collected object can no longer be released
+	w = performRequest("PATCH", "/objects/"+object.ID+"/unreserve", nil, "executor")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = performRequest("GET", "/objects/"+object.ID+"/history", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var history map[string][]models.HistoryEntry
+	json.Unmarshal(w.Body.Bytes(), &history)
+	if assert.Len(t, history["data"], 3) {
+		assert.Equal(t, "reserve", history["data"][0].Action)
+		assert.Equal(t, models.StatusApproved, history["data"][1].To)
+		assert.Equal(t, "ok", history["data"][1].Comment)
+		assert.Equal(t, models.StatusPickedUp, history["data"][2].To)
+	}
+
+	// Settings records must not leak into object listings
+	w = performRequest("GET", "/objects", nil, "")
+	var objects map[string][]models.Object
+	json.Unmarshal(w.Body.Bytes(), &objects)
+	assert.Len(t, objects["data"], 1)
+}
+
+func TestRejectedReservation(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	performRequest("PUT", "/homes/1/settings", map[string]interface{}{
+		"requireApproval": true,
+		"executorId":      "executor",
+	}, "")
+	object := createTestObject(t, map[string]interface{}{
+		"name": "Vase", "type": "decoration", "room_id": "room1",
+	})
+
+	w := performRequest("PATCH", "/objects/"+object.ID+"/reserve", nil, "heir1")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest("PATCH", "/objects/"+object.ID+"/reject", map[string]interface{}{"comment": "promised to heir2"}, "executor")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]models.Object
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, models.StatusRejected, response["data"].ReservationStatus)
+	assert.False(t, response["data"].IsReserved)
+	assert.Empty(t, response["data"].ReservedBy)
+
+	// Someone else can claim it afterwards
+	w = performRequest("PATCH", "/objects/"+object.ID+"/reserve", nil, "heir2")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHomeSettingsValidation(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	w := performRequest("PUT", "/homes/1/settings", map[string]interface{}{"requireApproval": true}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest("GET", "/homes/2/settings", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]models.HomeSettings
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.False(t, response["data"].RequireApproval)
+
+	// Without approval mode a reservation is approved straight away
+	object := createTestObject(t, map[string]interface{}{
+		"name": "Lamp", "type": "lighting", "room_id": "room9",
+	})
+	w = performRequest("PATCH", "/objects/"+object.ID+"/reserve", nil, "heir1")
+	var reserved map[string]models.Object
+	json.Unmarshal(w.Body.Bytes(), &reserved)
+	assert.Equal(t, models.StatusApproved, reserved["data"].ReservationStatus)
+}
+
+func TestReservationsOnlyTouchObjects(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	settings := `{"homeId":"1","requireApproval":true,"executorId":"executor"}`
+	mr.Set("home:1:settings", settings)
+
+	w := performRequest("PATCH", "/objects/home:1:settings/reserve", nil, "heir1")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest("PATCH", "/objects/home:1:settings/unreserve", nil, "heir1")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	stored, _ := mr.Get("home:1:settings")
+	assert.Equal(t, settings, stored)
+	assert.False(t, mr.Exists(""))
+}
+
+func TestExecutorReleasesAClaim(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	performRequest("PUT", "/homes/1/settings", map[string]interface{}{"requireApproval": true, "executorId": "executor"}, "admin1")
+	object := reserveTestObject(t, "Commode", "1", "heir1")
+	performRequest("PATCH", "/objects/"+object.ID+"/approve", nil, "executor")
+
+	// An approved claim is released by the executor, not by another heir
+	w := performRequest("PATCH", "/objects/"+object.ID+"/unreserve", nil, "heir2")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.True(t, mustLoadObject(t, object.ID).IsReserved)
+
+	w = performRequest("PATCH", "/objects/"+object.ID+"/unreserve", nil, "executor")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.False(t, mustLoadObject(t, object.ID).IsReserved)
+
+	w = performRequest("GET", "/objects/"+object.ID+"/history", nil, "")
+	var history map[string][]models.HistoryEntry
+	json.Unmarshal(w.Body.Bytes(), &history)
+	last := history["data"][len(history["data"])-1]
+	assert.Equal(t, "unreserve", last.Action)
+	assert.Equal(t, "executor", last.UserID)
+	assert.Equal(t, "heir1", last.ClaimantID)
+}
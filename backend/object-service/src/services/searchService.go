@@ -0,0 +1,458 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hexagone/object-service/src/database"
+	"hexagone/object-service/src/models"
+	"hexagone/object-service/src/search"
+	"hexagone/shared/logging"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// The search index is an inverted index kept in DragonflyDB:
+//   - search:terms is a sorted set of every known term, all with score 0, so
+//     terms starting with a prefix can be listed with ZRANGEBYLEX
+//   - search:term:<term> is a sorted set of "<kind>:<id>" members scored by
+//     how much the term weighs in that document
+//   - search:doc:<kind>:<id> holds the document and its terms, so the index
+//     can be updated when the document changes
+const (
+	searchTermsKey       = "search:terms"
+	searchMaxPrefixTerms = 50  // Terms expanded from one prefix
+	searchPrefixFactor   = 0.5 // Weight of a prefix match relative to a whole word
+	searchDefaultLimit   = 20
+	searchMaxLimit       = 100
+
+	titleWeight       = 4
+	tagWeight         = 3
+	descriptionWeight = 1
+)
+
+func searchTermKey(term string) string {
+	return "search:term:" + term
+}
+
+func searchMember(kind, id string) string {
+	return kind + ":" + id
+}
+
+func searchDocKey(member string) string {
+	return "search:doc:" + member
+}
+
+// indexedDocument is a search document as stored, along with its terms
+type indexedDocument struct {
+	models.SearchDocument
+	Terms map[string]float64 `json:"terms"`
+}
+
+// documentTerms weighs the terms of a document by the field they appear in
+func documentTerms(doc models.SearchDocument) map[string]float64 {
+	terms := map[string]float64{}
+	add := func(text string, weight float64) {
+		for _, term := range search.Terms(text) {
+			terms[term] += weight
+		}
+	}
+	add(doc.Title, titleWeight)
+	add(strings.Join(doc.Tags, " "), tagWeight)
+	add(doc.Description, descriptionWeight)
+	return terms
+}
+
+func loadIndexedDocument(ctx context.Context, member string) (*indexedDocument, error) {
+	val, err := database.RDB.Get(ctx, searchDocKey(member)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var doc indexedDocument
+	if err := json.Unmarshal([]byte(val), &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// pruneTerms drops terms no document uses anymore from the term dictionary.
+// Each term's postings are watched, so a term a document starts using in the
+// meantime is kept.
+func pruneTerms(ctx context.Context, terms []string) {
+	for _, term := range terms {
+		err := database.RDB.Watch(ctx, func(tx *redis.Tx) error {
+			count, err := tx.ZCard(ctx, searchTermKey(term)).Result()
+			if err != nil || count > 0 {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.ZRem(ctx, searchTermsKey, term)
+				return nil
+			})
+			return err
+		}, searchTermKey(term))
+		if err != nil && err != redis.TxFailedErr {
+			logging.LogFrom(ctx).WithFields(logrus.Fields{
+				"term":  term,
+				"error": err.Error(),
+			}).Warn("Failed to prune search term")
+		}
+	}
+}
+
+// indexDocument adds or refreshes a document in the search index
+func indexDocument(ctx context.Context, doc models.SearchDocument) error {
+	member := searchMember(doc.Kind, doc.ID)
+	previous, err := loadIndexedDocument(ctx, member)
+	if err != nil {
+		return err
+	}
+
+	stored := indexedDocument{SearchDocument: doc, Terms: documentTerms(doc)}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	pipe := database.RDB.TxPipeline()
+	removed := []string{}
+	if previous != nil {
+		for term := range previous.Terms {
+			if _, ok := stored.Terms[term]; !ok {
+				pipe.ZRem(ctx, searchTermKey(term), member)
+				removed = append(removed, term)
+			}
+		}
+	}
+	for term, weight := range stored.Terms {
+		pipe.ZAdd(ctx, searchTermKey(term), redis.Z{Score: weight, Member: member})
+		pipe.ZAdd(ctx, searchTermsKey, redis.Z{Score: 0, Member: term})
+	}
+	pipe.Set(ctx, searchDocKey(member), data, 0)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	pruneTerms(ctx, removed)
+	return nil
+}
+
+// removeDocument drops a document from the search index
+func removeDocument(ctx context.Context, kind, id string) error {
+	member := searchMember(kind, id)
+	previous, err := loadIndexedDocument(ctx, member)
+	if err != nil || previous == nil {
+		return err
+	}
+
+	pipe := database.RDB.TxPipeline()
+	removed := []string{}
+	for term := range previous.Terms {
+		pipe.ZRem(ctx, searchTermKey(term), member)
+		removed = append(removed, term)
+	}
+	pipe.Del(ctx, searchDocKey(member))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	pruneTerms(ctx, removed)
+	return nil
+}
+
+// indexObjectForSearch keeps the search index in step with an object write
+func indexObjectForSearch(ctx context.Context, object models.Object) {
+	err := indexDocument(ctx, models.SearchDocument{
+		Kind:        models.SearchKindObject,
+		ID:          object.ID,
+		Title:       object.Name,
+		Description: object.Description,
+		Tags:        object.Tags,
+		HomeID:      object.HomeID,
+		RoomID:      object.RoomID,
+	})
+	if err != nil {
+		logging.LogFrom(ctx).WithFields(logrus.Fields{
+			"objectID": object.ID,
+			"error":    err.Error(),
+		}).Error("Failed to index object for search")
+	}
+}
+
+// unindexObjectForSearch removes a deleted object from the search index
+func unindexObjectForSearch(ctx context.Context, objectID string) {
+	if err := removeDocument(ctx, models.SearchKindObject, objectID); err != nil {
+		logging.LogFrom(ctx).WithFields(logrus.Fields{
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to remove object from search index")
+	}
+}
+
+// matchTerm scores the documents matching one query term: documents
+// containing the whole word get its full weight, documents containing a
+// longer word starting with it get a fraction of it
+func matchTerm(ctx context.Context, queryTerm string) (map[string]float64, error) {
+	terms, err := database.RDB.ZRangeByLex(ctx, searchTermsKey, &redis.ZRangeBy{
+		Min:   "[" + queryTerm,
+		Max:   "[" + queryTerm + "\xff",
+		Count: searchMaxPrefixTerms,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	matches := map[string]float64{}
+	for _, term := range terms {
+		factor := searchPrefixFactor
+		if term == queryTerm {
+			factor = 1
+		}
+
+		postings, err := database.RDB.ZRangeWithScores(ctx, searchTermKey(term), 0, -1).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, posting := range postings {
+			member := posting.Member.(string)
+			matches[member] = max(matches[member], posting.Score*factor)
+		}
+	}
+	return matches, nil
+}
+
+// Search finds objects, rooms and homes whose names, descriptions or tags
+// contain every word of ?q=, ignoring case and accents. Words also match as
+// prefixes. Results can be narrowed with ?kind= and ?home_id=.
+func Search(c *gin.Context) {
+	q := c.Query("q")
+	terms := search.QueryTerms(q)
+	if len(terms) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	kind := c.Query("kind")
+	if kind != "" && kind != models.SearchKindObject && kind != models.SearchKindRoom && kind != models.SearchKindHome {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be object, room or home"})
+		return
+	}
+	homeID := c.Query("home_id")
+
+	limit := searchDefaultLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = min(n, searchMaxLimit)
+	}
+
+	// Every query term must match; scores add up
+	var scores map[string]float64
+	for _, term := range terms {
+		matches, err := matchTerm(requestCtx(c), term)
+		if err != nil {
+			logging.RequestLog(c).WithFields(logrus.Fields{
+				"query": q,
+				"error": err.Error(),
+			}).Error("Failed to read search index")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+			return
+		}
+
+		if scores == nil {
+			scores = matches
+			continue
+		}
+		for member := range scores {
+			if weight, ok := matches[member]; ok {
+				scores[member] += weight
+			} else {
+				delete(scores, member)
+			}
+		}
+	}
+
+	results := []models.SearchResult{}
+	if len(scores) > 0 {
+		members := make([]string, 0, len(scores))
+		keys := make([]string, 0, len(scores))
+		for member := range scores {
+			members = append(members, member)
+			keys = append(keys, searchDocKey(member))
+		}
+
+		values, err := database.RDB.MGet(requestCtx(c), keys...).Result()
+		if err != nil {
+			logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to load search documents")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+			return
+		}
+
+		for i, value := range values {
+			val, ok := value.(string)
+			if !ok {
+				continue
+			}
+			var doc indexedDocument
+			if err := json.Unmarshal([]byte(val), &doc); err != nil {
+				continue
+			}
+			if (kind != "" && doc.Kind != kind) || (homeID != "" && doc.HomeID != homeID) {
+				continue
+			}
+			results = append(results, models.SearchResult{SearchDocument: doc.SearchDocument, Score: scores[members[i]]})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Title < results[j].Title
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"query": q,
+		"count": len(results),
+	}).Info("Search completed")
+
+	c.JSON(http.StatusOK, gin.H{"data": results})
+}
+
+type SearchDocumentInput struct {
+	Title  string `json:"title" binding:"required"`
+	HomeID string `json:"homeId"`
+}
+
+// PutSearchDocument indexes a room or home on behalf of the service owning
+// it, which calls this whenever one is created or renamed
+func PutSearchDocument(c *gin.Context) {
+	kind := c.Param("kind")
+	id := c.Param("id")
+	if kind != models.SearchKindRoom && kind != models.SearchKindHome {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be room or home"})
+		return
+	}
+
+	var input SearchDocumentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doc := models.SearchDocument{Kind: kind, ID: id, Title: input.Title, HomeID: input.HomeID}
+	if kind == models.SearchKindHome {
+		doc.HomeID = id
+	} else {
+		doc.RoomID = id
+	}
+
+	if err := indexDocument(requestCtx(c), doc); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"kind":  kind,
+			"id":    id,
+			"error": err.Error(),
+		}).Error("Failed to index search document")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to index document"})
+		return
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"kind": kind,
+		"id":   id,
+	}).Info("Search document indexed")
+	c.JSON(http.StatusOK, gin.H{"data": doc})
+}
+
+// DeleteSearchDocument removes a deleted room or home from the index
+func DeleteSearchDocument(c *gin.Context) {
+	kind := c.Param("kind")
+	id := c.Param("id")
+	if kind != models.SearchKindRoom && kind != models.SearchKindHome {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be room or home"})
+		return
+	}
+
+	if err := removeDocument(requestCtx(c), kind, id); err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"kind":  kind,
+			"id":    id,
+			"error": err.Error(),
+		}).Error("Failed to remove search document")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove document"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Document removed from the search index"})
+}
+
+// ReindexSearch rebuilds the index from every object and, when the home and
+// room services are reachable, every home and room. It is meant for data
+// written before the index existed.
+func ReindexSearch(c *gin.Context) {
+	objects, err := fetchObjects(requestCtx(c), func(models.Object) bool { return true })
+	if err != nil {
+		logging.RequestLog(c).WithField("error", err.Error()).Error("Failed to fetch objects")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch objects"})
+		return
+	}
+	for _, object := range objects {
+		indexObjectForSearch(requestCtx(c), object)
+	}
+
+	counts := gin.H{"objects": len(objects), "homes": 0, "rooms": 0}
+	warnings := []string{}
+
+	homes, err := fetchHomes(requestCtx(c))
+	if err != nil {
+		warnings = append(warnings, err.Error())
+	}
+	rooms := 0
+	for _, home := range homes {
+		homeID := strconv.FormatUint(uint64(home.ID), 10)
+		if err := indexDocument(requestCtx(c), models.SearchDocument{Kind: models.SearchKindHome, ID: homeID, Title: home.Name, HomeID: homeID}); err != nil {
+			warnings = append(warnings, fmt.Sprintf("home %s: %v", homeID, err))
+		}
+
+		homeRooms, err := fetchRooms(requestCtx(c), homeID)
+		if err != nil {
+			warnings = append(warnings, err.Error())
+			continue
+		}
+		for _, room := range homeRooms {
+			roomID := strconv.FormatUint(uint64(room.ID), 10)
+			if err := indexDocument(requestCtx(c), models.SearchDocument{Kind: models.SearchKindRoom, ID: roomID, Title: room.Name, HomeID: homeID, RoomID: roomID}); err != nil {
+				warnings = append(warnings, fmt.Sprintf("room %s: %v", roomID, err))
+				continue
+			}
+			rooms++
+		}
+	}
+	counts["homes"] = len(homes)
+	counts["rooms"] = rooms
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"objects":  len(objects),
+		"homes":    len(homes),
+		"rooms":    rooms,
+		"warnings": len(warnings),
+	}).Info("Search index rebuilt")
+
+	c.JSON(http.StatusOK, gin.H{"data": counts, "warnings": warnings})
+}
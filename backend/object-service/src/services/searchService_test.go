@@ -0,0 +1,136 @@
+package services_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"hexagone/object-service/src/models"
+	"hexagone/object-service/src/services"
+	"hexagone/shared/auth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func searchTitles(t *testing.T, url string) []string {
+	w := performRequest("GET", url, nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string][]models.SearchResult
+	json.Unmarshal(w.Body.Bytes(), &response)
+	titles := []string{}
+	for _, result := range response["data"] {
+		titles = append(titles, result.Title)
+	}
+	return titles
+}
+
+// performServiceRequest calls an internal route the way the room and home
+// services do, with the service token
+func performServiceRequest(method, url string, body interface{}) *httptest.ResponseRecorder {
+	jsonInput, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, url, bytes.NewBuffer(jsonInput))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(auth.ServiceTokenHeader, testServiceToken)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSearch(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	createTestObject(t, map[string]interface{}{
+		"name": "Vase bleu", "type": "decoration", "room_id": "1",
+		"description": "Vase en céramique de Gien",
+	})
+	createTestObject(t, map[string]interface{}{
+		"name": "Théière", "type": "kitchen", "room_id": "2",
+		"description": "Service à thé bleu et blanc", "tags": []string{"porcelaine"},
+	})
+	lamp := createTestObject(t, map[string]interface{}{
+		"name": "Lampe de chevet", "type": "lighting", "room_id": "3",
+		"tags": []string{"céramique"},
+	})
+	w := performRequest("PUT", "/search/documents/room/2", map[string]interface{}{"title": "Cuisine", "homeId": "1"}, "admin")
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "only the services may write documents")
+	w = performServiceRequest("PUT", "/search/documents/room/2", map[string]interface{}{"title": "Cuisine", "homeId": "1"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performServiceRequest("PUT", "/search/documents/home/1", map[string]interface{}{"title": "Maison de Grand-Mère"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	tests := []struct {
+		name     string
+		url      string
+		expected []string
+	}{
+		{"Accent Insensitive", "/search?q=theiere", []string{"Théière"}},
+		{"Accented Query", "/search?q=C%C3%A9ramique", []string{"Lampe de chevet", "Vase bleu"}},
+		{"Prefix", "/search?q=cuis", []string{"Cuisine"}},
+		{"Every Word Must Match", "/search?q=vase%20bleu", []string{"Vase bleu"}},
+		{"Name Ranks Above Description", "/search?q=bleu", []string{"Vase bleu", "Théière"}},
+		{"Tags Rank Above Description", "/search?q=ceramique", []string{"Lampe de chevet", "Vase bleu"}},
+		{"Homes", "/search?q=grand%20mere", []string{"Maison de Grand-Mère"}},
+		{"Kind Filter", "/search?q=c&kind=room", []string{"Cuisine"}},
+		{"Home Filter", "/search?q=ceramique&home_id=1", []string{"Vase bleu"}},
+		{"No Match", "/search?q=commode", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, searchTitles(t, tt.url))
+		})
+	}
+
+	w = performRequest("GET", "/search?q=", nil, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performServiceRequest("PUT", "/search/documents/object/1", map[string]interface{}{"title": "x"})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "objects are indexed by this service only")
+
+	// Deletes keep the index in step
+	performRequest("DELETE", "/objects/"+lamp.ID, nil, "")
+	assert.Equal(t, []string{"Vase bleu"}, searchTitles(t, "/search?q=ceramique"))
+	assert.False(t, mr.Exists("search:term:chevet"), "unused terms are dropped")
+	terms, _ := mr.ZMembers("search:terms")
+	assert.NotContains(t, terms, "chevet")
+	assert.Contains(t, terms, "ceramique", "terms still in use are kept")
+
+	w = performRequest("DELETE", "/search/documents/room/2", nil, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	performServiceRequest("DELETE", "/search/documents/room/2", nil)
+	assert.Empty(t, searchTitles(t, "/search?q=cuisine"))
+}
+
+func TestReindexSearch(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	object := createTestObject(t, map[string]interface{}{"name": "Malle", "type": "storage", "room_id": "4"})
+
+	homeService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": [{"id": 1, "name": "Maison de famille"}]}`))
+	}))
+	defer homeService.Close()
+	roomService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "1", r.URL.Query().Get("home_id"))
+		w.Write([]byte(`{"data": [{"id": 4, "name": "Grenier", "home_id": 1}]}`))
+	}))
+	defer roomService.Close()
+	services.HomeServiceURL, services.RoomServiceURL = homeService.URL, roomService.URL
+
+	mr.Del("search:doc:object:" + object.ID)
+	mr.Del("search:term:malle")
+
+	w := performRequest("POST", "/search/reindex", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, []string{"Malle"}, searchTitles(t, "/search?q=malle"))
+	assert.Equal(t, []string{"Grenier"}, searchTitles(t, "/search?q=grenier"))
+	assert.Equal(t, []string{"Maison de famille"}, searchTitles(t, "/search?q=famille"))
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hexagone/shared/httpclient"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// upstreamClient calls the home and room services
+var upstreamClient = httpclient.New(10 * time.Second)
+
+// Home and Room mirror the records of the home and room services
+type Home struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+type Room struct {
+	ID     uint   `json:"id"`
+	Name   string `json:"name"`
+	HomeID uint   `json:"home_id"`
+}
+
+// HomeServiceURL and RoomServiceURL are the base URLs of the home and room
+// services, set from the configuration at startup
+var HomeServiceURL, RoomServiceURL string
+
+// getUpstream decodes the "data" field of a GET response into out
+func getUpstream(ctx context.Context, target string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", target, resp.Status)
+	}
+
+	response := struct {
+		Data interface{} `json:"data"`
+	}{Data: out}
+	return json.NewDecoder(resp.Body).Decode(&response)
+}
+
+// fetchHomes lists every home from the home service
+func fetchHomes(ctx context.Context) ([]Home, error) {
+	homes := []Home{}
+	err := getUpstream(ctx, HomeServiceURL+"/homes", &homes)
+	return homes, err
+}
+
+// errHomeNotFound is returned by fetchHome for homes the home service does not list
+var errHomeNotFound = errors.New("home not found")
+
+// fetchHome finds a home among those the home service lists
+func fetchHome(ctx context.Context, homeID string) (Home, error) {
+	homes, err := fetchHomes(ctx)
+	if err != nil {
+		return Home{}, err
+	}
+	for _, home := range homes {
+		if strconv.FormatUint(uint64(home.ID), 10) == homeID {
+			return home, nil
+		}
+	}
+	return Home{}, errHomeNotFound
+}
+
+// fetchRooms lists the rooms of a home from the room service
+func fetchRooms(ctx context.Context, homeID string) ([]Room, error) {
+	rooms := []Room{}
+	err := getUpstream(ctx, RoomServiceURL+"/rooms?home_id="+url.QueryEscape(homeID), &rooms)
+	return rooms, err
+}
+
+// errRoomNotFound is returned by fetchRoom for rooms the room service does not know
+var errRoomNotFound = errors.New("room not found")
+
+// fetchRoom reads a single room from the room service
+func fetchRoom(ctx context.Context, roomID string) (Room, error) {
+	var room Room
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, RoomServiceURL+"/rooms/"+url.PathEscape(roomID), nil)
+	if err != nil {
+		return room, err
+	}
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return room, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return room, errRoomNotFound
+	default:
+		return room, fmt.Errorf("GET %s/rooms/%s: %s", RoomServiceURL, roomID, resp.Status)
+	}
+
+	response := struct {
+		Data *Room `json:"data"`
+	}{Data: &room}
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	return room, err
+}
+
+// createRoom creates a room in a home through the room service
+func createRoom(ctx context.Context, homeID uint, name string) (Room, error) {
+	var room Room
+
+	body, err := json.Marshal(map[string]interface{}{"name": name, "home_id": homeID})
+	if err != nil {
+		return room, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, RoomServiceURL+"/rooms", bytes.NewReader(body))
+	if err != nil {
+		return room, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return room, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return room, fmt.Errorf("POST %s/rooms: %s", RoomServiceURL, resp.Status)
+	}
+
+	response := struct {
+		Data *Room `json:"data"`
+	}{Data: &room}
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	return room, err
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"hexagone/object-service/src/database"
+	"hexagone/object-service/src/models"
+	"hexagone/shared/logging"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// reservationIndexBuiltKey marks that objects reserved before the
+// reservation index existed have been indexed
+const reservationIndexBuiltKey = "reservation-index:built"
+
+// userReservationsKey is the set of object IDs a user currently holds a claim on
+func userReservationsKey(userID string) string {
+	return "user:" + userID + ":reservations"
+}
+
+// userHistoryKey lists every reservation change affecting a user's claims
+func userHistoryKey(userID string) string {
+	return "user:" + userID + ":history"
+}
+
+// indexReservation adds an object to a user's claims
+func indexReservation(ctx context.Context, userID, objectID string) {
+	if userID == "" {
+		return
+	}
+	if err := database.RDB.SAdd(ctx, userReservationsKey(userID), objectID).Err(); err != nil {
+		logging.LogFrom(ctx).WithFields(logrus.Fields{
+			"userID":   userID,
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to index reservation")
+	}
+}
+
+// unindexReservation removes an object from a user's claims
+func unindexReservation(ctx context.Context, userID, objectID string) {
+	if userID == "" {
+		return
+	}
+	if err := database.RDB.SRem(ctx, userReservationsKey(userID), objectID).Err(); err != nil {
+		logging.LogFrom(ctx).WithFields(logrus.Fields{
+			"userID":   userID,
+			"objectID": objectID,
+			"error":    err.Error(),
+		}).Error("Failed to unindex reservation")
+	}
+}
+
+// BuildReservationIndex adds the objects reserved before the reservation
+// index existed to it. It only does work the first time it runs against a
+// database.
+func BuildReservationIndex() error {
+	first, err := database.RDB.SetNX(database.Ctx, reservationIndexBuiltKey, time.Now().UTC().Format(time.RFC3339), 0).Result()
+	if err != nil || !first {
+		return err
+	}
+
+	objects, err := fetchObjects(database.Ctx, func(o models.Object) bool { return o.IsReserved && o.ReservedBy != "" })
+	if err == nil {
+		pipe := database.RDB.Pipeline()
+		for _, object := range objects {
+			pipe.SAdd(database.Ctx, userReservationsKey(object.ReservedBy), object.ID)
+		}
+		_, err = pipe.Exec(database.Ctx)
+	}
+	if err != nil {
+		// Try again on the next start
+		database.RDB.Del(database.Ctx, reservationIndexBuiltKey)
+		return err
+	}
+
+	logging.Log.WithField("count", len(objects)).Info("Reserved objects indexed by user")
+	return nil
+}
+
+// listUserReservations returns the objects a user currently holds a claim on,
+// pruning index entries whose object is gone or no longer claimed by the user.
+// Any other failure to read an object is returned and prunes nothing.
+func listUserReservations(ctx context.Context, userID string) ([]models.Object, error) {
+	objectIDs, err := database.RDB.SMembers(ctx, userReservationsKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []models.Object{}
+	for _, objectID := range objectIDs {
+		object, err := loadObject(ctx, objectID)
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		if err == redis.Nil || !object.IsReserved || object.ReservedBy != userID {
+			logging.LogFrom(ctx).WithFields(logrus.Fields{
+				"userID":   userID,
+				"objectID": objectID,
+			}).Warn("Stale reservation index entry, removing")
+			unindexReservation(ctx, userID, objectID)
+			continue
+		}
+		objects = append(objects, object)
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, nil
+}
+
+func respondUserReservations(c *gin.Context, userID string) {
+	logging.RequestLog(c).WithField("userID", userID).Info("Fetching reservations of user")
+
+	objects, err := listUserReservations(requestCtx(c), userID)
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err.Error(),
+		}).Error("Failed to fetch user reservations")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reservations"})
+		return
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"userID": userID,
+		"count":  len(objects),
+	}).Info("User reservations fetched successfully")
+
+	c.JSON(http.StatusOK, gin.H{"data": objects})
+}
+
+// ListUserReservations returns every object a user has claimed
+func ListUserReservations(c *gin.Context) {
+	respondUserReservations(c, c.Param("id"))
+}
+
+// ListMyReservations returns the claims of the user identified by X-User-ID
+func ListMyReservations(c *gin.Context) {
+	userID := c.GetHeader("X-User-ID")
+	if userID == "" {
+		logging.RequestLog(c).Warn("No user ID found in header")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	respondUserReservations(c, userID)
+}
+
+// GetUserHistory returns every reservation change that affected a user's claims
+func GetUserHistory(c *gin.Context) {
+	userID := c.Param("id")
+
+	vals, err := database.RDB.LRange(requestCtx(c), userHistoryKey(userID), 0, -1).Result()
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"userID": userID,
+			"error":  err.Error(),
+		}).Error("Failed to fetch user history")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user history"})
+		return
+	}
+
+	history := []models.HistoryEntry{}
+	for _, val := range vals {
+		var entry models.HistoryEntry
+		if err := json.Unmarshal([]byte(val), &entry); err != nil {
+			logging.RequestLog(c).WithField("userID", userID).Warn("Failed to unmarshal history entry, skipping")
+			continue
+		}
+		history = append(history, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": history})
+}
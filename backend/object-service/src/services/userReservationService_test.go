@@ -0,0 +1,115 @@
+package services_test
+
+import (
+	"encoding/json"
+	"hexagone/object-service/src/models"
+	"hexagone/object-service/src/services"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserReservations(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	chair := reserveTestObject(t, "Chaise", "1", "heir1")
+	table := reserveTestObject(t, "Table", "1", "heir1")
+	reserveTestObject(t, "Lampe", "1", "heir2")
+	vase := reserveTestObject(t, "Vase", "1", "heir1")
+
+	performRequest("PATCH", "/objects/"+table.ID+"/unreserve", nil, "heir1")
+	performRequest("DELETE", "/objects/"+vase.ID, nil, "")
+
+	tests := []struct {
+		name          string
+		url           string
+		userID        string
+		expectedCode  int
+		expectedNames []string
+	}{
+		{"By User ID", "/users/heir1/reservations", "", http.StatusOK, []string{"Chaise"}},
+		{"Other User", "/users/heir2/reservations", "", http.StatusOK, []string{"Lampe"}},
+		{"Nothing Claimed", "/users/heir3/reservations", "", http.StatusOK, []string{}},
+		{"Me", "/me/reservations", "heir1", http.StatusOK, []string{"Chaise"}},
+		{"Me Without User", "/me/reservations", "", http.StatusUnauthorized, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := performRequest("GET", tt.url, nil, tt.userID)
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedCode == http.StatusOK {
+				var response map[string][]models.Object
+				json.Unmarshal(w.Body.Bytes(), &response)
+				names := []string{}
+				for _, object := range response["data"] {
+					names = append(names, object.Name)
+				}
+				assert.Equal(t, tt.expectedNames, names)
+			}
+		})
+	}
+
+	w := performRequest("GET", "/objects/"+chair.ID+"/history", nil, "")
+	var objectHistory map[string][]models.HistoryEntry
+	json.Unmarshal(w.Body.Bytes(), &objectHistory)
+	if assert.Len(t, objectHistory["data"], 1) {
+		assert.Equal(t, "heir1", objectHistory["data"][0].ClaimantID)
+		assert.Equal(t, chair.ID, objectHistory["data"][0].ObjectID)
+	}
+
+	w = performRequest("GET", "/users/heir1/history", nil, "")
+	var userHistory map[string][]models.HistoryEntry
+	json.Unmarshal(w.Body.Bytes(), &userHistory)
+	actions := []string{}
+	for _, entry := range userHistory["data"] {
+		actions = append(actions, entry.Action+":"+entry.ObjectID)
+	}
+	assert.Equal(t, []string{
+		"reserve:" + chair.ID,
+		"reserve:" + table.ID,
+		"reserve:" + vase.ID,
+		"unreserve:" + table.ID,
+	}, actions)
+}
+
+func TestBuildReservationIndex(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	// Objects reserved before the reservation index existed
+	mr.Set("legacy-chair", `{"id": "legacy-chair", "name": "Chaise", "type": "furniture", "room_id": "room1", "isReserved": true, "reservedBy": "heir1"}`)
+	mr.Set("legacy-table", `{"id": "legacy-table", "name": "Table", "type": "furniture", "room_id": "room1"}`)
+
+	require.NoError(t, services.BuildReservationIndex())
+	members, _ := mr.Members("user:heir1:reservations")
+	assert.Equal(t, []string{"legacy-chair"}, members)
+
+	// Later runs leave the index alone
+	mr.SRem("user:heir1:reservations", "legacy-chair")
+	require.NoError(t, services.BuildReservationIndex())
+	assert.False(t, mr.Exists("user:heir1:reservations"))
+}
+
+func TestUserReservationsKeepIndexOnReadFailure(t *testing.T) {
+	if err := setupTestServer(); err != nil {
+		t.Fatalf("Failed to setup test server: %v", err)
+	}
+	defer cleanupTest()
+
+	chair := reserveTestObject(t, "Chaise", "1", "heir1")
+	mr.Set(chair.ID, "not json")
+
+	w := performRequest("GET", "/users/heir1/reservations", nil, "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	members, _ := mr.Members("user:heir1:reservations")
+	assert.Equal(t, []string{chair.ID}, members, "an unreadable object is not a stale entry")
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrNotFound is returned when no blob is stored under a key
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps media blobs outside DragonflyDB. Keys are slash-separated
+// paths such as "objects/<id>/photos/<photoId>/small.jpg".
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL the frontend can fetch the blob from directly
+	// until ttl has elapsed
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Media is the store used by the services, set up in main
+var Media Store
+
+// NewFromEnv builds the store selected by STORAGE_BACKEND ("local" by default, or "s3")
+func NewFromEnv() (Store, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		root := os.Getenv("MEDIA_DIR")
+		if root == "" {
+			root = "data/media"
+		}
+		store, err := NewLocalStore(root)
+		if err != nil {
+			return nil, err
+		}
+		store.BaseURL = os.Getenv("MEDIA_PUBLIC_URL")
+		if key := os.Getenv("MEDIA_SIGNING_KEY"); key != "" {
+			store.SigningKey = []byte(key)
+		}
+		return store, nil
+	case "s3":
+		store := &S3Store{
+			Endpoint:       os.Getenv("S3_ENDPOINT"),
+			PublicEndpoint: os.Getenv("S3_PUBLIC_ENDPOINT"),
+			Region:         os.Getenv("S3_REGION"),
+			Bucket:         os.Getenv("S3_BUCKET"),
+			AccessKey:      os.Getenv("S3_ACCESS_KEY"),
+			SecretKey:      os.Getenv("S3_SECRET_KEY"),
+			PathStyle:      os.Getenv("S3_PATH_STYLE") != "false",
+		}
+		if store.Region == "" {
+			store.Region = "us-east-1"
+		}
+		if store.Endpoint == "" || store.Bucket == "" || store.AccessKey == "" || store.SecretKey == "" {
+			return nil, errors.New("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY are required for the s3 storage backend")
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", backend)
+	}
+}
@@ -0,0 +1,43 @@
+// Package config reads the settings of the service from the environment.
+// They are loaded and checked once, before anything starts, so that a
+// missing or malformed setting stops the service with a clear message.
+package config
+
+import (
+	"errors"
+	shared "hexagone/shared/config"
+	"hexagone/shared/tracing"
+)
+
+// Config is the configuration of the room service
+type Config struct {
+	Port   string // PORT
+	DBPath string // DB_PATH, the SQLite database file
+	Server shared.Server
+
+	UserService      shared.UserService // where RequireAdmin checks roles
+	ObjectServiceURL string             // OBJECT_SERVICE_URL, or the compose service on OBJECT_PORT; search updates are skipped when unset
+	ServiceToken     string             // SERVICE_TOKEN, presented to the object service on internal routes
+
+	TracesExporter string // OTEL_TRACES_EXPORTER: none, stdout or otlp
+	Logging        shared.Logging
+}
+
+// Load reads the configuration and reports every invalid setting at once
+func Load() (*Config, error) {
+	var errs []error
+	cfg := &Config{
+		Port:   shared.Port("PORT", &errs),
+		DBPath: shared.Required("DB_PATH", &errs),
+		Server: shared.LoadServer(&errs),
+
+		UserService:      shared.LoadUserService(&errs),
+		ObjectServiceURL: shared.ServiceURL("OBJECT_SERVICE_URL", "object-service", "OBJECT_PORT"),
+		ServiceToken:     shared.ServiceToken(&errs),
+
+		TracesExporter: shared.OneOf("OTEL_TRACES_EXPORTER", tracing.ExporterNone,
+			[]string{tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP}, &errs),
+		Logging: shared.LoadLogging(&errs),
+	}
+	return cfg, errors.Join(errs...)
+}
@@ -0,0 +1,57 @@
+package config_test
+
+import (
+	"hexagone/room-service/src/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Setenv("PORT", "8082")
+	t.Setenv("USER_PORT", "8083")
+	t.Setenv("OBJECT_PORT", "8084")
+	t.Setenv("DB_PATH", "/app/data/room.db")
+	t.Setenv("SERVICE_TOKEN", "0123456789abcdef0123456789abcdef")
+	t.Setenv("HTTP_WRITE_TIMEOUT", "2m")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "8082", cfg.Port)
+	assert.Equal(t, "/app/data/room.db", cfg.DBPath)
+	assert.Equal(t, 2*time.Minute, cfg.Server.WriteTimeout)
+	assert.Equal(t, 30*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, 15*time.Second, cfg.Server.ShutdownTimeout)
+	assert.Equal(t, "none", cfg.TracesExporter)
+	assert.Equal(t, "info", cfg.Logging.Level)
+	assert.Equal(t, "json", cfg.Logging.Format)
+	assert.Equal(t, "http://user-service:8083", cfg.UserService.URL)
+	assert.Equal(t, 3*time.Second, cfg.UserService.Timeout)
+	assert.Equal(t, 30*time.Second, cfg.UserService.RoleTTL)
+	assert.Equal(t, "http://object-service:8084", cfg.ObjectServiceURL)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	t.Setenv("PORT", "http")
+	t.Setenv("USER_SERVICE_URL", "")
+	t.Setenv("USER_PORT", "")
+	t.Setenv("USER_ROLE_CACHE_TTL", "soon")
+	t.Setenv("DB_PATH", "")
+	t.Setenv("SERVICE_TOKEN", "")
+	t.Setenv("SHUTDOWN_TIMEOUT", "-1s")
+	t.Setenv("OTEL_TRACES_EXPORTER", "jaeger")
+	t.Setenv("LOG_LEVEL", "verbose")
+
+	_, err := config.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `PORT must be a port number, got "http"`)
+	assert.Contains(t, err.Error(), "USER_SERVICE_URL or USER_PORT is required")
+	assert.Contains(t, err.Error(), `USER_ROLE_CACHE_TTL must be a positive duration such as 30s, got "soon"`)
+	assert.Contains(t, err.Error(), "DB_PATH is required")
+	assert.Contains(t, err.Error(), "SERVICE_TOKEN is required")
+	assert.Contains(t, err.Error(), `SHUTDOWN_TIMEOUT must be a positive duration such as 30s, got "-1s"`)
+	assert.Contains(t, err.Error(), `OTEL_TRACES_EXPORTER must be one of none, stdout, otlp, got "jaeger"`)
+	assert.Contains(t, err.Error(), `LOG_LEVEL must be one of debug, info, warn, error, got "verbose"`)
+}
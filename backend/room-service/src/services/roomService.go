@@ -3,12 +3,14 @@ package services
 import (
 	"hexagone/room-service/src/database"
 	"hexagone/room-service/src/models"
-	"hexagone/room-service/src/utils"
+	"hexagone/shared/logging"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 type CreateRoomInput struct {
 	Name   string `json:"name" binding:"required"`
@@ -19,29 +21,31 @@ type CreateRoomInput struct {
 func CreateRoom(c *gin.Context) {
 	var input CreateRoomInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.Log.WithFields(logrus.Fields{
+		logging.RequestLog(c).WithFields(logrus.Fields{
 			"error": err.Error(),
 		}).Error("Error binding JSON in CreateRoom")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	utils.Log.WithFields(logrus.Fields{
+	logging.RequestLog(c).WithFields(logrus.Fields{
 		"name":   input.Name,
 		"homeID": input.HomeID,
 	}).Info("Creating room")
 
 	// Create the room
 	room := models.Room{Name: input.Name, HomeID: input.HomeID}
-	if result := database.DB.Create(&room); result.Error != nil {
-		utils.Log.WithFields(logrus.Fields{
+	if result := database.DB.WithContext(c.Request.Context()).Create(&room); result.Error != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
 			"error": result.Error.Error(),
 		}).Error("Error creating room in the database")
 		c.JSON(http.StatusBadRequest, gin.H{"error": result.Error.Error()})
 		return
 	}
 
-	utils.Log.WithFields(logrus.Fields{
+	indexRoomForSearch(c.Request.Context(), room)
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
 		"id":     room.ID,
 		"name":   room.Name,
 		"homeID": room.HomeID,
@@ -53,12 +57,12 @@ func CreateRoom(c *gin.Context) {
 func DeleteRoom(c *gin.Context) {
     roomId := c.Param("id")
     
-    utils.Log.WithField("roomId", roomId).Info("Attempting to delete room")
+    logging.RequestLog(c).WithField("roomId", roomId).Info("Attempting to delete room")
 
     // Delete the home
-    result := database.DB.Delete(&models.Room{}, roomId)
+    result := database.DB.WithContext(c.Request.Context()).Delete(&models.Room{}, roomId)
     if result.Error != nil {
-        utils.Log.WithFields(logrus.Fields{
+        logging.RequestLog(c).WithFields(logrus.Fields{
             "roomId": roomId,
             "error":  result.Error.Error(),
         }).Error("Failed to delete room")
@@ -67,52 +71,111 @@ func DeleteRoom(c *gin.Context) {
     }
 
     if result.RowsAffected == 0 {
-        utils.Log.WithField("roomId", roomId).Warn("Room not found")
+        logging.RequestLog(c).WithField("roomId", roomId).Warn("Room not found")
         c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
         return
     }
 
-    utils.Log.WithField("roomId", roomId).Info("Room deleted successfully")
+    unindexRoomForSearch(c.Request.Context(), roomId)
+
+    logging.RequestLog(c).WithField("roomId", roomId).Info("Room deleted successfully")
     c.JSON(http.StatusOK, gin.H{"message": "Room deleted successfully"})
 }
 
-// ListRooms handles fetching all rooms for a specific home
+// ListRooms handles fetching the rooms of one or more homes (?home_id=1,2),
+// or rooms by their IDs (?id=3,4)
 func ListRooms(c *gin.Context) {
-	homeIDStr := c.DefaultQuery("home_id", "")
-	if homeIDStr == "" {
-		utils.Log.Warn("home_id is missing in ListRooms request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "home_id is required"})
+	homeIDs, err := parseIDList(c.Query("home_id"))
+	if err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"homeIDStr": c.Query("home_id"),
+			"error":     err.Error(),
+		}).Error("Invalid home_id in ListRooms request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid home_id"})
 		return
 	}
 
-	homeID, err := strconv.ParseUint(homeIDStr, 10, 32)
+	roomIDs, err := parseIDList(c.Query("id"))
 	if err != nil {
-		utils.Log.WithFields(logrus.Fields{
-			"homeIDStr": homeIDStr,
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"roomIDStr": c.Query("id"),
 			"error":     err.Error(),
-		}).Error("Invalid home_id in ListRooms request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid home_id"})
+		}).Error("Invalid id in ListRooms request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
 		return
 	}
 
-	utils.Log.WithFields(logrus.Fields{
-		"homeID": homeID,
-	}).Info("Fetching rooms for home")
+	if len(homeIDs) == 0 && len(roomIDs) == 0 {
+		logging.RequestLog(c).Warn("home_id is missing in ListRooms request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "home_id is required"})
+		return
+	}
+
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"homeIDs": homeIDs,
+		"roomIDs": roomIDs,
+	}).Info("Fetching rooms for homes")
+
+	// Both lists may be given; a room matching either is returned
+	var query *gorm.DB
+	switch {
+	case len(roomIDs) == 0:
+		query = database.DB.WithContext(c.Request.Context()).Where("home_id IN ?", homeIDs)
+	case len(homeIDs) == 0:
+		query = database.DB.WithContext(c.Request.Context()).Where("id IN ?", roomIDs)
+	default:
+		query = database.DB.WithContext(c.Request.Context()).Where("home_id IN ?", homeIDs).Or("id IN ?", roomIDs)
+	}
 
 	var rooms []models.Room
-	if err := database.DB.Where("home_id = ?", homeID).Find(&rooms).Error; err != nil {
-		utils.Log.WithFields(logrus.Fields{
-			"homeID": homeID,
-			"error":  err.Error(),
+	if err := query.Find(&rooms).Error; err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"homeIDs": homeIDs,
+			"error":   err.Error(),
 		}).Error("Failed to retrieve rooms from the database")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve rooms"})
 		return
 	}
 
-	utils.Log.WithFields(logrus.Fields{
-		"homeID": homeID,
-		"count":  len(rooms),
+	logging.RequestLog(c).WithFields(logrus.Fields{
+		"homeIDs": homeIDs,
+		"count":   len(rooms),
 	}).Info("Rooms fetched successfully")
 
 	c.JSON(http.StatusOK, gin.H{"data": rooms})
-}
\ No newline at end of file
+}
+
+// parseIDList reads a comma-separated list of IDs, such as "1,2,3", which
+// lets other services fetch the rooms of several homes in one call
+func parseIDList(value string) ([]uint64, error) {
+	ids := []uint64{}
+	if value == "" {
+		return ids, nil
+	}
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// GetRoom handles fetching a single room, so other services can check that
+// a room exists and which home it belongs to
+func GetRoom(c *gin.Context) {
+	roomID := c.Param("id")
+
+	var room models.Room
+	if err := database.DB.WithContext(c.Request.Context()).First(&room, roomID).Error; err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
+			"roomID": roomID,
+			"error":  err.Error(),
+		}).Warn("Room not found")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": room})
+}
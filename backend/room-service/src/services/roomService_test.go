@@ -3,10 +3,11 @@ package services_test
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"hexagone/room-service/src/database"
 	"hexagone/room-service/src/models"
 	"hexagone/room-service/src/services"
-	"hexagone/room-service/src/utils"
+	"hexagone/shared/logging"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -19,7 +20,7 @@ var router *gin.Engine
 
 func setupTestServer() {
 	// Initialize logger
-	utils.InitLogger()
+	logging.InitLogger()
 	
 	// Set Gin to test mode
 	gin.SetMode(gin.TestMode)
@@ -31,6 +32,7 @@ func setupTestServer() {
 	router = gin.Default()
 	router.POST("/rooms", services.CreateRoom)
 	router.GET("/rooms", services.ListRooms)
+	router.GET("/rooms/:id", services.GetRoom)
 }
 
 func clearDatabase() {
@@ -184,4 +186,90 @@ func TestListRooms(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestListRoomsByIDList(t *testing.T) {
+	setupTestServer()
+	defer clearDatabase()
+
+	var created []models.Room
+	for _, room := range []services.CreateRoomInput{
+		{Name: "Living Room", HomeID: 1},
+		{Name: "Kitchen", HomeID: 2},
+		{Name: "Bedroom", HomeID: 3},
+	} {
+		jsonInput, _ := json.Marshal(room)
+		req := httptest.NewRequest("POST", "/rooms", bytes.NewBuffer(jsonInput))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]models.Room
+		json.Unmarshal(w.Body.Bytes(), &response)
+		created = append(created, response["data"])
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCode  int
+		expectedNames []string
+	}{
+		{"Several homes", "home_id=1,2", http.StatusOK, []string{"Living Room", "Kitchen"}},
+		{"Room IDs", fmt.Sprintf("id=%d,%d", created[0].ID, created[2].ID), http.StatusOK, []string{"Living Room", "Bedroom"}},
+		{"Homes or room IDs", fmt.Sprintf("home_id=2&id=%d", created[2].ID), http.StatusOK, []string{"Kitchen", "Bedroom"}},
+		{"Invalid room ID", "id=1,x", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/rooms?"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var response map[string][]models.Room
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			names := []string{}
+			for _, room := range response["data"] {
+				names = append(names, room.Name)
+			}
+			assert.ElementsMatch(t, tt.expectedNames, names)
+		})
+	}
+}
+
+func TestGetRoom(t *testing.T) {
+	setupTestServer()
+	defer clearDatabase()
+
+	jsonInput, _ := json.Marshal(services.CreateRoomInput{Name: "Cellar", HomeID: 3})
+	req := httptest.NewRequest("POST", "/rooms", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var created map[string]models.Room
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/rooms/%d", created["data"].ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]models.Room
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "Cellar", response["data"].Name)
+	assert.Equal(t, uint(3), response["data"].HomeID)
+
+	req = httptest.NewRequest("GET", "/rooms/9999", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
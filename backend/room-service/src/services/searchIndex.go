@@ -0,0 +1,92 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hexagone/room-service/src/models"
+	"hexagone/shared/auth"
+	"hexagone/shared/httpclient"
+	"hexagone/shared/logging"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The object service keeps the search index covering objects, rooms and
+// homes. Rooms are pushed to it on every write; failures are only logged
+// since an admin can rebuild the index with POST /search/reindex.
+var searchClient = httpclient.New(5 * time.Second)
+
+// ObjectServiceURL is the base URL of the object service, empty when rooms
+// are not indexed for search
+var ObjectServiceURL string
+
+// ServiceToken is presented to the object service, which only accepts search
+// updates from the other services
+var ServiceToken string
+
+// searchIndexURL is the object service endpoint for room documents, empty
+// when the object service is not configured
+func searchIndexURL(roomID string) string {
+	if ObjectServiceURL == "" {
+		return ""
+	}
+	return ObjectServiceURL + "/search/documents/room/" + roomID
+}
+
+// sendSearchUpdate sends the update in the background. It stays in the trace
+// of the request that caused it, but is not cancelled when that request ends.
+func sendSearchUpdate(ctx context.Context, method, roomID string, body []byte) {
+	url := searchIndexURL(roomID)
+	if url == "" {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := doSearchUpdate(ctx, method, url, body); err != nil {
+			logging.LogFrom(ctx).WithFields(logrus.Fields{
+				"roomID": roomID,
+				"error":  err.Error(),
+			}).Warn("Failed to update search index")
+		}
+	}()
+}
+
+func doSearchUpdate(ctx context.Context, method, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(auth.ServiceTokenHeader, ServiceToken)
+
+	resp, err := searchClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s", method, url, resp.Status)
+	}
+	return nil
+}
+
+// indexRoomForSearch adds or refreshes a room in the search index
+func indexRoomForSearch(ctx context.Context, room models.Room) {
+	body, _ := json.Marshal(map[string]string{
+		"title":  room.Name,
+		"homeId": strconv.FormatUint(uint64(room.HomeID), 10),
+	})
+	sendSearchUpdate(ctx, http.MethodPut, strconv.FormatUint(uint64(room.ID), 10), body)
+}
+
+// unindexRoomForSearch removes a deleted room from the search index
+func unindexRoomForSearch(ctx context.Context, roomID string) {
+	sendSearchUpdate(ctx, http.MethodDelete, roomID, nil)
+}
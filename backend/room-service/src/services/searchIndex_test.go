@@ -0,0 +1,49 @@
+package services_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"hexagone/room-service/src/services"
+	"hexagone/shared/auth"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateRoomUpdatesSearchIndex(t *testing.T) {
+	setupTestServer()
+	defer clearDatabase()
+
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	objectService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer objectService.Close()
+	services.ObjectServiceURL = objectService.URL
+	defer func() { services.ObjectServiceURL = "" }()
+	services.ServiceToken = "0123456789abcdef0123456789abcdef"
+
+	jsonInput, _ := json.Marshal(map[string]interface{}{"name": "Grenier", "home_id": 3})
+	req := httptest.NewRequest("POST", "/rooms", bytes.NewBuffer(jsonInput))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	select {
+	case r := <-received:
+		assert.Equal(t, "PUT", r.Method)
+		assert.Regexp(t, `^/search/documents/room/\d+$`, r.URL.Path)
+		assert.Equal(t, services.ServiceToken, r.Header.Get(auth.ServiceTokenHeader))
+		assert.JSONEq(t, `{"title": "Grenier", "homeId": "3"}`, string(<-bodies))
+	case <-time.After(2 * time.Second):
+		t.Fatal("the room was not sent to the search index")
+	}
+}
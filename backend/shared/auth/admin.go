@@ -0,0 +1,43 @@
+// Package auth guards the routes only administrators may call. The gateway
+// checks the session and passes the user on in the X-User-ID header; the
+// services ask the user service whether that user is an administrator.
+package auth
+
+import (
+	"errors"
+	"hexagone/shared/logging"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin lets through the requests of administrators only. It fails
+// closed: when the user service cannot tell, the request is refused with 503.
+func RequireAdmin(users *Users) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetHeader(IdentityHeader)
+		if userID == "" {
+			logging.RequestLog(c).Warn("No user ID found in header")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		isAdmin, err := users.IsAdmin(c.Request.Context(), userID)
+		switch {
+		case errors.Is(err, ErrUnknownUser):
+			logging.RequestLog(c).Warn("Failed to get user details")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication failed"})
+			return
+		case err != nil:
+			logging.RequestLog(c).WithError(err).Error("Failed to verify admin status")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to verify admin status"})
+			return
+		case !isAdmin:
+			logging.RequestLog(c).Warn("Non-admin user attempted admin action")
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin privileges required"})
+			return
+		}
+
+		c.Next()
+	}
+}
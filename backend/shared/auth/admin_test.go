@@ -0,0 +1,191 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"hexagone/shared/auth"
+	"hexagone/shared/config"
+	"hexagone/shared/logging"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// userService stands in for the user service: user 1 is an administrator,
+// user 2 is not, and the others do not exist
+type userService struct {
+	*httptest.Server
+	calls  atomic.Int32
+	status atomic.Int32 // answered to every call when set
+	delay  atomic.Int64 // before answering, in nanoseconds
+}
+
+func newUserService(t *testing.T) *userService {
+	s := &userService{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.calls.Add(1)
+		time.Sleep(time.Duration(s.delay.Load()))
+		if status := s.status.Load(); status != 0 {
+			w.WriteHeader(int(status))
+			return
+		}
+
+		var user auth.User
+		switch strings.TrimPrefix(r.URL.Path, "/users/") {
+		case "1":
+			user = auth.User{ID: 1, IsAdmin: true}
+		case "2":
+			user = auth.User{ID: 2}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(gin.H{"data": user})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func setupRouter(users *auth.Users) *gin.Engine {
+	r := gin.New()
+	r.DELETE("/rooms/:id", auth.RequireAdmin(users), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return r
+}
+
+func deleteRoom(r *gin.Engine, userID string) (int, string) {
+	req := httptest.NewRequest("DELETE", "/rooms/1", nil)
+	if userID != "" {
+		req.Header.Set("X-User-ID", userID)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body map[string]string
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body["error"]
+}
+
+func TestRequireAdmin(t *testing.T) {
+	logging.InitLogger()
+	gin.SetMode(gin.TestMode)
+
+	users := newUserService(t)
+	r := setupRouter(auth.NewUsers(config.UserService{URL: users.URL, Timeout: time.Second, RoleTTL: time.Minute}))
+
+	code, _ := deleteRoom(r, "1")
+	assert.Equal(t, http.StatusNoContent, code)
+
+	code, msg := deleteRoom(r, "2")
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Equal(t, "Admin privileges required", msg)
+
+	code, msg = deleteRoom(r, "3")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "Authentication failed", msg)
+
+	code, msg = deleteRoom(r, "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "Authentication required", msg)
+
+	t.Run("Roles are cached", func(t *testing.T) {
+		calls := users.calls.Load()
+		code, _ := deleteRoom(r, "1")
+		assert.Equal(t, http.StatusNoContent, code)
+		code, _ = deleteRoom(r, "2")
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.Equal(t, calls, users.calls.Load())
+
+		// Unknown users are asked about again
+		deleteRoom(r, "3")
+		assert.Equal(t, calls+1, users.calls.Load())
+	})
+}
+
+func TestRequireAdminCacheExpiry(t *testing.T) {
+	logging.InitLogger()
+	gin.SetMode(gin.TestMode)
+
+	users := newUserService(t)
+	r := setupRouter(auth.NewUsers(config.UserService{URL: users.URL, Timeout: time.Second, RoleTTL: 50 * time.Millisecond}))
+
+	deleteRoom(r, "1")
+	deleteRoom(r, "1")
+	assert.Equal(t, int32(1), users.calls.Load())
+
+	time.Sleep(60 * time.Millisecond)
+	deleteRoom(r, "1")
+	assert.Equal(t, int32(2), users.calls.Load())
+}
+
+func TestRequireAdminUserServiceDown(t *testing.T) {
+	logging.InitLogger()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Retried once", func(t *testing.T) {
+		users := newUserService(t)
+		users.status.Store(http.StatusInternalServerError)
+		r := setupRouter(auth.NewUsers(config.UserService{URL: users.URL, Timeout: time.Second, RoleTTL: time.Minute}))
+
+		code, msg := deleteRoom(r, "1")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "Failed to verify admin status", msg)
+		assert.Equal(t, int32(2), users.calls.Load())
+
+		// A role known from before the failure is still trusted
+		users.status.Store(0)
+		code, _ = deleteRoom(r, "1")
+		require.Equal(t, http.StatusNoContent, code)
+		users.status.Store(http.StatusInternalServerError)
+		code, _ = deleteRoom(r, "1")
+		assert.Equal(t, http.StatusNoContent, code)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		users := newUserService(t)
+		users.delay.Store(int64(100 * time.Millisecond))
+		r := setupRouter(auth.NewUsers(config.UserService{URL: users.URL, Timeout: 20 * time.Millisecond, RoleTTL: time.Minute}))
+
+		start := time.Now()
+		code, _ := deleteRoom(r, "1")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("Breaker opens", func(t *testing.T) {
+		users := newUserService(t)
+		users.status.Store(http.StatusBadGateway)
+		r := setupRouter(auth.NewUsers(config.UserService{URL: users.URL, Timeout: time.Second, RoleTTL: time.Minute}))
+
+		for i := 0; i < 5; i++ {
+			code, _ := deleteRoom(r, "1")
+			require.Equal(t, http.StatusServiceUnavailable, code)
+		}
+		calls := users.calls.Load()
+
+		// The user service is no longer called, even once it is back
+		users.status.Store(0)
+		code, msg := deleteRoom(r, "1")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "Failed to verify admin status", msg)
+		assert.Equal(t, calls, users.calls.Load())
+	})
+
+	t.Run("Unknown users do not open the breaker", func(t *testing.T) {
+		users := newUserService(t)
+		r := setupRouter(auth.NewUsers(config.UserService{URL: users.URL, Timeout: time.Second, RoleTTL: time.Minute}))
+
+		for i := 0; i < 10; i++ {
+			deleteRoom(r, "3")
+		}
+		code, _ := deleteRoom(r, "1")
+		assert.Equal(t, http.StatusNoContent, code)
+	})
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"hexagone/shared/logging"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServiceTokenHeader carries the token the gateway and the services present
+// to each other
+const ServiceTokenHeader = "X-Service-Token"
+
+// IdentityHeader carries the ID of the signed-in user, set by the gateway
+const IdentityHeader = "X-User-ID"
+
+// hasServiceToken reports whether the request carries the service token
+func hasServiceToken(c *gin.Context, token string) bool {
+	given := c.GetHeader(ServiceTokenHeader)
+	return token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// RequireServiceToken keeps a route for the other services, which present
+// the internal service token. The gateway drops the header from client
+// requests.
+func RequireServiceToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasServiceToken(c, token) {
+			logging.RequestLog(c).Warn("Internal route called without a valid service token")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Service token required"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// TrustIdentity drops the identity header of requests that do not carry the
+// service token. Only the gateway, which checked the session, can then tell a
+// service who is calling, even when the service is reached directly.
+func TrustIdentity(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(IdentityHeader) != "" && !hasServiceToken(c, token) {
+			logging.RequestLog(c).WithField("path", c.Request.URL.Path).Warn("Dropped identity header without a service token")
+			c.Request.Header.Del(IdentityHeader)
+		}
+
+		c.Next()
+	}
+}
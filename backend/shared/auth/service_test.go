@@ -0,0 +1,77 @@
+package auth_test
+
+import (
+	"hexagone/shared/auth"
+	"hexagone/shared/logging"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireServiceToken(t *testing.T) {
+	logging.InitLogger()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/search/documents/:kind/:id", auth.RequireServiceToken("0123456789abcdef0123456789abcdef"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name         string
+		token        string
+		expectedCode int
+	}{
+		{"Valid Token", "0123456789abcdef0123456789abcdef", http.StatusOK},
+		{"Wrong Token", "0123456789abcdef0123456789abcdeX", http.StatusUnauthorized},
+		{"No Token", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/search/documents/room/1", nil)
+			if tt.token != "" {
+				req.Header.Set(auth.ServiceTokenHeader, tt.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestTrustIdentity(t *testing.T) {
+	logging.InitLogger()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(auth.TrustIdentity("0123456789abcdef0123456789abcdef"))
+	r.GET("/me/reservations", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetHeader(auth.IdentityHeader))
+	})
+
+	tests := []struct {
+		name         string
+		token        string
+		expectedUser string
+	}{
+		{"Signed By The Gateway", "0123456789abcdef0123456789abcdef", "7"},
+		{"Wrong Token", "0123456789abcdef0123456789abcdeX", ""},
+		{"No Token", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me/reservations", nil)
+			req.Header.Set(auth.IdentityHeader, "7")
+			if tt.token != "" {
+				req.Header.Set(auth.ServiceTokenHeader, tt.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expectedUser, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hexagone/shared/config"
+	"hexagone/shared/httpclient"
+	"hexagone/shared/logging"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sony/gobreaker"
+)
+
+// The user service is asked up to attempts times, retryDelay apart, before a
+// lookup fails
+const (
+	attempts   = 2
+	retryDelay = 100 * time.Millisecond
+)
+
+// After breakerFailures lookups in a row fail, the breaker opens and the
+// lookups fail at once for breakerOpen, before one is let through to see
+// whether the user service is back
+const (
+	breakerFailures = 5
+	breakerOpen     = 30 * time.Second
+)
+
+var (
+	// ErrUnknownUser is returned for the users the user service does not know
+	ErrUnknownUser = errors.New("unknown user")
+	// ErrUnavailable is returned when the user service cannot tell the role
+	// of a user: it failed, timed out, or the breaker is open
+	ErrUnavailable = errors.New("user service is unavailable")
+)
+
+// User is the part of a user of the user service the roles are read from
+type User struct {
+	ID      uint `json:"id"`
+	IsAdmin bool `json:"isAdmin"`
+}
+
+// Users looks up the roles of users in the user service, and remembers them
+// for a short while so that every admin request does not cost a call
+type Users struct {
+	base    string
+	client  *http.Client
+	ttl     time.Duration
+	breaker *gobreaker.CircuitBreaker
+
+	mu    sync.Mutex
+	roles map[string]role
+}
+
+type role struct {
+	isAdmin bool
+	expires time.Time
+}
+
+// NewUsers returns a client of the user service at cfg.URL
+func NewUsers(cfg config.UserService) *Users {
+	return &Users{
+		base:   cfg.URL,
+		client: httpclient.New(cfg.Timeout),
+		ttl:    cfg.RoleTTL,
+		breaker: gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name:    "user-service",
+			Timeout: breakerOpen,
+			ReadyToTrip: func(counts gobreaker.Counts) bool {
+				return counts.ConsecutiveFailures >= breakerFailures
+			},
+			// Only the user service failing counts: not an unknown user, nor a
+			// caller hanging up
+			IsSuccessful: func(err error) bool {
+				return err == nil || errors.Is(err, ErrUnknownUser) || errors.Is(err, context.Canceled)
+			},
+			OnStateChange: func(name string, from, to gobreaker.State) {
+				logging.Log.WithFields(logrus.Fields{
+					"service": name,
+					"from":    from.String(),
+					"to":      to.String(),
+				}).Warn("User service circuit breaker changed state")
+			},
+		}),
+		roles: map[string]role{},
+	}
+}
+
+// IsAdmin tells whether a user is an administrator. The error is
+// ErrUnknownUser or wraps ErrUnavailable.
+func (u *Users) IsAdmin(ctx context.Context, userID string) (bool, error) {
+	if isAdmin, ok := u.cached(userID); ok {
+		return isAdmin, nil
+	}
+
+	result, err := u.breaker.Execute(func() (interface{}, error) {
+		return u.lookup(ctx, userID)
+	})
+	if errors.Is(err, ErrUnknownUser) {
+		return false, err
+	}
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	isAdmin := result.(bool)
+	u.remember(userID, isAdmin)
+	return isAdmin, nil
+}
+
+func (u *Users) cached(userID string) (bool, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	role, ok := u.roles[userID]
+	if !ok || time.Now().After(role.expires) {
+		return false, false
+	}
+	return role.isAdmin, true
+}
+
+func (u *Users) remember(userID string, isAdmin bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.roles[userID] = role{isAdmin: isAdmin, expires: time.Now().Add(u.ttl)}
+}
+
+// lookup asks the user service, again after a short delay if it failed
+func (u *Users) lookup(ctx context.Context, userID string) (bool, error) {
+	for attempt := 1; ; attempt++ {
+		isAdmin, err := u.get(ctx, userID)
+		if err == nil || errors.Is(err, ErrUnknownUser) || attempt == attempts || ctx.Err() != nil {
+			return isAdmin, err
+		}
+		logging.LogFrom(ctx).WithError(err).Warn("Failed to reach user service, retrying")
+
+		select {
+		case <-time.After(retryDelay):
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
+
+func (u *Users) get(ctx context.Context, userID string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.base+"/users/"+url.PathEscape(userID), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return false, ErrUnknownUser
+	default:
+		return false, fmt.Errorf("GET /users/%s: %s", userID, resp.Status)
+	}
+
+	var response struct {
+		Data User `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return false, fmt.Errorf("decode user: %w", err)
+	}
+	return response.Data.IsAdmin, nil
+}
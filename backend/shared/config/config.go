@@ -0,0 +1,181 @@
+// Package config reads the settings every service shares from the
+// environment, and provides the helpers each service reads its own settings
+// with. Every helper appends to errs rather than stopping at the first invalid
+// setting, so that a service can report them all at once.
+package config
+
+import (
+	"fmt"
+	"hexagone/shared/logging"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Server bounds how long the HTTP server waits on clients and on itself
+type Server struct {
+	ReadTimeout     time.Duration // HTTP_READ_TIMEOUT, reading a whole request
+	WriteTimeout    time.Duration // HTTP_WRITE_TIMEOUT, handling a request and writing its response
+	IdleTimeout     time.Duration // HTTP_IDLE_TIMEOUT, keeping an idle connection open
+	ShutdownTimeout time.Duration // SHUTDOWN_TIMEOUT, draining in-flight requests on SIGTERM
+}
+
+// LoadServer reads the timeouts of the HTTP server
+func LoadServer(errs *[]error) Server {
+	return Server{
+		ReadTimeout:     Duration("HTTP_READ_TIMEOUT", 30*time.Second, errs),
+		WriteTimeout:    Duration("HTTP_WRITE_TIMEOUT", 60*time.Second, errs),
+		IdleTimeout:     Duration("HTTP_IDLE_TIMEOUT", 120*time.Second, errs),
+		ShutdownTimeout: Duration("SHUTDOWN_TIMEOUT", 15*time.Second, errs),
+	}
+}
+
+// Logging is how much the service logs, and in which format
+type Logging struct {
+	Level  string // LOG_LEVEL: debug, info, warn or error
+	Format string // LOG_FORMAT: json or text
+}
+
+// LoadLogging reads the level and format of the logs
+func LoadLogging(errs *[]error) Logging {
+	return Logging{
+		Level:  OneOf("LOG_LEVEL", "info", []string{"debug", "info", "warn", "error"}, errs),
+		Format: OneOf("LOG_FORMAT", logging.FormatJSON, []string{logging.FormatJSON, logging.FormatText}, errs),
+	}
+}
+
+// UserService is how a service asks the user service for the roles of its
+// callers
+type UserService struct {
+	URL     string        // USER_SERVICE_URL, or the compose service on USER_PORT
+	Timeout time.Duration // USER_SERVICE_TIMEOUT, each call to the user service
+	RoleTTL time.Duration // USER_ROLE_CACHE_TTL, how long a role is trusted without asking again
+}
+
+// LoadUserService reads the address of the user service and how it is called
+func LoadUserService(errs *[]error) UserService {
+	return UserService{
+		URL:     RequiredServiceURL("USER_SERVICE_URL", "user-service", "USER_PORT", errs),
+		Timeout: Duration("USER_SERVICE_TIMEOUT", 3*time.Second, errs),
+		RoleTTL: Duration("USER_ROLE_CACHE_TTL", 30*time.Second, errs),
+	}
+}
+
+// ServiceURL is the base URL of another service: urlEnv when set (tests and
+// non-compose deployments), otherwise the compose service host on portEnv.
+// It is empty when neither is configured.
+func ServiceURL(urlEnv, host, portEnv string) string {
+	if base := os.Getenv(urlEnv); base != "" {
+		return base
+	}
+	if port := os.Getenv(portEnv); port != "" {
+		return "http://" + host + ":" + port
+	}
+	return ""
+}
+
+// RequiredServiceURL reads the base URL of another service like ServiceURL,
+// reporting it when neither variable is set or urlEnv is not an http or
+// https address
+func RequiredServiceURL(urlEnv, host, portEnv string, errs *[]error) string {
+	base := ServiceURL(urlEnv, host, portEnv)
+	if base == "" {
+		*errs = append(*errs, fmt.Errorf("%s or %s is required", urlEnv, portEnv))
+		return base
+	}
+	if parsed, err := url.Parse(base); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		*errs = append(*errs, fmt.Errorf("%s must be an http or https address, got %q", urlEnv, base))
+	}
+	return strings.TrimRight(base, "/")
+}
+
+// minServiceTokenLength keeps the internal service token out of reach of guessing
+const minServiceTokenLength = 32
+
+// ServiceToken reads SERVICE_TOKEN, the secret the services present to each
+// other on their internal routes
+func ServiceToken(errs *[]error) string {
+	token := Required("SERVICE_TOKEN", errs)
+	if token != "" && len(token) < minServiceTokenLength {
+		*errs = append(*errs, fmt.Errorf("SERVICE_TOKEN must be at least %d characters long", minServiceTokenLength))
+	}
+	return token
+}
+
+// Required reads a setting that must be set
+func Required(name string, errs *[]error) string {
+	value := os.Getenv(name)
+	if value == "" {
+		*errs = append(*errs, fmt.Errorf("%s is required", name))
+	}
+	return value
+}
+
+// BaseURL reads a required absolute http or https address, returned without
+// its trailing slash so that paths can be appended to it
+func BaseURL(name string, errs *[]error) string {
+	value := Required(name, errs)
+	if value == "" {
+		return value
+	}
+	if parsed, err := url.Parse(value); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		*errs = append(*errs, fmt.Errorf("%s must be an http or https address, got %q", name, value))
+	}
+	return strings.TrimRight(value, "/")
+}
+
+// Port reads a required port number
+func Port(name string, errs *[]error) string {
+	value := Required(name, errs)
+	if value == "" {
+		return value
+	}
+	if number, err := strconv.Atoi(value); err != nil || number < 1 || number > 65535 {
+		*errs = append(*errs, fmt.Errorf("%s must be a port number, got %q", name, value))
+	}
+	return value
+}
+
+// Positive reads a positive number, fallback when the variable is unset
+func Positive(name string, fallback int, errs *[]error) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		*errs = append(*errs, fmt.Errorf("%s must be a positive number, got %q", name, value))
+		return fallback
+	}
+	return number
+}
+
+// Duration reads a duration such as 30s, fallback when the variable is unset
+func Duration(name string, fallback time.Duration, errs *[]error) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		*errs = append(*errs, fmt.Errorf("%s must be a positive duration such as 30s, got %q", name, value))
+		return fallback
+	}
+	return d
+}
+
+// OneOf reads a setting limited to a few values, fallback when it is unset
+func OneOf(name, fallback string, allowed []string, errs *[]error) string {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	if !slices.Contains(allowed, value) {
+		*errs = append(*errs, fmt.Errorf("%s must be one of %s, got %q", name, strings.Join(allowed, ", "), value))
+		return fallback
+	}
+	return value
+}
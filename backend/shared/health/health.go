@@ -0,0 +1,133 @@
+// Package health answers the liveness and readiness probes of the services
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hexagone/shared/logging"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Status of a service and of each of its dependencies
+const (
+	OK   = "ok"
+	Down = "down"
+)
+
+// checkTimeout bounds each readiness check unless it has a timeout of its
+// own, so that a hung dependency makes the probe fail rather than time out
+const checkTimeout = 2 * time.Second
+
+// client leaves the timeout to the context of the check
+var client = &http.Client{}
+
+// Check tells whether a dependency answers, such as a database or a service
+type Check func(ctx context.Context) error
+
+// Dependency is a check with a timeout of its own, such as a service the
+// caller already waits on for longer than checkTimeout
+type Dependency struct {
+	Check   Check
+	Timeout time.Duration
+}
+
+// DependencyCheck is the outcome of one readiness check
+type DependencyCheck struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Healthz answers GET /healthz: the process is up and serving requests
+func Healthz(service string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": OK, "service": service})
+	}
+}
+
+// Readyz answers GET /readyz: runs the checks and answers 503 when any of
+// them fails
+func Readyz(service string, checks map[string]Check) gin.HandlerFunc {
+	dependencies := make(map[string]Dependency, len(checks))
+	for name, check := range checks {
+		dependencies[name] = Dependency{Check: check, Timeout: checkTimeout}
+	}
+	return ReadyzDependencies(service, dependencies)
+}
+
+// ReadyzDependencies is Readyz with a timeout per check
+func ReadyzDependencies(service string, dependencies map[string]Dependency) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, results := run(c.Request.Context(), dependencies)
+		code := http.StatusOK
+		if status != OK {
+			code = http.StatusServiceUnavailable
+			logging.RequestLog(c).WithField("checks", results).Warn("Service is not ready")
+		}
+		c.JSON(code, gin.H{"status": status, "service": service, "checks": results})
+	}
+}
+
+// run runs the checks at the same time and returns OK when they all pass
+func run(ctx context.Context, dependencies map[string]Dependency) (string, map[string]DependencyCheck) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	status, results := OK, map[string]DependencyCheck{}
+
+	for name, dependency := range dependencies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			timeout := dependency.Timeout
+			if timeout <= 0 {
+				timeout = checkTimeout
+			}
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := dependency.Check(ctx)
+			if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("no answer within %s", timeout)
+			}
+			result := DependencyCheck{Status: OK, LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				result.Status, result.Error = Down, err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[name] = result
+			if err != nil {
+				status = Down
+			}
+		}()
+	}
+	wg.Wait()
+	return status, results
+}
+
+// PingService checks that another service is alive through its /healthz
+func PingService(ctx context.Context, base string) error {
+	if base == "" {
+		return errors.New("service address is not configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/healthz", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET /healthz: %s", resp.Status)
+	}
+	return nil
+}
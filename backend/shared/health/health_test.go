@@ -0,0 +1,104 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hexagone/shared/health"
+	"hexagone/shared/logging"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type readiness struct {
+	Status  string                            `json:"status"`
+	Service string                            `json:"service"`
+	Checks  map[string]health.DependencyCheck `json:"checks"`
+}
+
+func probe(t *testing.T, r *gin.Engine, path string) (int, readiness) {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+	var body readiness
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func TestHealth(t *testing.T) {
+	logging.InitLogger()
+	gin.SetMode(gin.TestMode)
+
+	userStatus := http.StatusOK
+	userService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/healthz", r.URL.Path)
+		w.WriteHeader(userStatus)
+	}))
+	defer userService.Close()
+
+	var databaseErr error
+	r := gin.New()
+	r.GET("/healthz", health.Healthz("room-service"))
+	r.GET("/readyz", health.Readyz("room-service", map[string]health.Check{
+		"sqlite":       func(ctx context.Context) error { return databaseErr },
+		"user-service": func(ctx context.Context) error { return health.PingService(ctx, userService.URL) },
+		"search":       func(ctx context.Context) error { return health.PingService(ctx, "") },
+	}))
+
+	code, body := probe(t, r, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.OK, body.Status)
+	assert.Equal(t, "room-service", body.Service)
+
+	// The service not configured keeps it unready
+	code, body = probe(t, r, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.Down, body.Status)
+	assert.Equal(t, health.OK, body.Checks["sqlite"].Status)
+	assert.Equal(t, health.OK, body.Checks["user-service"].Status)
+	assert.Equal(t, "service address is not configured", body.Checks["search"].Error)
+
+	// Each failing dependency is reported with its error
+	databaseErr = errors.New("database is closed")
+	userStatus = http.StatusInternalServerError
+	_, body = probe(t, r, "/readyz")
+	assert.Equal(t, "database is closed", body.Checks["sqlite"].Error)
+	assert.Equal(t, "GET /healthz: 500 Internal Server Error", body.Checks["user-service"].Error)
+}
+
+func TestReadyzDependencies(t *testing.T) {
+	logging.InitLogger()
+	gin.SetMode(gin.TestMode)
+
+	// Each check waits for its dependency within its own timeout
+	wait := func(d time.Duration) health.Check {
+		return func(ctx context.Context) error {
+			select {
+			case <-time.After(d):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	r := gin.New()
+	r.GET("/readyz", health.ReadyzDependencies("gateway-service", map[string]health.Dependency{
+		"slow-service":  {Check: wait(50 * time.Millisecond), Timeout: time.Second},
+		"hung-service":  {Check: wait(time.Minute), Timeout: 20 * time.Millisecond},
+		"quick-service": {Check: wait(0)},
+	}))
+
+	code, body := probe(t, r, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "gateway-service", body.Service)
+	assert.Equal(t, health.OK, body.Checks["slow-service"].Status)
+	assert.Equal(t, health.OK, body.Checks["quick-service"].Status)
+	assert.Equal(t, health.Down, body.Checks["hung-service"].Status)
+	assert.Equal(t, "no answer within 20ms", body.Checks["hung-service"].Error)
+}
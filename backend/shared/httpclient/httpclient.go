@@ -0,0 +1,46 @@
+// Package httpclient builds the clients the services call each other with.
+// A call made while handling a request stays in the request's trace, through
+// the W3C traceparent header, and carries its X-Request-ID, so that the
+// service called logs under the same ID.
+package httpclient
+
+import (
+	"hexagone/shared/logging"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New returns a client giving up on a call after timeout, headers and body
+// included. A timeout of 0 leaves the deadline to the context of each call.
+func New(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: Transport(nil)}
+}
+
+// Transport traces the requests sent through base and passes the trace and
+// the request ID on. Requests sent outside of a trace are not traced, and
+// those sent outside of a request keep their headers. A nil base stands for
+// http.DefaultTransport.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	traced := otelhttp.NewTransport(base, otelhttp.WithFilter(func(r *http.Request) bool {
+		return trace.SpanContextFromContext(r.Context()).IsValid()
+	}))
+	return requestIDTransport{base: traced}
+}
+
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+func (t requestIDTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	// The ID the service settled on replaces any the caller sent, such as a
+	// malformed one a client passed to the gateway's reverse proxy
+	if id := logging.RequestIDFrom(r.Context()); id != "" {
+		// A RoundTripper must not modify the request it is given
+		r = r.Clone(r.Context())
+		r.Header.Set(logging.RequestIDHeader, id)
+	}
+	return t.base.RoundTrip(r)
+}
@@ -0,0 +1,70 @@
+package httpclient_test
+
+import (
+	"context"
+	"hexagone/shared/httpclient"
+	"hexagone/shared/logging"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestClient(t *testing.T) {
+	logging.InitLogger()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	provider := sdktrace.NewTracerProvider()
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	// The service called records what it received
+	var requestID, traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get(logging.RequestIDHeader)
+		traceparent = r.Header.Get("traceparent")
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer upstream.Close()
+	client := httpclient.New(100 * time.Millisecond)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(logging.RequestID())
+	r.GET("/", func(c *gin.Context) {
+		ctx, span := provider.Tracer("test").Start(c.Request.Context(), "request")
+		defer span.End()
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+		req.Header.Set(logging.RequestIDHeader, "client id")
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	})
+
+	// Within a request, the ID and the trace are passed on
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(logging.RequestIDHeader, "gateway-42")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "gateway-42", requestID)
+	assert.Contains(t, traceparent, "00-")
+
+	// Outside of one, the request goes out as it is
+	req, _ = http.NewRequest(http.MethodGet, upstream.URL, nil)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Empty(t, requestID)
+	assert.Empty(t, traceparent)
+
+	// and the client gives up on a slow service
+	_, err = client.Get(upstream.URL + "/slow")
+	assert.Error(t, err)
+}
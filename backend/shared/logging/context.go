@@ -0,0 +1,36 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx carrying the logger of the request being
+// handled, as set up by RequestID
+func WithLogger(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey{}, entry)
+}
+
+// LogFrom returns the logger of the request ctx belongs to, with its request
+// ID, user, route and trace, or Log itself outside of a request
+func LogFrom(ctx context.Context) *logrus.Entry {
+	if entry, ok := ctx.Value(loggerKey{}).(*logrus.Entry); ok {
+		return entry
+	}
+	return logrus.NewEntry(Log)
+}
+
+// RequestLog returns the logger of the request c handles
+func RequestLog(c *gin.Context) *logrus.Entry {
+	return LogFrom(c.Request.Context())
+}
+
+// AddLogFields adds fields to the logger of the request c handles, for what is
+// only known once the request is under way, such as the user of its session
+func AddLogFields(c *gin.Context, fields logrus.Fields) {
+	c.Request = c.Request.WithContext(WithLogger(c.Request.Context(), RequestLog(c).WithFields(fields)))
+}
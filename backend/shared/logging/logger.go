@@ -0,0 +1,49 @@
+// Package logging is the structured logger of the services. Every line is
+// cleared of email addresses and secrets, and the lines logged while handling
+// a request carry its ID, user, route and trace.
+package logging
+
+import (
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+var Log *logrus.Logger
+
+// Formats accepted in LOG_FORMAT
+const (
+	FormatJSON = "json" // One JSON object per line, for log collectors
+	FormatText = "text" // key=value pairs, easier to read in a terminal
+)
+
+// InitLogger sets up Log with JSON output at info level, until Configure
+// applies the settings of the environment
+func InitLogger() {
+	Log = logrus.New()
+
+	// Set the output to stdout
+	Log.Out = os.Stdout
+
+	// Set the log level (info by default)
+	Log.SetLevel(logrus.InfoLevel)
+
+	// Use JSON formatter for structured logging
+	Log.SetFormatter(&logrus.JSONFormatter{})
+
+	// Emails and secrets never reach the output
+	Log.AddHook(redactHook{})
+}
+
+// Configure sets the level and format of Log, as read from LOG_LEVEL and
+// LOG_FORMAT by config.Load
+func Configure(level, format string) {
+	if parsed, err := logrus.ParseLevel(level); err == nil {
+		Log.SetLevel(parsed)
+	}
+	if format == FormatText {
+		Log.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	} else {
+		Log.SetFormatter(&logrus.JSONFormatter{})
+	}
+}
@@ -0,0 +1,73 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"hexagone/shared/logging"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capture sends the output of logging.Log to a buffer for the test
+func capture(t *testing.T) *bytes.Buffer {
+	logging.InitLogger()
+	var out bytes.Buffer
+	logging.Log.Out = &out
+	return &out
+}
+
+func TestRedaction(t *testing.T) {
+	out := capture(t)
+
+	logging.Log.WithFields(logrus.Fields{
+		"email":         "alice@example.com",
+		"password":      "hunter2",
+		"adminKey":      "letmein",
+		"Authorization": "Bearer abc",
+		"error":         errors.New("no user with email bob@example.org"),
+		"roomID":        7,
+	}).Info("Login for alice@example.com")
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "Login for a***@example.com", entry["msg"])
+	assert.Equal(t, "a***@example.com", entry["email"])
+	assert.Equal(t, "[REDACTED]", entry["password"])
+	assert.Equal(t, "[REDACTED]", entry["adminKey"])
+	assert.Equal(t, "[REDACTED]", entry["Authorization"])
+	assert.Equal(t, "no user with email b***@example.org", entry["error"])
+	assert.Equal(t, float64(7), entry["roomID"])
+	assert.NotContains(t, out.String(), "hunter2")
+}
+
+func TestConfigure(t *testing.T) {
+	out := capture(t)
+
+	logging.Configure("warn", logging.FormatText)
+	logging.Log.Info("Hidden")
+	logging.Log.WithField("email", "carol@example.com").Warn("Shown")
+
+	assert.NotContains(t, out.String(), "Hidden")
+	assert.Contains(t, out.String(), `msg=Shown`)
+	assert.Contains(t, out.String(), `email="c***@example.com"`)
+}
+
+func TestLogFrom(t *testing.T) {
+	out := capture(t)
+
+	// Outside of a request, the global logger
+	logging.LogFrom(context.Background()).Info("Plain")
+	// Within one, the logger the middleware put in its context
+	ctx := logging.WithLogger(context.Background(), logging.Log.WithField("requestId", "abc"))
+	logging.LogFrom(ctx).Info("Scoped")
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	assert.NotContains(t, string(lines[0]), "requestId")
+	assert.Contains(t, string(lines[1]), `"requestId":"abc"`)
+}
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// redacted replaces the value of the fields holding a secret
+const redacted = "[REDACTED]"
+
+// secretFields are the words that mark a field as holding a secret, matched
+// case-insensitively anywhere in its name, such as "adminKey" or "Authorization"
+var secretFields = []string{"password", "secret", "token", "adminkey", "signingkey", "authorization", "cookie"}
+
+var emailPattern = regexp.MustCompile(`([A-Za-z0-9._%+-])[A-Za-z0-9._%+-]*@([A-Za-z0-9.-]+\.[A-Za-z]{2,})`)
+
+// RedactEmails masks the email addresses found in s, keeping the first letter
+// and the domain: alice@example.com becomes a***@example.com
+func RedactEmails(s string) string {
+	return emailPattern.ReplaceAllString(s, "$1***@$2")
+}
+
+func isSecretField(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range secretFields {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactHook removes secrets and email addresses from every entry before it
+// is written, whichever handler logged them
+type redactHook struct{}
+
+func (redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (redactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = RedactEmails(entry.Message)
+	for name, value := range entry.Data {
+		if isSecretField(name) {
+			entry.Data[name] = redacted
+			continue
+		}
+		switch value := value.(type) {
+		case string:
+			entry.Data[name] = RedactEmails(value)
+		case error:
+			entry.Data[name] = RedactEmails(value.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the ID of a request from the gateway to every
+// service it calls, so that their logs can be put side by side. Clients may
+// send one of their own, and read it back from the response.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// RequestID gives every request an ID: the X-Request-ID it came with, or a new
+// one if it had none. The ID is sent back in the response and the request's
+// context gets a logger carrying it, along with the user, the route and the
+// trace; handlers log through RequestLog(c). Register it after the tracing
+// middleware, so that the trace has started.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+
+		ctx := c.Request.Context()
+		fields := logrus.Fields{"requestId": id}
+		// Set by the gateway once it has checked the session; on the
+		// gateway itself, RequireSession adds the user later on
+		if userID := c.GetHeader("X-User-ID"); userID != "" {
+			fields["userId"] = userID
+		}
+		if route := c.FullPath(); route != "" {
+			fields["route"] = route
+		}
+		if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+			fields["traceId"] = span.TraceID().String()
+		}
+
+		ctx = context.WithValue(ctx, requestIDKey{}, id)
+		c.Request = c.Request.WithContext(WithLogger(ctx, Log.WithFields(fields)))
+		c.Next()
+	}
+}
+
+// RequestIDFrom returns the ID of the request ctx belongs to, empty outside of
+// a request
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID accepts the IDs of up to 128 letters, digits, dots, dashes
+// and underscores, so that a caller cannot slip anything else into the logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,70 @@
+package logging_test
+
+import (
+	"context"
+	"encoding/json"
+	"hexagone/shared/logging"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// withSpan puts in the request the span a tracing middleware would have started
+func withSpan(c *gin.Context) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	span := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+	c.Request = c.Request.WithContext(trace.ContextWithSpanContext(c.Request.Context(), span))
+}
+
+func TestRequestID(t *testing.T) {
+	out := capture(t)
+
+	var requestID string
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(withSpan, logging.RequestID())
+	r.GET("/rooms/:id", func(c *gin.Context) {
+		requestID = logging.RequestIDFrom(c.Request.Context())
+		logging.RequestLog(c).Info("Handled")
+		c.Status(http.StatusOK)
+	})
+
+	// An ID set by the caller is kept and logged
+	req := httptest.NewRequest("GET", "/rooms/1", nil)
+	req.Header.Set(logging.RequestIDHeader, "gateway-42")
+	req.Header.Set("X-User-ID", "7")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "gateway-42", w.Header().Get(logging.RequestIDHeader))
+	assert.Equal(t, "gateway-42", requestID)
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "gateway-42", entry["requestId"])
+	assert.Equal(t, "7", entry["userId"])
+	assert.Equal(t, "/rooms/:id", entry["route"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entry["traceId"])
+
+	// A missing or malformed ID is replaced by a new one
+	for _, id := range []string{"", "bad id\n{\"admin\":true}", strings.Repeat("a", 129)} {
+		req := httptest.NewRequest("GET", "/rooms/1", nil)
+		req.Header.Set(logging.RequestIDHeader, id)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		generated := w.Header().Get(logging.RequestIDHeader)
+		assert.Len(t, generated, 32)
+		assert.NotEqual(t, id, generated)
+		assert.Equal(t, generated, requestID)
+	}
+
+	// Outside of a request there is no ID
+	assert.Empty(t, logging.RequestIDFrom(context.Background()))
+}
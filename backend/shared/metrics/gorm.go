@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+)
+
+// startedKey holds when a statement started, on the statement itself
+const startedKey = "metrics:started"
+
+var dbDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "db_query_duration_seconds",
+	Help:    "Time taken by database statements, by operation, table and status.",
+	Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14), // 0.5ms to 4s
+}, []string{"operation", "table", "status"})
+
+// InstrumentGORM times the statements run through db. A record that is not
+// found is not counted as an error.
+func InstrumentGORM(db *gorm.DB) error {
+	start := func(tx *gorm.DB) {
+		tx.InstanceSet(startedKey, time.Now())
+	}
+	observe := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			started, ok := tx.InstanceGet(startedKey)
+			if !ok {
+				return
+			}
+			status := "ok"
+			if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+				status = "error"
+			}
+			dbDuration.WithLabelValues(operation, tx.Statement.Table, status).Observe(time.Since(started.(time.Time)).Seconds())
+		}
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("metrics:before_create", start),
+		callbacks.Create().After("gorm:create").Register("metrics:after_create", observe("create")),
+		callbacks.Query().Before("gorm:query").Register("metrics:before_query", start),
+		callbacks.Query().After("gorm:query").Register("metrics:after_query", observe("query")),
+		callbacks.Update().Before("gorm:update").Register("metrics:before_update", start),
+		callbacks.Update().After("gorm:update").Register("metrics:after_update", observe("update")),
+		callbacks.Delete().Before("gorm:delete").Register("metrics:before_delete", start),
+		callbacks.Delete().After("gorm:delete").Register("metrics:after_delete", observe("delete")),
+		callbacks.Row().Before("gorm:row").Register("metrics:before_row", start),
+		callbacks.Row().After("gorm:row").Register("metrics:after_row", observe("row")),
+		callbacks.Raw().Before("gorm:raw").Register("metrics:before_raw", start),
+		callbacks.Raw().After("gorm:raw").Register("metrics:after_raw", observe("raw")),
+	)
+}
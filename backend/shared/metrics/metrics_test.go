@@ -0,0 +1,111 @@
+package metrics_test
+
+import (
+	"context"
+	"hexagone/shared/metrics"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func scrape(t *testing.T, r *gin.Engine) string {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(metrics.Middleware())
+	r.GET("/metrics", metrics.Handler())
+	r.GET("/things/:id", func(c *gin.Context) {
+		if c.Param("id") == "0" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": c.Param("id")})
+	})
+	r.NoRoute(func(c *gin.Context) {
+		if c.Request.URL.Path == "/proxied/3" {
+			c.Set(metrics.RouteKey, "/proxied")
+		}
+		c.Status(http.StatusNotFound)
+	})
+
+	for _, path := range []string{"/things/1", "/things/2", "/things/0", "/wp-login.php", "/proxied/3"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	body := scrape(t, r)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/things/:id",status="200"} 2`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/things/:id",status="404"} 1`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="GET",route="/things/:id",status="200"} 2`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/proxied",status="404"} 1`)
+	assert.NotContains(t, body, "wp-login")
+}
+
+func TestInstrumentGORM(t *testing.T) {
+	type Thing struct {
+		ID   uint
+		Name string
+	}
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, metrics.InstrumentGORM(db))
+	require.NoError(t, db.AutoMigrate(&Thing{}))
+
+	require.NoError(t, db.Create(&Thing{Name: "lamp"}).Error)
+	var thing Thing
+	require.NoError(t, db.First(&thing, 1).Error)
+	assert.Error(t, db.First(&thing, 2).Error)
+	assert.Error(t, db.Exec("SELECT * FROM missing").Error)
+
+	r := gin.New()
+	r.GET("/metrics", metrics.Handler())
+	body := scrape(t, r)
+	assert.Contains(t, body, `db_query_duration_seconds_count{operation="create",status="ok",table="things"} 1`)
+	assert.Contains(t, body, `db_query_duration_seconds_count{operation="query",status="ok",table="things"} 2`, "a record not found is not an error")
+	assert.Contains(t, body, `db_query_duration_seconds_count{operation="raw",status="error",table=""} 1`)
+}
+
+func TestInstrumentRedis(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	metrics.InstrumentRedis(client)
+
+	require.NoError(t, client.Set(ctx, "object", "lamp", 0).Err())
+	require.NoError(t, client.Get(ctx, "object").Err())
+	assert.Equal(t, redis.Nil, client.Get(ctx, "missing").Err())
+	assert.Error(t, client.Incr(ctx, "object").Err())
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, "room:1:objects", "object")
+		pipe.SAdd(ctx, "room:2:objects", "object")
+		return nil
+	})
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.GET("/metrics", metrics.Handler())
+	body := scrape(t, r)
+	assert.Contains(t, body, `redis_command_duration_seconds_count{command="set",status="ok"} 1`)
+	assert.Contains(t, body, `redis_command_duration_seconds_count{command="get",status="ok"} 2`, "a missing key is not an error")
+	assert.Contains(t, body, `redis_command_duration_seconds_count{command="incr",status="error"} 1`)
+	assert.Contains(t, body, `redis_command_duration_seconds_count{command="pipeline",status="ok"} 1`)
+}
@@ -0,0 +1,27 @@
+// Package middleware holds the gin middleware the services share
+package middleware
+
+import (
+	"hexagone/shared/logging"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// Origins are the frontend addresses of docker-compose
+var Origins = []string{"http://localhost", "http://localhost:80", "http://frontend", "http://frontend:80"}
+
+// SetupCORS lets the frontend call a service directly. Behind the gateway it
+// stays out of the way: the gateway answers for CORS and drops the Origin
+// header of the requests it forwards.
+func SetupCORS() gin.HandlerFunc {
+	return cors.New(cors.Config{
+		AllowOrigins:     Origins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "X-User-ID", "Authorization", "X-Requested-With", logging.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", logging.RequestIDHeader},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	})
+}
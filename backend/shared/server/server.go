@@ -0,0 +1,61 @@
+// Package server runs the HTTP server of a service and stops it without
+// cutting off the requests in flight
+package server
+
+import (
+	"context"
+	"errors"
+	"hexagone/shared/config"
+	"hexagone/shared/logging"
+	"net"
+	"net/http"
+	"time"
+)
+
+// readHeaderTimeout bounds reading the headers of a request, whatever the
+// configured read timeout, against clients that open connections and stall
+const readHeaderTimeout = 5 * time.Second
+
+// New builds the HTTP server of the service on port
+func New(port string, handler http.Handler, cfg config.Server) *http.Server {
+	return &http.Server{
+		Addr:              ":" + port,
+		Handler:           handler,
+		ReadHeaderTimeout: min(readHeaderTimeout, cfg.ReadTimeout),
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
+// Run listens on the server's address and serves until ctx is done
+func Run(ctx context.Context, srv *http.Server, shutdownTimeout time.Duration) error {
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	return Serve(ctx, srv, listener, shutdownTimeout)
+}
+
+// Serve serves on listener until ctx is done, typically on SIGTERM. It then
+// stops accepting connections and waits up to shutdownTimeout for the
+// requests in flight to finish.
+func Serve(ctx context.Context, srv *http.Server, listener net.Listener, shutdownTimeout time.Duration) error {
+	failed := make(chan error, 1)
+	go func() {
+		if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			failed <- err
+		}
+	}()
+
+	select {
+	case err := <-failed:
+		return err
+	case <-ctx.Done():
+	}
+
+	logging.Log.WithField("timeout", shutdownTimeout.String()).Info("Shutting down, draining in-flight requests")
+	drainCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(drainCtx)
+}
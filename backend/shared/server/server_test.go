@@ -0,0 +1,81 @@
+package server_test
+
+import (
+	"context"
+	"hexagone/shared/config"
+	"hexagone/shared/logging"
+	"hexagone/shared/server"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeDrainsRequests(t *testing.T) {
+	logging.InitLogger()
+
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+	srv := server.New("0", handler, config.Server{ReadTimeout: time.Second, WriteTimeout: time.Second, IdleTimeout: time.Second})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, stop := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Serve(ctx, srv, listener, time.Second) }()
+
+	response := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		response <- string(body)
+	}()
+
+	// Stop while the request is being handled
+	<-started
+	stop()
+
+	assert.Equal(t, "done", <-response, "the request in flight should complete")
+	assert.NoError(t, <-stopped)
+
+	_, err = http.Get("http://" + listener.Addr().String())
+	assert.Error(t, err, "new connections should be refused")
+}
+
+func TestServeDrainTimeout(t *testing.T) {
+	logging.InitLogger()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	srv := server.New("0", handler, config.Server{ReadTimeout: time.Second, WriteTimeout: time.Second, IdleTimeout: time.Second})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, stop := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Serve(ctx, srv, listener, 50*time.Millisecond) }()
+
+	go http.Get("http://" + listener.Addr().String())
+	<-started
+	stop()
+
+	assert.ErrorIs(t, <-stopped, context.DeadlineExceeded)
+}
@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// redisHook adds a span for the commands sent within a trace. Commands sent
+// outside of one, such as the lottery worker polling for closed windows, are
+// left out rather than each starting a trace of its own.
+type redisHook struct{}
+
+func (redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			return next(ctx, cmd)
+		}
+		ctx, span := startRedisSpan(ctx, cmd.Name())
+		defer span.End()
+
+		err := next(ctx, cmd)
+		endRedisSpan(span, err)
+		return err
+	}
+}
+
+func (redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			return next(ctx, cmds)
+		}
+		ctx, span := startRedisSpan(ctx, "pipeline")
+		defer span.End()
+		span.SetAttributes(attribute.Int("db.redis.pipeline_length", len(cmds)))
+
+		err := next(ctx, cmds)
+		endRedisSpan(span, err)
+		return err
+	}
+}
+
+// startRedisSpan names the span after the command only: the arguments hold
+// keys and values, which may be personal data
+func startRedisSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemRedis, semconv.DBOperationName(operation)),
+	)
+}
+
+// endRedisSpan does not count a missing key or a watched key that changed as
+// an error, since the service expects and handles both
+func endRedisSpan(span trace.Span, err error) {
+	if err == nil || errors.Is(err, redis.Nil) || errors.Is(err, redis.TxFailedErr) {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// InstrumentRedis traces the commands sent through client with a context
+// carrying a span, such as c.Request.Context() in a handler
+func InstrumentRedis(client *redis.Client) {
+	client.AddHook(redisHook{})
+}
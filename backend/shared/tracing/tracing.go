@@ -0,0 +1,76 @@
+// Package tracing records OpenTelemetry traces: a span for every request a
+// service handles, every call it makes to another service and every database
+// statement or command. The W3C traceparent header carries the trace from one
+// service to the next, so that a request through the gateway shows up as a
+// single trace.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "hexagone/shared/tracing"
+
+// Exporters accepted in OTEL_TRACES_EXPORTER
+const (
+	ExporterNone   = "none"   // Spans are not recorded, trace context is still passed on
+	ExporterStdout = "stdout" // Spans are written to stdout, one JSON object each
+	ExporterOTLP   = "otlp"   // Spans are sent over OTLP/HTTP to OTEL_EXPORTER_OTLP_ENDPOINT
+)
+
+// Setup installs the W3C trace-context propagator and, unless the exporter is
+// ExporterNone, a tracer provider sending its spans to that exporter. The
+// spans are reported under serviceName, unless OTEL_SERVICE_NAME is set. The
+// returned function flushes the spans not yet exported; call it on shutdown.
+func Setup(ctx context.Context, serviceName, exporter string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New()
+	case ExporterOTLP:
+		// The endpoint, headers and timeout come from OTEL_EXPORTER_OTLP_*
+		spanExporter, err = otlptracehttp.New(ctx)
+	default:
+		return func(context.Context) error { return nil }, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// StartJob starts the trace of work done outside of a request, such as a
+// lottery draw, so that the commands it sends are traced
+func StartJob(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithNewRoot())
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
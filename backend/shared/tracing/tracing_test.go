@@ -0,0 +1,195 @@
+package tracing_test
+
+import (
+	"context"
+	"hexagone/shared/httpclient"
+	"hexagone/shared/metrics"
+	"hexagone/shared/tracing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// A caller's trace: trace ID 4bf92f3577b34da6a3ce929d0e0e4736, span ID 00f067aa0ba902b7
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// record installs a tracer provider keeping the spans in memory
+func record(t *testing.T) *tracetest.SpanRecorder {
+	_, err := tracing.Setup(context.Background(), "test-service", tracing.ExporterNone)
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return recorder
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	values := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		values[kv.Key] = kv.Value
+	}
+	return values
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := record(t)
+
+	// The user service answers the call made while handling the request
+	var received string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+	client := httpclient.New(0)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(tracing.Middleware())
+	r.GET("/things/:id", func(c *gin.Context) {
+		req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, upstream.URL, nil)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		if c.Param("id") == "0" {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": c.Param("id")})
+	})
+	r.NoRoute(func(c *gin.Context) {
+		c.Set(metrics.RouteKey, "/proxied")
+		c.Status(http.StatusBadGateway)
+	})
+
+	req := httptest.NewRequest("GET", "/things/1", nil)
+	req.Header.Set("traceparent", traceparent)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	outbound, server := spans[0], spans[1]
+
+	// The request continues the caller's trace
+	assert.Equal(t, "GET /things/:id", server.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, "/things/:id", attributes(server)["http.route"].AsString())
+	assert.Equal(t, int64(200), attributes(server)["http.response.status_code"].AsInt64())
+	assert.Equal(t, codes.Unset, server.Status().Code)
+
+	// and passes it on to the service it calls
+	assert.Equal(t, server.SpanContext().SpanID(), outbound.Parent().SpanID())
+	assert.Contains(t, received, "4bf92f3577b34da6a3ce929d0e0e4736-"+outbound.SpanContext().SpanID().String())
+
+	// A request without traceparent starts a trace; a 5xx marks it as failed
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/things/0", nil))
+	spans = recorder.Ended()
+	require.Len(t, spans, 4)
+	assert.False(t, spans[3].Parent().IsValid())
+	assert.Equal(t, codes.Error, spans[3].Status().Code)
+
+	// A handler serving several paths names the span after the route it reports
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/proxied/3", nil))
+	spans = recorder.Ended()
+	require.Len(t, spans, 5)
+	assert.Equal(t, "GET /proxied", spans[4].Name())
+	assert.Equal(t, "/proxied", attributes(spans[4])["http.route"].AsString())
+}
+
+func TestInstrumentGORM(t *testing.T) {
+	type Thing struct {
+		ID   uint
+		Name string
+	}
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Thing{}))
+	require.NoError(t, tracing.InstrumentGORM(db))
+	recorder := record(t)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	require.NoError(t, db.WithContext(ctx).Create(&Thing{Name: "secret lamp"}).Error)
+	var thing Thing
+	assert.ErrorIs(t, db.WithContext(ctx).First(&thing, 42).Error, gorm.ErrRecordNotFound)
+	assert.Error(t, db.WithContext(ctx).Table("missing").Find(&[]Thing{}).Error)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	for _, span := range spans[:3] {
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.Equal(t, "sqlite", attributes(span)["db.system"].AsString())
+	}
+
+	assert.Equal(t, "create things", spans[0].Name())
+	assert.Equal(t, "things", attributes(spans[0])["db.collection.name"].AsString())
+	assert.Contains(t, attributes(spans[0])["db.query.text"].AsString(), "INSERT INTO `things`")
+	assert.NotContains(t, attributes(spans[0])["db.query.text"].AsString(), "secret lamp")
+
+	// Not found is an answer, a missing table is an error
+	assert.Equal(t, "query things", spans[1].Name())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	assert.Equal(t, "query missing", spans[2].Name())
+	assert.Equal(t, codes.Error, spans[2].Status().Code)
+}
+
+func TestInstrumentRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	tracing.InstrumentRedis(client)
+	recorder := record(t)
+
+	// Commands outside of a trace are not recorded
+	require.NoError(t, client.Set(context.Background(), "lamp", "secret", 0).Err())
+	assert.Empty(t, recorder.Ended())
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	require.NoError(t, client.Get(ctx, "lamp").Err())
+	assert.ErrorIs(t, client.Get(ctx, "chair").Err(), redis.Nil)
+	assert.Error(t, client.LPush(ctx, "lamp", "x").Err())
+	pipe := client.Pipeline()
+	pipe.Incr(ctx, "count")
+	pipe.Incr(ctx, "count")
+	_, err := pipe.Exec(ctx)
+	require.NoError(t, err)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 5)
+	for _, span := range spans[:4] {
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.Equal(t, "redis", attributes(span)["db.system"].AsString())
+	}
+
+	// Spans are named after the command, without its keys and values
+	assert.Equal(t, "get", spans[0].Name())
+	for _, kv := range spans[0].Attributes() {
+		assert.NotContains(t, kv.Value.Emit(), "secret")
+		assert.NotContains(t, kv.Value.Emit(), "lamp")
+	}
+
+	// A missing key is an answer, a command on the wrong type is an error
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	assert.Equal(t, "lpush", spans[2].Name())
+	assert.Equal(t, codes.Error, spans[2].Status().Code)
+	assert.Equal(t, "pipeline", spans[3].Name())
+	assert.Equal(t, int64(2), attributes(spans[3])["db.redis.pipeline_length"].AsInt64())
+}
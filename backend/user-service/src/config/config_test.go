@@ -0,0 +1,43 @@
+package config_test
+
+import (
+	"hexagone/user-service/src/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Setenv("PORT", "8083")
+	t.Setenv("DB_PATH", "/app/data/user.db")
+	t.Setenv("HTTP_WRITE_TIMEOUT", "2m")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "8083", cfg.Port)
+	assert.Equal(t, "/app/data/user.db", cfg.DBPath)
+	assert.Equal(t, 2*time.Minute, cfg.Server.WriteTimeout)
+	assert.Equal(t, 30*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, 15*time.Second, cfg.Server.ShutdownTimeout)
+	assert.Equal(t, "none", cfg.TracesExporter)
+	assert.Equal(t, "info", cfg.Logging.Level)
+	assert.Equal(t, "json", cfg.Logging.Format)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	t.Setenv("PORT", "http")
+	t.Setenv("DB_PATH", "")
+	t.Setenv("SHUTDOWN_TIMEOUT", "-1s")
+	t.Setenv("OTEL_TRACES_EXPORTER", "jaeger")
+	t.Setenv("LOG_LEVEL", "verbose")
+
+	_, err := config.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `PORT must be a port number, got "http"`)
+	assert.Contains(t, err.Error(), "DB_PATH is required")
+	assert.Contains(t, err.Error(), `SHUTDOWN_TIMEOUT must be a positive duration such as 30s, got "-1s"`)
+	assert.Contains(t, err.Error(), `OTEL_TRACES_EXPORTER must be one of none, stdout, otlp, got "jaeger"`)
+	assert.Contains(t, err.Error(), `LOG_LEVEL must be one of debug, info, warn, error, got "verbose"`)
+}
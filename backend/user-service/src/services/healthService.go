@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"hexagone/shared/health"
+	"hexagone/user-service/src/database"
+)
+
+// Healthz handles GET /healthz: the process is up and serving requests
+var Healthz = health.Healthz("user-service")
+
+// Readyz handles GET /readyz: checks the database, and answers 503 when it
+// is down
+var Readyz = health.Readyz("user-service", map[string]health.Check{
+	"sqlite": pingDatabase,
+})
+
+// pingDatabase checks that the SQLite database answers
+func pingDatabase(ctx context.Context) error {
+	if database.DB == nil {
+		return errors.New("database is not connected")
+	}
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,67 @@
+package services_test
+
+import (
+	"encoding/json"
+	"hexagone/shared/health"
+	"hexagone/shared/logging"
+	"hexagone/user-service/src/database"
+	"hexagone/user-service/src/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type readiness struct {
+	Status string                            `json:"status"`
+	Checks map[string]health.DependencyCheck `json:"checks"`
+}
+
+func probe(t *testing.T, r *gin.Engine, path string) (int, readiness) {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+	var body readiness
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func TestHealth(t *testing.T) {
+	logging.InitLogger()
+	gin.SetMode(gin.TestMode)
+	database.ConnectDatabase(":memory:")
+
+	r := gin.New()
+	r.GET("/healthz", services.Healthz)
+	r.GET("/readyz", services.Readyz)
+
+	code, body := probe(t, r, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.OK, body.Status)
+
+	t.Run("Ready", func(t *testing.T) {
+		code, body := probe(t, r, "/readyz")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, health.OK, body.Status)
+		assert.Equal(t, health.OK, body.Checks["sqlite"].Status)
+	})
+
+	t.Run("Database closed", func(t *testing.T) {
+		sqlDB, err := database.DB.DB()
+		require.NoError(t, err)
+		sqlDB.Close()
+		defer database.ConnectDatabase(":memory:")
+
+		code, body := probe(t, r, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, health.Down, body.Checks["sqlite"].Status)
+		assert.NotEmpty(t, body.Checks["sqlite"].Error)
+
+		// Liveness does not depend on the database
+		code, _ = probe(t, r, "/healthz")
+		assert.Equal(t, http.StatusOK, code)
+	})
+}
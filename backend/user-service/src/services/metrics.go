@@ -0,0 +1,17 @@
+package services
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Outcome of a login, as counted on /metrics
+const (
+	loginSucceeded = "succeeded"
+	loginFailed    = "failed"
+)
+
+var logins = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "logins_total",
+	Help: "Login attempts with well-formed input, by result.",
+}, []string{"result"})
@@ -0,0 +1,54 @@
+package services_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metricValue scrapes /metrics for the value of one series, 0 when absent
+func metricValue(t *testing.T, series string) float64 {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if value, found := strings.CutPrefix(line, series+" "); found {
+			number, err := strconv.ParseFloat(value, 64)
+			require.NoError(t, err)
+			return number
+		}
+	}
+	return 0
+}
+
+func TestLoginMetrics(t *testing.T) {
+	setupTestServer()
+	defer clearDatabase()
+
+	send := func(path, body string) int {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	require.Equal(t, http.StatusOK, send("/users", `{"username":"alice","email":"alice@example.com","password":"password123"}`))
+
+	succeeded := metricValue(t, `logins_total{result="succeeded"}`)
+	failed := metricValue(t, `logins_total{result="failed"}`)
+
+	send("/login", `{"email":"alice@example.com","password":"password123"}`)
+	send("/login", `{"email":"alice@example.com","password":"wrong"}`)
+	send("/login", `{"email":"bob@example.com","password":"password123"}`)
+	send("/login", `{"email":"alice@example.com"}`)
+
+	assert.Equal(t, succeeded+1, metricValue(t, `logins_total{result="succeeded"}`))
+	assert.Equal(t, failed+2, metricValue(t, `logins_total{result="failed"}`), "malformed input is not a login attempt")
+}
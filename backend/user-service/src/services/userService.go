@@ -1,9 +1,9 @@
 package services
 
 import (
+	"hexagone/shared/logging"
 	"hexagone/user-service/src/database"
 	"hexagone/user-service/src/models"
-	"hexagone/user-service/src/utils"
 	"net/http"
 	"os"
 
@@ -26,10 +26,10 @@ type LoginInput struct {
 
 // HashPassword hashes a password using bcrypt
 func HashPassword(password string) (string, error) {
-	utils.Log.Info("Hashing password")
+	logging.Log.Info("Hashing password")
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		utils.Log.WithFields(logrus.Fields{
+		logging.Log.WithFields(logrus.Fields{
 			"error": err.Error(),
 		}).Error("Failed to hash password")
 	}
@@ -40,14 +40,14 @@ func HashPassword(password string) (string, error) {
 func CreateUser(c *gin.Context) {
 	var input CreateUserInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.Log.WithFields(logrus.Fields{
+		logging.RequestLog(c).WithFields(logrus.Fields{
 			"error": err.Error(),
 		}).Error("Error binding JSON in CreateUser")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	utils.Log.WithFields(logrus.Fields{
+	logging.RequestLog(c).WithFields(logrus.Fields{
 		"username": input.Username,
 		"email":    input.Email,
 	}).Info("Creating user")
@@ -57,7 +57,7 @@ func CreateUser(c *gin.Context) {
 	adminKey := os.Getenv("ADMIN_KEY")
 	if adminKey != "" && input.AdminKey == adminKey {
 		isAdmin = true
-		utils.Log.Info("Creating admin user")
+		logging.RequestLog(c).Info("Creating admin user")
 	}
 
 	// Hash the password
@@ -75,8 +75,8 @@ func CreateUser(c *gin.Context) {
 		IsAdmin: isAdmin,
 	}
 	
-	if result := database.DB.Create(&user); result.Error != nil {
-		utils.Log.WithFields(logrus.Fields{
+	if result := database.DB.WithContext(c.Request.Context()).Create(&user); result.Error != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
 			"username": input.Username,
 			"email":    input.Email,
 			"error":    result.Error.Error(),
@@ -85,7 +85,7 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	utils.Log.WithFields(logrus.Fields{
+	logging.RequestLog(c).WithFields(logrus.Fields{
 		"id":       user.ID,
 		"username": user.Username,
 		"email":    user.Email,
@@ -102,32 +102,34 @@ func CreateUser(c *gin.Context) {
 func Login(c *gin.Context) {
 	var input LoginInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.Log.WithFields(logrus.Fields{
+		logging.RequestLog(c).WithFields(logrus.Fields{
 			"error": err.Error(),
 		}).Error("Error binding JSON in Login")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	utils.Log.WithFields(logrus.Fields{
+	logging.RequestLog(c).WithFields(logrus.Fields{
 		"email": input.Email,
 	}).Info("Attempting login")
 
 	// Find the user by email
 	var user models.User
-	if err := database.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
-		utils.Log.WithFields(logrus.Fields{
+	if err := database.DB.WithContext(c.Request.Context()).Where("email = ?", input.Email).First(&user).Error; err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
 			"email": input.Email,
 		}).Warn("Invalid email during login")
+		logins.WithLabelValues(loginFailed).Inc()
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
 	// Check the password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
-		utils.Log.WithFields(logrus.Fields{
+		logging.RequestLog(c).WithFields(logrus.Fields{
 			"email": input.Email,
 		}).Warn("Invalid password during login")
+		logins.WithLabelValues(loginFailed).Inc()
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
@@ -140,11 +142,12 @@ func Login(c *gin.Context) {
 		IsAdmin:  user.IsAdmin,
 	}
 
-	utils.Log.WithFields(logrus.Fields{
+	logging.RequestLog(c).WithFields(logrus.Fields{
 		"id":      user.ID,
 		"email":   user.Email,
 		"isAdmin": user.IsAdmin,
 	}).Info("Login successful")
+	logins.WithLabelValues(loginSucceeded).Inc()
 
 	c.JSON(http.StatusOK, gin.H{"message": "Login successful", "user": safeUser})
 }
@@ -152,18 +155,18 @@ func Login(c *gin.Context) {
 
 // ListUsers retrieves all users
 func ListUsers(c *gin.Context) {
-	utils.Log.Info("Fetching all users")
+	logging.RequestLog(c).Info("Fetching all users")
 
 	var users []models.User
-	if err := database.DB.Find(&users).Error; err != nil {
-		utils.Log.WithFields(logrus.Fields{
+	if err := database.DB.WithContext(c.Request.Context()).Find(&users).Error; err != nil {
+		logging.RequestLog(c).WithFields(logrus.Fields{
 			"error": err.Error(),
 		}).Error("Failed to retrieve users from the database")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
 		return
 	}
 
-	utils.Log.WithFields(logrus.Fields{
+	logging.RequestLog(c).WithFields(logrus.Fields{
 		"count": len(users),
 	}).Info("Users fetched successfully")
 
@@ -173,13 +176,13 @@ func ListUsers(c *gin.Context) {
 func GetUser(c *gin.Context) {
     userID := c.Param("id")
     
-    utils.Log.WithFields(logrus.Fields{
+    logging.RequestLog(c).WithFields(logrus.Fields{
         "userID": userID,
     }).Info("Fetching user by ID")
 
     var user models.User
-    if err := database.DB.First(&user, userID).Error; err != nil {
-        utils.Log.WithFields(logrus.Fields{
+    if err := database.DB.WithContext(c.Request.Context()).First(&user, userID).Error; err != nil {
+        logging.RequestLog(c).WithFields(logrus.Fields{
             "userID": userID,
             "error":  err.Error(),
         }).Error("Failed to retrieve user from the database")
@@ -187,7 +190,7 @@ func GetUser(c *gin.Context) {
         return
     }
 
-    utils.Log.WithFields(logrus.Fields{
+    logging.RequestLog(c).WithFields(logrus.Fields{
         "userID":   user.ID,
         "username": user.Username,
     }).Info("User fetched successfully")
@@ -3,10 +3,11 @@ package services_test
 import (
 	"bytes"
 	"encoding/json"
+	"hexagone/shared/logging"
+	"hexagone/shared/metrics"
 	"hexagone/user-service/src/database"
 	"hexagone/user-service/src/models"
 	"hexagone/user-service/src/services"
-	"hexagone/user-service/src/utils"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -19,7 +20,7 @@ var router *gin.Engine
 
 func setupTestServer() {
 	// Initialize logger
-	utils.InitLogger()
+	logging.InitLogger()
 	
 	// Set Gin to test mode
 	gin.SetMode(gin.TestMode)
@@ -32,6 +33,7 @@ func setupTestServer() {
 	router.POST("/users", services.CreateUser)
 	router.POST("/login", services.Login)
 	router.GET("/users", services.ListUsers)
+	router.GET("/metrics", metrics.Handler())
 }
 
 func clearDatabase() {
@@ -274,4 +276,35 @@ func TestListUsers(t *testing.T) {
 			assert.NotEqual(t, testUsers[i]["password"], user.Password) // Password should be hashed
 		}
 	})
-}
\ No newline at end of file
+}
+func TestLogsRedacted(t *testing.T) {
+	setupTestServer()
+	defer clearDatabase()
+	var logs bytes.Buffer
+	logging.Log.Out = &logs
+
+	t.Setenv("ADMIN_KEY", "admin-key-123")
+	for _, call := range []struct {
+		path string
+		body map[string]interface{}
+	}{
+		{"/users", map[string]interface{}{"username": "alice", "email": "alice@example.com", "password": "s3cret-pass", "adminKey": "admin-key-123"}},
+		{"/login", map[string]interface{}{"email": "alice@example.com", "password": "s3cret-pass"}},
+		{"/login", map[string]interface{}{"email": "alice@example.com", "password": "wrong-pass"}},
+		{"/login", map[string]interface{}{"email": "nobody@example.com", "password": "s3cret-pass"}},
+	} {
+		jsonInput, _ := json.Marshal(call.body)
+		req := httptest.NewRequest("POST", call.path, bytes.NewBuffer(jsonInput))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// The requests were logged, with the emails masked and no secret
+	assert.Contains(t, logs.String(), "User created successfully")
+	assert.Contains(t, logs.String(), "Invalid password during login")
+	assert.Contains(t, logs.String(), `"email":"a***@example.com"`)
+	assert.Contains(t, logs.String(), `"email":"n***@example.com"`)
+	for _, secret := range []string{"alice@example.com", "nobody@example.com", "s3cret-pass", "wrong-pass", "admin-key-123"} {
+		assert.NotContains(t, logs.String(), secret)
+	}
+}
@@ -8,6 +8,7 @@ import LoginPage from './pages/Login';
 import SignUpPage from './pages/Signin';
 import HomeRooms from './pages/Room';
 import ObjectsPage from './pages/Object';
+import ScannedLabel from './pages/ScannedLabel';
 
 function App() {
   return (
@@ -26,6 +27,7 @@ function App() {
                 <Route path="/room" element={<Room />} />
                 <Route path="/object" element={<Object />} />
                 <Route path="/room/:id" element={<ObjectsPage />} />
+                <Route path="/o/:code" element={<ScannedLabel />} />
               </Routes>
             </Layout>
           }
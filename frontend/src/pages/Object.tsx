@@ -145,7 +145,7 @@ export default function ObjectsPage() {
     }
 
     try {
-      await objectService.reserveObject(objectId, currentUser.id, id);
+      await objectService.reserveObject(objectId);
       fetchObjects();
     } catch (err: any) {
       setError(err.message);
@@ -0,0 +1,83 @@
+import { useState, useEffect } from 'react';
+import { useNavigate, useParams } from 'react-router-dom';
+import { ArrowLeft, Package, QrCode } from 'lucide-react';
+import { Card, CardHeader, CardDescription } from "@/components/ui/card";
+import { Button } from "@/components/ui/button";
+import { objectService } from '../services/object';
+import { Object } from '../types/object';
+
+// Landing page of the QR labels: /o/:code resolves the printed short code
+// to its object, and leads to the room the object is in
+export default function ScannedLabel() {
+  const { code } = useParams<{ code: string }>();
+  const navigate = useNavigate();
+
+  const [object, setObject] = useState<Object | null>(null);
+  const [isLoading, setIsLoading] = useState(true);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    if (!code) return;
+
+    setIsLoading(true);
+    objectService.lookupLabel(code)
+      .then((response) => {
+        setObject(response.data);
+        setError(null);
+      })
+      .catch((err: any) => setError(err.message))
+      .finally(() => setIsLoading(false));
+  }, [code]);
+
+  return (
+    <div className="space-y-6">
+      <div className="flex items-center gap-4">
+        <Button
+          variant="ghost"
+          size="icon"
+          onClick={() => navigate('/')}
+        >
+          <ArrowLeft className="h-6 w-6" />
+        </Button>
+        <h1 className="text-3xl font-bold flex items-center gap-2">
+          <QrCode className="h-8 w-8" />
+          {code?.toUpperCase()}
+        </h1>
+      </div>
+
+      {error && (
+        <div className="bg-red-50 text-red-600 p-4 rounded-md">
+          {error}
+        </div>
+      )}
+
+      {isLoading ? (
+        <div className="text-center py-4">Looking up label...</div>
+      ) : object && (
+        <Card className={object.isReserved ? 'bg-gray-50 border-yellow-200' : ''}>
+          <CardHeader>
+            <div className="flex items-center justify-between">
+              <div>
+                <h3 className="font-semibold">{object.name}</h3>
+                <CardDescription>{object.type}</CardDescription>
+              </div>
+              <Package className={`h-5 w-5 ${object.isReserved ? 'text-yellow-500' : 'text-gray-500'}`} />
+            </div>
+            {object.isReserved && (
+              <div className="text-sm text-yellow-600 bg-yellow-50 p-2 rounded-md">
+                Reserved
+              </div>
+            )}
+            <Button
+              variant="outline"
+              className="w-full"
+              onClick={() => navigate(`/room/${object.room_id}`)}
+            >
+              Open room
+            </Button>
+          </CardHeader>
+        </Card>
+      )}
+    </div>
+  );
+}
@@ -8,7 +8,7 @@ import {
     User
 } from '../types/auth';
 
-const API_URL = 'http://localhost:8083'; // Using USER_PORT from env file
+const API_URL = '/api'; // The gateway, proxied by nginx on the same origin
 
 class AuthService {
     // Helper method for HTTP requests
@@ -88,8 +88,9 @@ class AuthService {
         }
     }
 
-    // Logout user
+    // Logout user, clearing the session cookie set by the gateway
     logout(): void {
+        fetch(`${API_URL}/logout`, { method: 'POST' }).catch(() => {});
         localStorage.removeItem('user');
         localStorage.removeItem('isAuthenticated');
     }
@@ -1,14 +1,11 @@
 import { CreateHomeRequest, HomeResponse, ListHomesResponse } from '../types/home';
-import { authService } from './auth';
 
-const API_URL = 'http://localhost:8081'; // Using HOME_PORT from env file
+const API_URL = '/api'; // The gateway, proxied by nginx on the same origin
 
 class HomeService {
   async fetchWithAuth(endpoint: string, options: RequestInit = {}): Promise<any> {
-    const currentUser = authService.getCurrentUser();
     const headers = {
       'Content-Type': 'application/json',
-      'X-User-ID': currentUser?.id.toString() || '',
       ...options.headers,
     };
 
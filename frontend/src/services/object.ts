@@ -4,16 +4,13 @@ import {
     ObjectResponse,
     ListObjectsResponse,
 } from '../types/object';
-import { authService } from './auth';
 
-const API_URL = 'http://localhost:8080';
+const API_URL = '/api'; // The gateway, proxied by nginx on the same origin
 
 class ObjectService {
     async fetchWithAuth(endpoint: string, options: RequestInit = {}): Promise<any> {
-        const currentUser = authService.getCurrentUser();
         const headers = {
             'Content-Type': 'application/json',
-            'X-User-ID': currentUser?.id.toString() || '',
             ...options.headers,
         };
 
@@ -70,17 +67,13 @@ class ObjectService {
         }
     }
 
-    async reserveObject(objectId: string, userId: number, roomId: string): Promise<ObjectResponse> {
+    async reserveObject(objectId: string): Promise<ObjectResponse> {
         try {
             const response = await fetch(`${API_URL}/objects/${objectId}/reserve`, {
                 method: 'PATCH',
                 headers: {
                     'Content-Type': 'application/json',
-                },
-                body: JSON.stringify({
-                    userId: userId.toString(),
-                    room_id: roomId
-                }),
+                }
             });
 
             if (!response.ok) {
@@ -128,6 +121,11 @@ class ObjectService {
         }
     }
 
+    // Resolves the short code printed on a QR label, as typed or scanned
+    async lookupLabel(code: string): Promise<ObjectResponse> {
+        return this.fetchWithAuth(`/labels/${encodeURIComponent(code)}`);
+    }
+
     async deleteObject(objectId: number): Promise<void> {
         await this.fetchWithAuth(`/objects/${objectId}`, {
             method: 'DELETE',
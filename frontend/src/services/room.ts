@@ -1,14 +1,11 @@
 import { CreateRoomRequest, RoomResponse, ListRoomsResponse } from '../types/room';
-import { authService } from './auth';
 
-const API_URL = 'http://localhost:8082'; // Using ROOM_PORT from env file
+const API_URL = '/api'; // The gateway, proxied by nginx on the same origin
 
 class RoomService {
   async fetchWithAuth(endpoint: string, options: RequestInit = {}): Promise<any> {
-    const currentUser = authService.getCurrentUser();
     const headers = {
       'Content-Type': 'application/json',
-      'X-User-ID': currentUser?.id.toString() || '',
       ...options.headers,
     };
 
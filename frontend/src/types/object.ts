@@ -4,7 +4,8 @@ export interface Object {
   type: string;
   isReserved: boolean;
   reservedBy?: string;
-  roomId: string;
+  room_id: string;
+  home_id?: string;
 }
 
 export interface CreateObjectRequest {
@@ -13,11 +14,6 @@ export interface CreateObjectRequest {
   room_id: string;
 }
 
-export interface ReserveObjectRequest {
-  userId: string;
-  room_id: string;
-}
-
 export interface ObjectResponse {
   data: Object;
 }
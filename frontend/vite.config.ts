@@ -9,4 +9,10 @@ export default defineConfig({
       "@": path.resolve(__dirname, "./src"),
     },
   },
+  server: {
+    // The API is the gateway, as behind nginx in docker-compose
+    proxy: {
+      "/api": `http://localhost:${process.env.GATEWAY_PORT || 8090}`,
+    },
+  },
 })